@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"duckduckgo-chat-cli/internal/chat"
+	"duckduckgo-chat-cli/internal/config"
+	"duckduckgo-chat-cli/internal/models"
+	"duckduckgo-chat-cli/internal/ui"
+
+	"github.com/fatih/color"
+	"golang.org/x/term"
+)
+
+// stringList is a flag that can be repeated
+type stringList []string
+
+func (s *stringList) String() string { return strings.Join(*s, ", ") }
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// runAsk sends a single prompt and prints only the answer on stdout, so the
+// tool can be used in shell pipelines. Everything else, including errors,
+// goes to stderr. Piped stdin is added as context. It returns the process
+// exit code: 1 for chat or context errors, 2 for usage errors.
+func runAsk(args []string) int {
+	// Keep stdout for the answer: status messages are written to stderr
+	answerOut := os.Stdout
+	os.Stdout = os.Stderr
+	color.Output = color.Error
+
+	var prompt, modelName, format string
+	var files, urls, searches stringList
+	var acceptTOS bool
+
+	flags := flag.NewFlagSet("ask", flag.ContinueOnError)
+	flags.StringVar(&prompt, "p", "", "prompt to send")
+	flags.StringVar(&prompt, "prompt", "", "prompt to send")
+	flags.StringVar(&modelName, "model", "", "model alias or name (default: the configured model)")
+	flags.Var(&files, "file", "add a file as context (repeatable)")
+	flags.Var(&urls, "url", "add a webpage as context (repeatable)")
+	flags.Var(&searches, "search", "add web search results as context (repeatable)")
+	flags.StringVar(&format, "format", "", `answer format: "raw" markdown or "rendered" (default: rendered on a terminal, raw otherwise)`)
+	flags.BoolVar(&acceptTOS, "accept-tos", false, "accept the DuckDuckGo AI Chat terms of service without prompting")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), `Usage:
+  duckchat                          start the interactive chat
+  duckchat -p "question" [flags]    ask a single question
+  duckchat ask [flags] question     ask a single question
+  duckchat serve [flags]            run the API server (see duckchat serve --help)
+
+Piped input is added as context, e.g. git diff | duckchat -p "write a commit message"
+
+Flags:
+`)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+
+	if prompt == "" {
+		prompt = strings.Join(flags.Args(), " ")
+	}
+	if strings.TrimSpace(prompt) == "" {
+		ui.Errorln("No prompt given. Use -p \"question\" or duckchat ask \"question\".")
+		return 2
+	}
+
+	if format == "" {
+		format = "raw"
+		if term.IsTerminal(int(answerOut.Fd())) {
+			format = "rendered"
+		}
+	}
+	if format != "raw" && format != "rendered" {
+		ui.Errorln("Invalid format %q: use raw or rendered", format)
+		return 2
+	}
+
+	cfg = config.Initialize()
+
+	// Only the DuckDuckGo models are known in advance: other providers get
+	// the name as it is, like /model
+	if modelName != "" {
+		if cfg.Provider.Type == config.ProviderOpenAI {
+			cfg.Provider.Model = modelName
+		} else if alias, _, ok := models.LookupModel(modelName); ok {
+			cfg.DefaultModel = string(alias)
+		} else {
+			ui.Errorln("Unknown model: %s", modelName)
+			return 2
+		}
+	}
+
+	if !cfg.TOSAccepted && !acceptTOS {
+		ui.Errorln("The terms of service have not been accepted. Run duckchat once interactively, or pass --accept-tos.")
+		return 1
+	}
+
+	// Read piped input before doing any network work, so a broken pipe fails fast
+	var stdinContent []byte
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		content, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			ui.Errorln("Cannot read standard input: %v", err)
+			return 1
+		}
+		stdinContent = content
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	chatSession = chat.NewSession(cfg)
+
+	if len(strings.TrimSpace(string(stdinContent))) > 0 {
+		chatSession.AddFileContext("stdin", stdinContent)
+	}
+	for _, path := range files {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			ui.Errorln("File error: %v", err)
+			return 1
+		}
+		chatSession.AddFileContext(path, content)
+	}
+	for _, url := range urls {
+		if err := chatSession.AddURLContext(ctx, url); err != nil {
+			ui.Errorln("Error retrieving %s: %v", url, err)
+			return 1
+		}
+	}
+	for _, query := range searches {
+		if _, err := chatSession.AddSearchContext(ctx, query, cfg); err != nil {
+			ui.Errorln("Search error: %v", err)
+			return 1
+		}
+	}
+
+	stream, err := chat.ProcessInputStream(ctx, chatSession, prompt, cfg)
+	if err != nil {
+		ui.Errorln("%v", err)
+		return 1
+	}
+
+	result := &chat.StreamResult{}
+	for event := range stream {
+		result.Add(event)
+		if format == "raw" && event.Type == chat.EventDelta {
+			fmt.Fprint(answerOut, event.Content)
+		}
+	}
+	answer := result.Content()
+
+	if errors.Is(ctx.Err(), context.Canceled) {
+		ui.Warningln("\nInterrupted.")
+		return 130
+	}
+	if answer == "" {
+		if result.Err != nil {
+			ui.Errorln("%v", result.Err)
+		} else {
+			ui.Errorln("The model returned an empty answer.")
+		}
+		return 1
+	}
+
+	if format == "rendered" {
+		fmt.Fprint(answerOut, chat.RenderMarkdown(answer))
+	} else if !strings.HasSuffix(answer, "\n") {
+		fmt.Fprintln(answerOut)
+	}
+
+	// What was received is still printed, but a script must not take a
+	// partial answer for a complete one
+	if result.Err != nil {
+		ui.Errorln("The answer is incomplete: %v", result.Err)
+		return 1
+	}
+	if result.Truncated() {
+		ui.Warningln("The answer hit the output limit.")
+	}
+	return 0
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"duckduckgo-chat-cli/internal/cassette"
+	"duckduckgo-chat-cli/internal/chat"
+	"duckduckgo-chat-cli/internal/ui"
+
+	"github.com/fatih/color"
+)
+
+// setupCassette handles the --record and --replay flags, which apply to every
+// mode, and returns the remaining arguments. With --record the exchanges with
+// the DuckDuckGo chat API are saved to a cassette file; with --replay they are
+// answered from one by a local stand-in server instead of duckduckgo.com. The
+// notices go to stderr, which keeps the one-shot mode's stdout clean.
+func setupCassette(args []string) ([]string, error) {
+	var record, replay string
+	rest := make([]string, 0, len(args))
+
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(strings.TrimLeft(args[i], "-"), "=")
+		if !strings.HasPrefix(args[i], "-") || (name != "record" && name != "replay") {
+			rest = append(rest, args[i])
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("flag --%s needs a cassette file", name)
+			}
+			i++
+			value = args[i]
+		}
+		if name == "record" {
+			record = value
+		} else {
+			replay = value
+		}
+	}
+
+	switch {
+	case record != "" && replay != "":
+		return nil, fmt.Errorf("--record and --replay cannot be used together")
+	case record != "":
+		chat.Transport = cassette.NewRecorder(record, chat.Transport)
+		ui.WarningColor.Fprintf(color.Error, "⏺️  Recording DuckDuckGo exchanges to %s\n", record)
+	case replay != "":
+		c, err := cassette.Load(replay)
+		if err != nil {
+			return nil, err
+		}
+		server, err := cassette.NewServer(c)
+		if err != nil {
+			return nil, err
+		}
+		chat.Transport = server.Transport(chat.Transport)
+		ui.WarningColor.Fprintf(color.Error, "▶️  Replaying %d DuckDuckGo exchanges from %s\n", len(c.Interactions), replay)
+	}
+	return rest, nil
+}
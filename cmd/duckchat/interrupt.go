@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"duckduckgo-chat-cli/internal/ui"
+
+	"github.com/c-bata/go-prompt"
+)
+
+// exitConfirmWindow is how soon a second Ctrl+C must follow the first one to
+// exit from the prompt
+const exitConfirmWindow = 2 * time.Second
+
+// interrupts routes Ctrl+C. While a command runs, the terminal is in cooked
+// mode and Ctrl+C arrives as SIGINT: it cancels the command's requests and
+// leaves the session running. At the prompt, the terminal is in raw mode and
+// Ctrl+C arrives as a key: pressing it twice in a row exits.
+var interrupts struct {
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	last   time.Time
+}
+
+// commandContext returns the context of a command typed at the prompt, which
+// the next interrupt cancels. The returned function must be called once the
+// command is done.
+func commandContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	interrupts.mu.Lock()
+	interrupts.cancel = cancel
+	interrupts.mu.Unlock()
+
+	return ctx, func() {
+		interrupts.mu.Lock()
+		interrupts.cancel = nil
+		interrupts.mu.Unlock()
+		cancel()
+	}
+}
+
+// cancelCommand cancels the running command, if any, and reports whether
+// there was one
+func cancelCommand() bool {
+	interrupts.mu.Lock()
+	defer interrupts.mu.Unlock()
+
+	if interrupts.cancel == nil {
+		return false
+	}
+	interrupts.cancel()
+	interrupts.cancel = nil
+	interrupts.last = time.Now()
+	return true
+}
+
+// promptInterrupt is the Ctrl+C key binding of the prompt
+func promptInterrupt(*prompt.Buffer) {
+	interrupts.mu.Lock()
+	confirmed := time.Since(interrupts.last) < exitConfirmWindow
+	interrupts.last = time.Now()
+	interrupts.mu.Unlock()
+
+	if confirmed {
+		exitGracefully("\nExiting chat. Goodbye!")
+	}
+	ui.Mutedln("(Press Ctrl+C again to exit, or type /exit)")
+}
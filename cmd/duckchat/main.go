@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"os"
 	"os/signal"
 	"runtime"
@@ -14,6 +15,7 @@ import (
 	"duckduckgo-chat-cli/internal/command"
 	"duckduckgo-chat-cli/internal/config"
 	"duckduckgo-chat-cli/internal/models"
+	"duckduckgo-chat-cli/internal/proxy"
 	"duckduckgo-chat-cli/internal/ui"
 	"duckduckgo-chat-cli/internal/update"
 
@@ -68,6 +70,16 @@ func getCommands() []prompt.Suggest {
 
 var commands = getCommands()
 
+// modelSuggestions returns the offered models for autocompletion
+func modelSuggestions() []prompt.Suggest {
+	offered := models.Offered()
+	suggestions := make([]prompt.Suggest, len(offered))
+	for i, m := range offered {
+		suggestions[i] = prompt.Suggest{Text: string(m.Alias), Description: m.DisplayName}
+	}
+	return suggestions
+}
+
 func completer(d prompt.Document) []prompt.Suggest {
 	text := d.TextBeforeCursor()
 	segment := text
@@ -75,8 +87,17 @@ func completer(d prompt.Document) []prompt.Suggest {
 		segment = strings.TrimLeft(text[i+2:], " ")
 	}
 
-	// We only want to complete the command name, not its arguments.
-	if strings.Contains(segment, " ") {
+	// Arguments are only completed for the commands that take models
+	if name, arg, found := strings.Cut(segment, " "); found {
+		if chatSession.Provider.Name() != config.ProviderDuckDuckGo {
+			return nil
+		}
+		switch {
+		case (name == "/model" || name == "/retry") && !strings.Contains(arg, " "):
+			return prompt.FilterHasPrefix(modelSuggestions(), arg, true)
+		case name == "/compare" && !strings.Contains(arg, "--"):
+			return prompt.FilterHasPrefix(modelSuggestions(), arg[strings.LastIndex(arg, " ")+1:], true)
+		}
 		return nil
 	}
 
@@ -89,6 +110,22 @@ func completer(d prompt.Document) []prompt.Suggest {
 }
 
 func main() {
+	args, err := setupCassette(os.Args[1:])
+	if err != nil {
+		ui.Errorln("%v", err)
+		os.Exit(2)
+	}
+	if len(args) > 0 {
+		switch {
+		case args[0] == "serve":
+			os.Exit(runServe(args[1:]))
+		case args[0] == "ask":
+			os.Exit(runAsk(args[1:]))
+		case strings.HasPrefix(args[0], "-"):
+			os.Exit(runAsk(args))
+		}
+	}
+
 	// Save the terminal state at startup
 	if err := saveTerminalState(); err != nil {
 		ui.Warningln("Warning: Could not save terminal state: %v", err)
@@ -107,19 +144,13 @@ func main() {
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
 	go func() {
-		<-sigChan
-		ui.Warningln("\nReceived interrupt. Exiting gracefully.")
-
-		// Show session statistics before exiting
-		if chatSession != nil {
-			chatSession.ShowSessionStats()
-		}
-
-		// Restore terminal state before exiting
-		if err := restoreTerminalState(); err != nil {
-			ui.Warningln("Warning: Could not restore terminal state: %v", err)
+		for sig := range sigChan {
+			// Ctrl+C during a command only stops that command's requests
+			if sig == os.Interrupt && cancelCommand() {
+				continue
+			}
+			exitGracefully("\nReceived interrupt. Exiting gracefully.")
 		}
-		os.Exit(0)
 	}()
 
 	ui.Systemln("Welcome to DuckDuckGo AI Chat CLI!")
@@ -133,6 +164,7 @@ func main() {
 	}
 
 	chatSession = chat.InitializeSession(cfg)
+	chat.RecoverUnfinishedSession(chatSession)
 
 	if cfg.API.Enabled && cfg.API.Autostart {
 		api.StartServer(chatSession, cfg, cfg.API.Port)
@@ -153,9 +185,27 @@ func main() {
 		prompt.OptionTitle("duckduckgo-chat-cli"),
 		prompt.OptionPrefix("You: "),
 		prompt.OptionPrefixTextColor(prompt.Blue),
+		prompt.OptionAddKeyBind(prompt.KeyBind{Key: prompt.ControlC, Fn: promptInterrupt}),
 	)
 	p.Run()
+	chatSession.Close()
+}
+
+// exitGracefully shows the session statistics, restores the terminal and exits
+func exitGracefully(message string) {
+	ui.Warningln("%s", message)
+
+	// Show session statistics and save the conversation before exiting
+	if chatSession != nil {
+		chatSession.ShowSessionStats()
+		chatSession.Close()
+	}
 
+	// Restore terminal state before exiting
+	if err := restoreTerminalState(); err != nil {
+		ui.Warningln("Warning: Could not restore terminal state: %v", err)
+	}
+	os.Exit(0)
 }
 
 func executor(input string) {
@@ -163,18 +213,7 @@ func executor(input string) {
 		return
 	}
 	if input == "/exit" {
-		ui.Warningln("\nExiting chat. Goodbye!")
-
-		// Show session statistics before exiting
-		if chatSession != nil {
-			chatSession.ShowSessionStats()
-		}
-
-		// Restore terminal state before exiting
-		if err := restoreTerminalState(); err != nil {
-			ui.Warningln("Warning: Could not restore terminal state: %v", err)
-		}
-		os.Exit(0)
+		exitGracefully("\nExiting chat. Goodbye!")
 	}
 
 	// Track command usage
@@ -191,30 +230,56 @@ func executor(input string) {
 		return
 	}
 
+	ctx, done := commandContext()
+	defer done()
+
+	// Commands that touch the conversation wait for API requests in progress
+	if chatSession != nil && needsTurn(chainedCmd) {
+		if err := chatSession.AcquireInteractive(ctx); err != nil {
+			ui.Warningln("Request canceled")
+			return
+		}
+		defer chatSession.Release()
+	}
+
 	if len(chainedCmd.Commands) == 1 && !command.IsChainableCommand(chainedCmd.Commands[0].Type) {
 		// Pour les commandes non chainables (ex: /prompt), passer le prompt à handleCommand
 		if chainedCmd.Prompt != "" {
 			chainedCmd.Commands[0].Raw = strings.TrimSpace(chainedCmd.Commands[0].Raw + " -- " + chainedCmd.Prompt)
 		}
-		handleCommand(chatSession, cfg, chainedCmd.Commands[0])
+		handleCommand(ctx, chatSession, cfg, chainedCmd.Commands[0])
 	} else if len(chainedCmd.Commands) > 1 || chainedCmd.Prompt != "" {
-		handleCommandChain(chatSession, cfg, chainedCmd)
+		handleCommandChain(ctx, chatSession, cfg, chainedCmd)
 	} else if len(chainedCmd.Commands) == 1 {
-		handleCommand(chatSession, cfg, chainedCmd.Commands[0])
+		handleCommand(ctx, chatSession, cfg, chainedCmd.Commands[0])
+	}
+}
+
+// needsTurn reports whether the input reads or changes the chat session.
+// Server management and informational commands run without waiting, so that
+// /api can still stop a server whose requests hold the session.
+func needsTurn(chainedCmd *command.ChainedCommand) bool {
+	if len(chainedCmd.Commands) != 1 || chainedCmd.Prompt != "" {
+		return true
+	}
+	switch chainedCmd.Commands[0].Type {
+	case "/api", "/help", "/version", "/stats", "/doctor", "/update":
+		return false
 	}
+	return true
 }
 
-func handleCommandChain(chatSession *chat.Chat, cfg *config.Config, chainedCmd *command.ChainedCommand) {
+func handleCommandChain(ctx context.Context, chatSession *chat.Chat, cfg *config.Config, chainedCmd *command.ChainedCommand) {
 	chainCtx := chatcontext.New()
 
 	for _, cmd := range chainedCmd.Commands {
 		switch cmd.Type {
 		case "/file":
-			chat.HandleFileCommand(chatSession, cmd.Raw, cfg, chainCtx)
+			chat.HandleFileCommand(ctx, chatSession, cmd.Raw, cfg, chainCtx)
 		case "/url":
-			chat.HandleURLCommand(chatSession, cmd.Raw, cfg, chainCtx)
+			chat.HandleURLCommand(ctx, chatSession, cmd.Raw, cfg, chainCtx)
 		case "/search":
-			chat.HandleSearchCommand(chatSession, cmd.Raw, cfg, chainCtx)
+			chat.HandleSearchCommand(ctx, chatSession, cmd.Raw, cfg, chainCtx)
 		default:
 			ui.Errorln("Command '%s' is not supported in a command chain.", cmd.Type)
 			return
@@ -224,7 +289,7 @@ func handleCommandChain(chatSession *chat.Chat, cfg *config.Config, chainedCmd *
 	if chainCtx.IsEmpty() {
 		if chainedCmd.Prompt != "" {
 			// This case is for when the user just types "-- some prompt"
-			chat.ProcessInput(chatSession, chainedCmd.Prompt, cfg)
+			chat.ProcessInput(ctx, chatSession, chainedCmd.Prompt, cfg)
 		}
 		return
 	}
@@ -233,7 +298,7 @@ func handleCommandChain(chatSession *chat.Chat, cfg *config.Config, chainedCmd *
 	finalInput := chainCtx.String()
 	if chainedCmd.Prompt != "" {
 		finalInput += "\n\n" + chainedCmd.Prompt
-		chat.ProcessInput(chatSession, finalInput, cfg)
+		chat.ProcessInput(ctx, chatSession, finalInput, cfg)
 	} else {
 		// Context loaded, but no prompt. Add to session and notify user.
 		chatSession.AddContextMessage(finalInput)
@@ -241,7 +306,7 @@ func handleCommandChain(chatSession *chat.Chat, cfg *config.Config, chainedCmd *
 	}
 }
 
-func handleCommand(chatSession *chat.Chat, cfg *config.Config, cmd *command.Command) {
+func handleCommand(ctx context.Context, chatSession *chat.Chat, cfg *config.Config, cmd *command.Command) {
 	// if the input is empty, return
 	if cmd.Raw == "" {
 		return
@@ -251,23 +316,35 @@ func handleCommand(chatSession *chat.Chat, cfg *config.Config, cmd *command.Comm
 	case cmd.Type == "/clear":
 		chatSession.Clear(cfg)
 	case cmd.Type == "/history":
-		chat.PrintHistory(chatSession)
+		chat.PrintHistory(chatSession, cmd.Args)
+	case cmd.Type == "/branch":
+		chat.HandleBranchCommand(chatSession, cmd.Args)
+	case cmd.Type == "/retry":
+		chat.HandleRetryCommand(ctx, chatSession, cmd.Args)
+	case cmd.Type == "/edit":
+		chat.HandleEditCommand(ctx, chatSession, cmd.Args)
+	case cmd.Type == "/undo":
+		chat.HandleUndoCommand(chatSession)
+	case cmd.Type == "/compare":
+		chat.HandleCompareCommand(ctx, chatSession, cmd.Raw, cfg)
 	case cmd.Type == "/search":
-		chat.HandleSearchCommand(chatSession, cmd.Raw, cfg, nil)
+		chat.HandleSearchCommand(ctx, chatSession, cmd.Raw, cfg, nil)
 	case cmd.Type == "/file":
-		chat.HandleFileCommand(chatSession, cmd.Raw, cfg, nil)
+		chat.HandleFileCommand(ctx, chatSession, cmd.Raw, cfg, nil)
 	case cmd.Type == "/library":
-		chat.HandleLibraryCommand(chatSession, cmd.Raw, cfg)
+		chat.HandleLibraryCommand(ctx, chatSession, cmd.Raw, cfg)
 	case cmd.Type == "/url":
-		chat.HandleURLCommand(chatSession, cmd.Raw, cfg, nil)
+		chat.HandleURLCommand(ctx, chatSession, cmd.Raw, cfg, nil)
 	case cmd.Type == "/pmp":
-		chat.HandlePMPCommand(chatSession, cmd.Raw, cfg)
+		chat.HandlePMPCommand(ctx, chatSession, cmd.Raw, cfg)
 	case cmd.Type == "/export":
 		chat.HandleExportCommand(chatSession, cfg)
 	case cmd.Type == "/copy":
 		chat.HandleCopyCommand(chatSession)
 	case cmd.Type == "/config":
 		config.HandleConfiguration(cfg, chatSession)
+	case cmd.Type == "/model" && chatSession.Provider.Name() != config.ProviderDuckDuckGo:
+		chat.HandleProviderModelCommand(chatSession, strings.TrimSpace(cmd.Args), cfg)
 	case cmd.Type == "/model":
 		newModel := models.HandleModelChange(chatSession, cmd.Args)
 		if newModel != "" {
@@ -310,6 +387,7 @@ func handleCommand(chatSession *chat.Chat, cfg *config.Config, cmd *command.Comm
 		ui.AIln("DuckDuckGo AI Chat CLI version %s", Version)
 		ui.Mutedln("Go version: %s", runtime.Version())
 		ui.Mutedln("OS/Arch: %s/%s", runtime.GOOS, runtime.GOARCH)
+		ui.Mutedln("Proxy: %s", proxy.Describe())
 	case cmd.Type == "/stats":
 		// Show current session analytics
 		if chatSession != nil {
@@ -317,6 +395,8 @@ func handleCommand(chatSession *chat.Chat, cfg *config.Config, cmd *command.Comm
 		} else {
 			ui.Errorln("No active chat session found.")
 		}
+	case cmd.Type == "/doctor":
+		chat.HandleDoctorCommand(chatSession, cmd.Args)
 	case cmd.Type == "/update":
 		// Handle update command
 		force := strings.Contains(cmd.Args, "--force")
@@ -326,7 +406,7 @@ func handleCommand(chatSession *chat.Chat, cfg *config.Config, cmd *command.Comm
 	case cmd.Type == "/load":
 		chat.HandleLoadCommand(chatSession, cmd.Args)
 	case cmd.Type == "/prompt":
-		chat.HandlePromptCommand(chatSession, cmd.Raw, cfg)
+		chat.HandlePromptCommand(ctx, chatSession, cmd.Raw, cfg)
 	default:
 		// Check if the input is potentially pasted content (long text, URLs, etc.)
 		if cfg.ConfirmLongInput && shouldConfirmLongInput(cmd.Raw) {
@@ -336,7 +416,7 @@ func handleCommand(chatSession *chat.Chat, cfg *config.Config, cmd *command.Comm
 				return
 			}
 		}
-		chat.ProcessInput(chatSession, cmd.Raw, cfg)
+		chat.ProcessInput(ctx, chatSession, cmd.Raw, cfg)
 	}
 }
 
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The tests run this test binary again as duckchat, in one-shot mode, with
+// the DuckDuckGo chat API answered from the cassettes in reverse/fixtures.
+func TestMain(m *testing.M) {
+	if os.Getenv("DUCKCHAT_REPLAY_TEST") == "1" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+type replayResult struct {
+	stdout   string
+	stderr   string
+	exitCode int
+	retries  int
+}
+
+// replay asks one question with the given fixture and network settings, in a
+// home directory of its own so the user's config and history are untouched
+func replay(t *testing.T, fixture string, network string) replayResult {
+	t.Helper()
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	configFile := filepath.Join(configDir, "duckduckgo-chat-cli", "config.json")
+	if err := os.MkdirAll(filepath.Dir(configFile), 0755); err != nil {
+		t.Fatal(err)
+	}
+	config := `{"tos_accepted": true, "export_dir": ` + quote(filepath.Join(home, "duckchat")) + `, "network": ` + network + `}`
+	if err := os.WriteFile(configFile, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(os.Args[0], "--replay", filepath.Join("..", "..", "reverse", "fixtures", fixture), "-p", "Hi")
+	cmd.Env = append(os.Environ(), "DUCKCHAT_REPLAY_TEST=1", "NO_COLOR=1")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	result := replayResult{}
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			t.Fatalf("cannot run duckchat: %v", err)
+		}
+		result.exitCode = exitErr.ExitCode()
+	}
+	result.stdout = stdout.String()
+	result.stderr = stderr.String()
+	result.retries = strings.Count(result.stderr, "retrying in")
+	return result
+}
+
+func quote(s string) string {
+	return `"` + strings.ReplaceAll(strings.ReplaceAll(s, `\`, `\\`), `"`, `\"`) + `"`
+}
+
+// fastRetries keeps the retry scenarios quick
+const fastRetries = `{"retry_base_delay": 1}`
+
+const fullAnswer = "Hello! How can I help you today?\n"
+
+func TestReplayChatOK(t *testing.T) {
+	result := replay(t, "chat_ok.json", fastRetries)
+	if result.exitCode != 0 {
+		t.Fatalf("exit code %d, want 0\n%s", result.exitCode, result.stderr)
+	}
+	if result.stdout != fullAnswer {
+		t.Errorf("answer %q, want %q", result.stdout, fullAnswer)
+	}
+	if result.retries != 0 {
+		t.Errorf("%d retries, want none", result.retries)
+	}
+}
+
+func TestReplayInvalidVQDRetry(t *testing.T) {
+	result := replay(t, "invalid_vqd_retry.json", fastRetries)
+	if result.exitCode != 0 {
+		t.Fatalf("exit code %d, want 0\n%s", result.exitCode, result.stderr)
+	}
+	if result.stdout != fullAnswer {
+		t.Errorf("answer %q, want %q", result.stdout, fullAnswer)
+	}
+	if result.retries != 1 || !strings.Contains(result.stderr, "Error 400 detected") {
+		t.Errorf("%d retries, want one after the 400\n%s", result.retries, result.stderr)
+	}
+}
+
+func TestReplayTeapotRetry(t *testing.T) {
+	result := replay(t, "teapot_retry.json", fastRetries)
+	if result.exitCode != 0 {
+		t.Fatalf("exit code %d, want 0\n%s", result.exitCode, result.stderr)
+	}
+	if result.stdout != fullAnswer {
+		t.Errorf("answer %q, want %q", result.stdout, fullAnswer)
+	}
+	if result.retries != 1 || !strings.Contains(result.stderr, "Error 418 detected") {
+		t.Errorf("%d retries, want one after the 418\n%s", result.retries, result.stderr)
+	}
+}
+
+func TestReplayRateLimited(t *testing.T) {
+	result := replay(t, "rate_limited.json", fastRetries)
+	if result.exitCode != 1 {
+		t.Fatalf("exit code %d, want 1\n%s", result.exitCode, result.stderr)
+	}
+	if result.stdout != "" {
+		t.Errorf("answer %q, want none", result.stdout)
+	}
+	if result.retries != 3 {
+		t.Errorf("%d retries, want the default 3\n%s", result.retries, result.stderr)
+	}
+	if !strings.Contains(result.stderr, "429") {
+		t.Errorf("the 429 is not reported\n%s", result.stderr)
+	}
+}
+
+func TestReplayRateLimitedWithoutRetries(t *testing.T) {
+	result := replay(t, "rate_limited.json", `{"max_retries": 0}`)
+	if result.exitCode != 1 {
+		t.Fatalf("exit code %d, want 1\n%s", result.exitCode, result.stderr)
+	}
+	if result.retries != 0 {
+		t.Errorf("%d retries, want none with max_retries 0\n%s", result.retries, result.stderr)
+	}
+}
+
+func TestReplayStreamError(t *testing.T) {
+	result := replay(t, "stream_error.json", fastRetries)
+	if result.exitCode != 1 {
+		t.Fatalf("exit code %d, want 1 for an incomplete answer\n%s", result.exitCode, result.stderr)
+	}
+	// What was received is still printed
+	if want := "Hello! How can I\n"; result.stdout != want {
+		t.Errorf("answer %q, want %q", result.stdout, want)
+	}
+	if !strings.Contains(result.stderr, "incomplete") {
+		t.Errorf("the incomplete answer is not reported\n%s", result.stderr)
+	}
+	if result.retries != 0 {
+		t.Errorf("%d retries, want none once the answer started", result.retries)
+	}
+}
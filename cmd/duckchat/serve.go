@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"duckduckgo-chat-cli/internal/api"
+	"duckduckgo-chat-cli/internal/chat"
+	"duckduckgo-chat-cli/internal/config"
+	"duckduckgo-chat-cli/internal/ui"
+)
+
+// runServe runs the API server without the interactive prompt, for systemd
+// units and containers. It never reads from or reconfigures the terminal and
+// returns the process exit code.
+func runServe(args []string) int {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	port := flags.Int("port", 0, "port to listen on (default: the configured API port)")
+	acceptTOS := flags.Bool("accept-tos", false, "accept the DuckDuckGo AI Chat terms of service without prompting")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: duckchat serve [--port N] [--accept-tos]\n\nRun the API server without the interactive prompt.\n\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+
+	cfg = config.Initialize()
+
+	if !cfg.TOSAccepted && !*acceptTOS {
+		ui.Errorln("The terms of service have not been accepted. Run duckchat once interactively, or pass --accept-tos.")
+		return 1
+	}
+
+	if *port == 0 {
+		*port = cfg.API.Port
+	}
+	if *port < 1 || *port > 65535 {
+		ui.Errorln("Invalid port number: %d", *port)
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	ui.Systemln("DuckDuckGo AI Chat CLI %s running in headless mode", Version)
+	chatSession = chat.NewSession(cfg)
+	ui.Systemln("Chat initialized with model: %s", chatSession.Model)
+
+	if err := api.Serve(ctx, chatSession, cfg, *port); err != nil {
+		ui.Errorln("%v", err)
+		return 1
+	}
+	return 0
+}
@@ -24,33 +24,50 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/chat": {
-            "post": {
-                "description": "Send a message to the AI and receive a response",
-                "consumes": [
-                    "application/json"
+        "/archive/search": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
                 ],
+                "description": "Find the saved conversations whose messages contain the query, case-insensitively",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Chat"
+                    "Archive"
                 ],
-                "summary": "Send a chat message",
+                "summary": "Search saved conversations",
                 "parameters": [
                     {
-                        "description": "Chat message request",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/ChatRequest"
-                        }
+                        "type": "string",
+                        "description": "Text to search for",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Maximum number of sessions to return",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Number of sessions to skip",
+                        "name": "offset",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Successful chat response",
+                        "description": "Search completed successfully",
                         "schema": {
                             "allOf": [
                                 {
@@ -60,7 +77,7 @@ const docTemplate = `{
                                     "type": "object",
                                     "properties": {
                                         "data": {
-                                            "$ref": "#/definitions/ChatResponse"
+                                            "$ref": "#/definitions/ArchiveListResponse"
                                         }
                                     }
                                 }
@@ -68,7 +85,7 @@ const docTemplate = `{
                         }
                     },
                     "400": {
-                        "description": "Invalid request",
+                        "description": "Missing query or invalid paging parameters",
                         "schema": {
                             "allOf": [
                                 {
@@ -86,7 +103,7 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "Internal server error",
+                        "description": "History storage cannot be read",
                         "schema": {
                             "allOf": [
                                 {
@@ -106,19 +123,43 @@ const docTemplate = `{
                 }
             }
         },
-        "/health": {
+        "/archive/sessions": {
             "get": {
-                "description": "Check the health status of the API server",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "List the conversations saved by the history manager, most recent first",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Health"
+                    "Archive"
+                ],
+                "summary": "List saved conversations",
+                "parameters": [
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Maximum number of sessions to return",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Number of sessions to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Health check",
                 "responses": {
                     "200": {
-                        "description": "Service is healthy",
+                        "description": "Saved sessions retrieved successfully",
                         "schema": {
                             "allOf": [
                                 {
@@ -128,7 +169,43 @@ const docTemplate = `{
                                     "type": "object",
                                     "properties": {
                                         "data": {
-                                            "$ref": "#/definitions/HealthResponse"
+                                            "$ref": "#/definitions/ArchiveListResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid paging parameters",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "error": {
+                                            "$ref": "#/definitions/APIError"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "History storage cannot be read",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "error": {
+                                            "$ref": "#/definitions/APIError"
                                         }
                                     }
                                 }
@@ -138,35 +215,33 @@ const docTemplate = `{
                 }
             }
         },
-        "/history": {
+        "/archive/sessions/{id}": {
             "get": {
-                "description": "Retrieve the complete chat session history",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get a saved conversation's summary and full transcript",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Chat"
+                    "Archive"
                 ],
-                "summary": "Get chat history",
+                "summary": "Get a saved conversation",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "default": 50,
-                        "description": "Maximum number of messages to return",
-                        "name": "limit",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "default": 0,
-                        "description": "Number of messages to skip",
-                        "name": "offset",
-                        "in": "query"
+                        "type": "string",
+                        "description": "Saved session ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Chat history retrieved successfully",
+                        "description": "Saved session retrieved successfully",
                         "schema": {
                             "allOf": [
                                 {
@@ -176,15 +251,15 @@ const docTemplate = `{
                                     "type": "object",
                                     "properties": {
                                         "data": {
-                                            "$ref": "#/definitions/HistoryResponse"
+                                            "$ref": "#/definitions/ArchiveSessionResponse"
                                         }
                                     }
                                 }
                             ]
                         }
                     },
-                    "400": {
-                        "description": "Invalid query parameters",
+                    "404": {
+                        "description": "Saved session not found",
                         "schema": {
                             "allOf": [
                                 {
@@ -202,39 +277,96 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "Clear the current chat session history",
+            }
+        },
+        "/archive/sessions/{id}/restore": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Replace the live conversation with a saved one, like /load. The current conversation is saved first.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Chat"
+                    "Archive"
+                ],
+                "summary": "Restore a saved conversation",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Saved session ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Clear chat history",
                 "responses": {
                     "200": {
-                        "description": "Chat history cleared successfully",
+                        "description": "Saved session restored successfully",
                         "schema": {
                             "$ref": "#/definitions/APIResponse"
                         }
+                    },
+                    "404": {
+                        "description": "Saved session not found",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "error": {
+                                            "$ref": "#/definitions/APIError"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "503": {
+                        "description": "Session busy, retry after the Retry-After delay",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "error": {
+                                            "$ref": "#/definitions/APIError"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
                     }
                 }
             }
         },
-        "/models": {
+        "/archive/stats": {
             "get": {
-                "description": "Retrieve list of all available AI models",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get the number and size of saved conversations",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Models"
+                    "Archive"
                 ],
-                "summary": "Get available models",
+                "summary": "Get history storage statistics",
                 "responses": {
                     "200": {
-                        "description": "Available models retrieved successfully",
+                        "description": "Storage statistics retrieved successfully",
                         "schema": {
                             "allOf": [
                                 {
@@ -244,7 +376,25 @@ const docTemplate = `{
                                     "type": "object",
                                     "properties": {
                                         "data": {
-                                            "$ref": "#/definitions/ModelsResponse"
+                                            "$ref": "#/definitions/ArchiveStatsResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "History storage cannot be read",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "error": {
+                                            "$ref": "#/definitions/APIError"
                                         }
                                     }
                                 }
@@ -252,33 +402,1261 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
+            }
+        },
+        "/chat": {
             "post": {
-                "description": "Change the current AI model for the chat session",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Send a message to the AI and receive a response.\nWith stream=true the answer is sent as Server-Sent Events: one \"chunk\" event per\npartial response ({\"content\"}), \"metadata\" once the upstream service names the model\n({\"model\",\"id\"}), \"tool\" for other upstream actions ({\"action\",\"data\"}) and \"error\" if\nthe answer fails once started ({\"error\"}), then a \"done\" event carrying the full\nChatResponse and its metadata, including whether the answer was truncated.",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "text/event-stream"
                 ],
                 "tags": [
-                    "Models"
+                    "Chat"
                 ],
-                "summary": "Change AI model",
+                "summary": "Send a chat message",
                 "parameters": [
                     {
-                        "description": "Model change request",
+                        "description": "Chat message request",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/ModelChangeRequest"
+                            "$ref": "#/definitions/ChatRequest"
                         }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Stream the response as Server-Sent Events",
+                        "name": "stream",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Model changed successfully",
+                        "description": "Successful chat response",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/ChatResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request or unknown model",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "error": {
+                                            "$ref": "#/definitions/APIError"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "error": {
+                                            "$ref": "#/definitions/APIError"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "503": {
+                        "description": "Session busy, retry after the Retry-After delay",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "error": {
+                                            "$ref": "#/definitions/APIError"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/context": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "List the files, webpages, search results and projects loaded in the conversation",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Context"
+                ],
+                "summary": "List loaded context",
+                "responses": {
+                    "200": {
+                        "description": "Context retrieved successfully",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/ContextResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/context/file": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Add a file to the conversation, like /file. Send it as multipart form data in the \"file\" field,\nor as the raw request body with its name in the \"name\" query parameter. Files are limited to 10 MB.",
+                "consumes": [
+                    "multipart/form-data",
+                    "application/octet-stream",
+                    "text/plain"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Context"
+                ],
+                "summary": "Load a file as context",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "File to load",
+                        "name": "file",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "default": "upload.txt",
+                        "description": "File name when sending the raw body",
+                        "name": "name",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "File loaded successfully",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/ContextItem"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Missing or unreadable file",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "error": {
+                                            "$ref": "#/definitions/APIError"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "413": {
+                        "description": "File too large",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "error": {
+                                            "$ref": "#/definitions/APIError"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "503": {
+                        "description": "Session busy, retry after the Retry-After delay",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "error": {
+                                            "$ref": "#/definitions/APIError"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/context/library": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Add files from a configured library to the conversation, like /library load. The library is chosen\nby name or number; \"files\" lists paths relative to the library and defaults to every supported file.\nThe files are limited to 10 MB in total.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Context"
+                ],
+                "summary": "Load library files as context",
+                "parameters": [
+                    {
+                        "description": "Library and files to load",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/LibraryContextRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Library files loaded successfully",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/ContextResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request, unknown library or file, or library system disabled",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "error": {
+                                            "$ref": "#/definitions/APIError"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "413": {
+                        "description": "Files too large",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "error": {
+                                            "$ref": "#/definitions/APIError"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "503": {
+                        "description": "Session busy, retry after the Retry-After delay",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "error": {
+                                            "$ref": "#/definitions/APIError"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/context/pmp": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Run PMP (Prompt My Project) and add the generated prompt to the conversation, like /pmp. The project\nmust be a configured library or a directory inside one, so API clients cannot read arbitrary paths,\nand PMP options are not accepted. PMP must already be installed on the server.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Context"
+                ],
+                "summary": "Load a project as context",
+                "parameters": [
+                    {
+                        "description": "Project to load",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/PMPContextRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Project loaded successfully",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/ContextItem"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request, unknown library or path, or library system disabled",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "error": {
+                                            "$ref": "#/definitions/APIError"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "PMP is not installed or failed",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "error": {
+                                            "$ref": "#/definitions/APIError"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "503": {
+                        "description": "Session busy, retry after the Retry-After delay",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "error": {
+                                            "$ref": "#/definitions/APIError"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/context/search": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Search the web and add the results to the conversation, like /search",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Context"
+                ],
+                "summary": "Load search results as context",
+                "parameters": [
+                    {
+                        "description": "Search query",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/SearchContextRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Search results loaded successfully",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/ContextItem"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "error": {
+                                            "$ref": "#/definitions/APIError"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "502": {
+                        "description": "Search failed or returned no results",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "error": {
+                                            "$ref": "#/definitions/APIError"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "503": {
+                        "description": "Session busy, retry after the Retry-After delay",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "error": {
+                                            "$ref": "#/definitions/APIError"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/context/url": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Scrape a webpage and add its content to the conversation, like /url",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Context"
+                ],
+                "summary": "Load a webpage as context",
+                "parameters": [
+                    {
+                        "description": "Webpage to load",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/URLContextRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Webpage loaded successfully",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/ContextItem"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "error": {
+                                            "$ref": "#/definitions/APIError"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "502": {
+                        "description": "Webpage could not be retrieved",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "error": {
+                                            "$ref": "#/definitions/APIError"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "503": {
+                        "description": "Session busy, retry after the Retry-After delay",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "error": {
+                                            "$ref": "#/definitions/APIError"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/health": {
+            "get": {
+                "description": "Check the health status of the API server",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Health"
+                ],
+                "summary": "Health check",
+                "responses": {
+                    "200": {
+                        "description": "Service is healthy",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/HealthResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/history": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Retrieve the complete chat session history",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Chat"
+                ],
+                "summary": "Get chat history",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Maximum number of messages to return",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Number of messages to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Chat history retrieved successfully",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/HistoryResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid query parameters",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "error": {
+                                            "$ref": "#/definitions/APIError"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Clear the current chat session history",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Chat"
+                ],
+                "summary": "Clear chat history",
+                "responses": {
+                    "200": {
+                        "description": "Chat history cleared successfully",
+                        "schema": {
+                            "$ref": "#/definitions/APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/models": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Retrieve the models of the configured provider: the DuckDuckGo models, or the models listed by an OpenAI-compatible server",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Models"
+                ],
+                "summary": "Get available models",
+                "responses": {
+                    "200": {
+                        "description": "Available models retrieved successfully",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/ModelsResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "502": {
+                        "description": "The provider could not list its models",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "error": {
+                                            "$ref": "#/definitions/APIError"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Change the current AI model for the chat session",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Models"
+                ],
+                "summary": "Change AI model",
+                "parameters": [
+                    {
+                        "description": "Model change request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/ModelChangeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Model changed successfully",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/ModelInfo"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request or model not found",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "error": {
+                                            "$ref": "#/definitions/APIError"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/session": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get information about the current chat session",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Session"
+                ],
+                "summary": "Get session information",
+                "responses": {
+                    "200": {
+                        "description": "Session information retrieved successfully",
+                        "schema": {
+                            "$ref": "#/definitions/APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sessions": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Create an isolated chat session with its own history, model and VQD state.\nSessions are evicted after a period of inactivity.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Session"
+                ],
+                "summary": "Create a session",
+                "parameters": [
+                    {
+                        "description": "Session options",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/CreateSessionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Session created successfully",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/SessionResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request or unknown model",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "error": {
+                                            "$ref": "#/definitions/APIError"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "429": {
+                        "description": "Maximum number of sessions reached",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "error": {
+                                            "$ref": "#/definitions/APIError"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/sessions/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get a session's information and message history",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Session"
+                ],
+                "summary": "Get a session",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Session ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Session retrieved successfully",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/SessionResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "Session not found",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "error": {
+                                            "$ref": "#/definitions/APIError"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Delete a session and its message history",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Session"
+                ],
+                "summary": "Delete a session",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Session ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Session deleted successfully",
+                        "schema": {
+                            "$ref": "#/definitions/APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Session not found",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "error": {
+                                            "$ref": "#/definitions/APIError"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/sessions/{id}/chat": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Send a message within an isolated session. Requests on the same session are queued and processed one at a time.\nWith stream=true the answer is sent as Server-Sent Events, like POST /chat.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "Session"
+                ],
+                "summary": "Send a chat message to a session",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Session ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Chat message request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/ChatRequest"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Stream the response as Server-Sent Events",
+                        "name": "stream",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful chat response",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/ChatResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "error": {
+                                            "$ref": "#/definitions/APIError"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "Session not found",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "error": {
+                                            "$ref": "#/definitions/APIError"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
                         "schema": {
                             "allOf": [
                                 {
@@ -287,16 +1665,16 @@ const docTemplate = `{
                                 {
                                     "type": "object",
                                     "properties": {
-                                        "data": {
-                                            "$ref": "#/definitions/ModelInfo"
+                                        "error": {
+                                            "$ref": "#/definitions/APIError"
                                         }
                                     }
                                 }
                             ]
                         }
                     },
-                    "400": {
-                        "description": "Invalid request or model not found",
+                    "503": {
+                        "description": "Session busy, retry after the Retry-After delay",
                         "schema": {
                             "allOf": [
                                 {
@@ -315,26 +1693,6 @@ const docTemplate = `{
                     }
                 }
             }
-        },
-        "/session": {
-            "get": {
-                "description": "Get information about the current chat session",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "Session"
-                ],
-                "summary": "Get session information",
-                "responses": {
-                    "200": {
-                        "description": "Session information retrieved successfully",
-                        "schema": {
-                            "$ref": "#/definitions/APIResponse"
-                        }
-                    }
-                }
-            }
         }
     },
     "definitions": {
@@ -378,6 +1736,153 @@ const docTemplate = `{
                 }
             }
         },
+        "ArchiveListResponse": {
+            "description": "Page of saved conversations, most recent first",
+            "type": "object",
+            "properties": {
+                "limit": {
+                    "type": "integer",
+                    "example": 20
+                },
+                "offset": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "sessions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/ArchivedSession"
+                    }
+                },
+                "total": {
+                    "type": "integer",
+                    "example": 42
+                }
+            }
+        },
+        "ArchiveSessionResponse": {
+            "description": "Saved conversation with its summary and full transcript",
+            "type": "object",
+            "properties": {
+                "compressed": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "end_time": {
+                    "type": "string",
+                    "example": "2023-01-01T12:30:00Z"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "session_1700000000000000000"
+                },
+                "message_count": {
+                    "type": "integer",
+                    "example": 12
+                },
+                "messages": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/MessageResponse"
+                    }
+                },
+                "model": {
+                    "type": "string",
+                    "example": "gpt-4o-mini"
+                },
+                "start_time": {
+                    "type": "string",
+                    "example": "2023-01-01T12:00:00Z"
+                },
+                "summary": {
+                    "$ref": "#/definitions/ArchiveSummary"
+                }
+            }
+        },
+        "ArchiveStatsResponse": {
+            "description": "History storage statistics",
+            "type": "object",
+            "properties": {
+                "compressed_sessions": {
+                    "type": "integer",
+                    "example": 42
+                },
+                "newest_session": {
+                    "type": "string",
+                    "example": "2023-01-31T12:00:00Z"
+                },
+                "oldest_session": {
+                    "type": "string",
+                    "example": "2023-01-01T12:00:00Z"
+                },
+                "total_sessions": {
+                    "type": "integer",
+                    "example": 42
+                },
+                "total_size_bytes": {
+                    "type": "integer",
+                    "example": 1048576
+                }
+            }
+        },
+        "ArchiveSummary": {
+            "description": "Summary of a saved conversation",
+            "type": "object",
+            "properties": {
+                "duration_seconds": {
+                    "type": "integer",
+                    "example": 1800
+                },
+                "first_message": {
+                    "type": "string",
+                    "example": "How do I write a REST API in Go?"
+                },
+                "key_topics": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "go",
+                        "api"
+                    ]
+                },
+                "last_message": {
+                    "type": "string",
+                    "example": "Can you add authentication?"
+                }
+            }
+        },
+        "ArchivedSession": {
+            "description": "Saved conversation metadata",
+            "type": "object",
+            "properties": {
+                "compressed": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "end_time": {
+                    "type": "string",
+                    "example": "2023-01-01T12:30:00Z"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "session_1700000000000000000"
+                },
+                "message_count": {
+                    "type": "integer",
+                    "example": 12
+                },
+                "model": {
+                    "type": "string",
+                    "example": "gpt-4o-mini"
+                },
+                "start_time": {
+                    "type": "string",
+                    "example": "2023-01-01T12:00:00Z"
+                }
+            }
+        },
         "ChatMetadata": {
             "description": "Metadata about the chat response",
             "type": "object",
@@ -386,13 +1891,25 @@ const docTemplate = `{
                     "type": "integer",
                     "example": 3
                 },
+                "finish_reason": {
+                    "type": "string",
+                    "example": "stop"
+                },
                 "processing_time_ms": {
                     "type": "integer",
                     "example": 1500
                 },
+                "served_model": {
+                    "type": "string",
+                    "example": "gpt-4o-mini-2024-07-18"
+                },
                 "tokens_estimate": {
                     "type": "integer",
                     "example": 45
+                },
+                "truncated": {
+                    "type": "boolean",
+                    "example": false
                 }
             }
         },
@@ -412,6 +1929,10 @@ const docTemplate = `{
                 "model": {
                     "type": "string",
                     "example": "gpt-4o-mini"
+                },
+                "stream": {
+                    "type": "boolean",
+                    "example": false
                 }
             }
         },
@@ -421,7 +1942,7 @@ const docTemplate = `{
             "properties": {
                 "message_id": {
                     "type": "string",
-                    "example": "msg_123456"
+                    "example": "msg_5f2b9c0e1a7d3b64"
                 },
                 "metadata": {
                     "$ref": "#/definitions/ChatMetadata"
@@ -436,6 +1957,58 @@ const docTemplate = `{
                 }
             }
         },
+        "ContextItem": {
+            "description": "Context loaded in the conversation",
+            "type": "object",
+            "properties": {
+                "characters": {
+                    "type": "integer",
+                    "example": 1024
+                },
+                "index": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "kind": {
+                    "type": "string",
+                    "example": "file"
+                },
+                "source": {
+                    "type": "string",
+                    "example": "main.go"
+                }
+            }
+        },
+        "ContextResponse": {
+            "description": "Context loaded in the conversation",
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/ContextItem"
+                    }
+                },
+                "total_characters": {
+                    "type": "integer",
+                    "example": 4096
+                },
+                "total_items": {
+                    "type": "integer",
+                    "example": 2
+                }
+            }
+        },
+        "CreateSessionRequest": {
+            "description": "Session creation request payload",
+            "type": "object",
+            "properties": {
+                "model": {
+                    "type": "string",
+                    "example": "gpt-4o-mini"
+                }
+            }
+        },
         "HealthResponse": {
             "description": "Health check response payload",
             "type": "object",
@@ -488,6 +2061,28 @@ const docTemplate = `{
                 }
             }
         },
+        "LibraryContextRequest": {
+            "description": "Library context request payload",
+            "type": "object",
+            "required": [
+                "library"
+            ],
+            "properties": {
+                "files": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "notes/todo.md"
+                    ]
+                },
+                "library": {
+                    "type": "string",
+                    "example": "my_docs"
+                }
+            }
+        },
         "MessageResponse": {
             "description": "Individual message in chat history",
             "type": "object",
@@ -498,12 +2093,24 @@ const docTemplate = `{
                 },
                 "id": {
                     "type": "string",
-                    "example": "msg_123456"
+                    "example": "msg_5f2b9c0e1a7d3b64"
+                },
+                "latency_ms": {
+                    "type": "integer",
+                    "example": 1500
+                },
+                "model": {
+                    "type": "string",
+                    "example": "gpt-4o-mini"
                 },
                 "role": {
                     "type": "string",
                     "example": "user"
                 },
+                "source": {
+                    "type": "string",
+                    "example": "typed"
+                },
                 "timestamp": {
                     "type": "string",
                     "example": "2023-01-01T12:00:00Z"
@@ -527,6 +2134,16 @@ const docTemplate = `{
             "description": "Information about an available model",
             "type": "object",
             "properties": {
+                "aliases": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "context_window": {
+                    "type": "integer",
+                    "example": 50000
+                },
                 "description": {
                     "type": "string",
                     "example": "Fast and efficient model for general conversations"
@@ -542,6 +2159,20 @@ const docTemplate = `{
                 "name": {
                     "type": "string",
                     "example": "GPT-4o-mini"
+                },
+                "strengths": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "general",
+                        "fast"
+                    ]
+                },
+                "upstream_id": {
+                    "type": "string",
+                    "example": "gpt-4o-mini"
                 }
             }
         },
@@ -564,11 +2195,94 @@ const docTemplate = `{
                     "example": 5
                 }
             }
+        },
+        "PMPContextRequest": {
+            "description": "PMP context request payload",
+            "type": "object",
+            "required": [
+                "library"
+            ],
+            "properties": {
+                "library": {
+                    "type": "string",
+                    "example": "my_project"
+                },
+                "path": {
+                    "type": "string",
+                    "example": "internal/api"
+                }
+            }
+        },
+        "SearchContextRequest": {
+            "description": "Search context request payload",
+            "type": "object",
+            "required": [
+                "query"
+            ],
+            "properties": {
+                "query": {
+                    "type": "string",
+                    "example": "golang generics tutorial"
+                }
+            }
+        },
+        "SessionResponse": {
+            "description": "Information about an API session",
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string",
+                    "example": "2023-01-01T12:00:00Z"
+                },
+                "expires_at": {
+                    "type": "string",
+                    "example": "2023-01-01T12:35:00Z"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "sess_9f86d081884c7d659a2feaa0c55ad015"
+                },
+                "last_activity": {
+                    "type": "string",
+                    "example": "2023-01-01T12:05:00Z"
+                },
+                "message_count": {
+                    "type": "integer",
+                    "example": 4
+                },
+                "messages": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/MessageResponse"
+                    }
+                },
+                "model": {
+                    "type": "string",
+                    "example": "gpt-4o-mini"
+                },
+                "queue_depth": {
+                    "type": "integer",
+                    "example": 0
+                }
+            }
+        },
+        "URLContextRequest": {
+            "description": "URL context request payload",
+            "type": "object",
+            "required": [
+                "url"
+            ],
+            "properties": {
+                "url": {
+                    "type": "string",
+                    "example": "https://go.dev/doc/effective_go"
+                }
+            }
         }
     },
     "securityDefinitions": {
         "ApiKeyAuth": {
-            "description": "API key authorization. Example: \"Bearer {api_key}\"",
+            "description": "API key authorization. Example: \"Bearer {api_key}\". The key can also be sent in the X-API-Key header.",
             "type": "apiKey",
             "name": "Authorization",
             "in": "header"
@@ -0,0 +1,193 @@
+package analytics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// responseTimeBuckets are the upper bounds, in seconds, of the response time histograms
+var responseTimeBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// chatSeries identifies an upstream chat request counter
+type chatSeries struct {
+	model     string
+	success   bool
+	errorType string
+}
+
+// apiSeries identifies a REST API call counter
+type apiSeries struct {
+	success   bool
+	errorType string
+}
+
+// histogram is a minimal Prometheus-style histogram over responseTimeBuckets
+type histogram struct {
+	buckets []uint64 // non-cumulative counts, one per bucket
+	count   uint64
+	sum     float64
+}
+
+func newHistogram() *histogram {
+	return &histogram{buckets: make([]uint64, len(responseTimeBuckets))}
+}
+
+func (h *histogram) observe(d time.Duration) {
+	seconds := d.Seconds()
+	h.count++
+	h.sum += seconds
+	for i, bound := range responseTimeBuckets {
+		if seconds <= bound {
+			h.buckets[i]++
+			return
+		}
+	}
+}
+
+// WritePrometheus writes the analytics counters and histograms in the
+// Prometheus text exposition format.
+func (ca *ChatAnalytics) WritePrometheus(w io.Writer) {
+	ca.mutex.RLock()
+	defer ca.mutex.RUnlock()
+
+	writeHeader(w, "duckchat_chat_requests_total", "counter", "Upstream chat requests by model, result and error type.")
+	chatKeys := make([]chatSeries, 0, len(ca.chatRequests))
+	for key := range ca.chatRequests {
+		chatKeys = append(chatKeys, key)
+	}
+	sort.Slice(chatKeys, func(i, j int) bool {
+		return fmt.Sprint(chatKeys[i]) < fmt.Sprint(chatKeys[j])
+	})
+	for _, key := range chatKeys {
+		fmt.Fprintf(w, "duckchat_chat_requests_total{model=%q,result=%q,error_type=%q} %d\n",
+			escapeLabel(key.model), result(key.success), escapeLabel(key.errorType), ca.chatRequests[key])
+	}
+
+	writeHeader(w, "duckchat_chat_response_seconds", "histogram", "Upstream chat response time by model.")
+	models := make([]string, 0, len(ca.chatLatency))
+	for model := range ca.chatLatency {
+		models = append(models, model)
+	}
+	sort.Strings(models)
+	for _, model := range models {
+		writeHistogram(w, "duckchat_chat_response_seconds", fmt.Sprintf("model=%q", escapeLabel(model)), ca.chatLatency[model])
+	}
+
+	writeHeader(w, "duckchat_api_requests_total", "counter", "REST API calls by result and error type.")
+	apiKeys := make([]apiSeries, 0, len(ca.apiRequests))
+	for key := range ca.apiRequests {
+		apiKeys = append(apiKeys, key)
+	}
+	sort.Slice(apiKeys, func(i, j int) bool {
+		return fmt.Sprint(apiKeys[i]) < fmt.Sprint(apiKeys[j])
+	})
+	for _, key := range apiKeys {
+		fmt.Fprintf(w, "duckchat_api_requests_total{result=%q,error_type=%q} %d\n",
+			result(key.success), escapeLabel(key.errorType), ca.apiRequests[key])
+	}
+
+	writeHeader(w, "duckchat_api_response_seconds", "histogram", "REST API response time.")
+	writeHistogram(w, "duckchat_api_response_seconds", "", ca.apiLatency)
+
+	writeHeader(w, "duckchat_upstream_retries_total", "counter", "Upstream chat requests sent again, by the status that caused the retry.")
+	statuses := make([]string, 0, len(ca.retries))
+	for status := range ca.retries {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+	for _, status := range statuses {
+		fmt.Fprintf(w, "duckchat_upstream_retries_total{status=%q} %d\n", escapeLabel(status), ca.retries[status])
+	}
+
+	writeCounter(w, "duckchat_vqd_refreshes_total", "VQD token refreshes after upstream errors.", ca.VQDRefreshCount)
+	writeCounter(w, "duckchat_header_refreshes_total", "Dynamic header refreshes.", ca.HeaderRefreshCount)
+	writeCounter(w, "duckchat_truncated_answers_total", "Answers cut short by an upstream error or the output limit.", ca.TruncatedAnswers)
+	writeCounter(w, "duckchat_context_optimizations_total", "Context optimizations performed.", ca.ContextOptimizations)
+	writeCounter(w, "duckchat_context_compressions_total", "Context compressions performed.", ca.ContextCompressions)
+	writeCounter(w, "duckchat_context_bytes_saved_total", "Bytes saved by context optimization.", int(ca.BytesSaved))
+	writeCounter(w, "duckchat_tokens_estimate_total", "Estimated tokens exchanged.", ca.TotalTokensEstimate)
+	writeCounter(w, "duckchat_model_changes_total", "Model changes.", ca.ModelChanges)
+	writeCounter(w, "duckchat_files_processed_total", "Files added as context.", ca.FilesProcessed)
+	writeCounter(w, "duckchat_urls_processed_total", "URLs added as context.", ca.URLsProcessed)
+	writeCounter(w, "duckchat_searches_total", "Web searches performed.", ca.SearchesPerformed)
+
+	writeHeader(w, "duckchat_messages_total", "counter", "Messages by role.")
+	fmt.Fprintf(w, "duckchat_messages_total{role=\"user\"} %d\n", ca.UserMessages)
+	fmt.Fprintf(w, "duckchat_messages_total{role=\"assistant\"} %d\n", ca.AssistantMessages)
+	fmt.Fprintf(w, "duckchat_messages_total{role=\"context\"} %d\n", ca.ContextMessages)
+
+	writeHeader(w, "duckchat_served_answers_total", "counter", "Answers by the model the upstream service reported.")
+	served := make([]string, 0, len(ca.ServedModels))
+	for model := range ca.ServedModels {
+		served = append(served, model)
+	}
+	sort.Strings(served)
+	for _, model := range served {
+		fmt.Fprintf(w, "duckchat_served_answers_total{model=%q} %d\n", escapeLabel(model), ca.ServedModels[model])
+	}
+
+	writeHeader(w, "duckchat_commands_total", "counter", "Commands used in the terminal.")
+	commands := make([]string, 0, len(ca.CommandsUsed))
+	for command := range ca.CommandsUsed {
+		commands = append(commands, command)
+	}
+	sort.Strings(commands)
+	for _, command := range commands {
+		fmt.Fprintf(w, "duckchat_commands_total{command=%q} %d\n", escapeLabel(command), ca.CommandsUsed[command])
+	}
+
+	writeHeader(w, "duckchat_session_start_time_seconds", "gauge", "Start time of the session since unix epoch in seconds.")
+	fmt.Fprintf(w, "duckchat_session_start_time_seconds %d\n", ca.SessionStartTime.Unix())
+}
+
+func writeHeader(w io.Writer, name, metricType, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+func writeCounter(w io.Writer, name, help string, value int) {
+	writeHeader(w, name, "counter", help)
+	fmt.Fprintf(w, "%s %d\n", name, value)
+}
+
+func writeHistogram(w io.Writer, name, labels string, h *histogram) {
+	prefix := labels
+	if prefix != "" {
+		prefix += ","
+	}
+
+	var cumulative uint64
+	for i, bound := range responseTimeBuckets {
+		cumulative += h.buckets[i]
+		fmt.Fprintf(w, "%s_bucket{%sle=%q} %d\n", name, prefix, strconv.FormatFloat(bound, 'g', -1, 64), cumulative)
+	}
+	fmt.Fprintf(w, "%s_bucket{%sle=\"+Inf\"} %d\n", name, prefix, h.count)
+
+	if labels != "" {
+		labels = "{" + labels + "}"
+	}
+	fmt.Fprintf(w, "%s_sum%s %s\n", name, labels, strconv.FormatFloat(h.sum, 'g', -1, 64))
+	fmt.Fprintf(w, "%s_count%s %d\n", name, labels, h.count)
+}
+
+func result(success bool) string {
+	if success {
+		return "success"
+	}
+	return "error"
+}
+
+// escapeLabel prepares a label value for %q formatting. %q already escapes
+// backslashes, quotes and newlines the way Prometheus expects, but it would
+// turn other non-printable characters into Go escapes, so those are dropped.
+func escapeLabel(value string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\n' || r >= ' ' {
+			return r
+		}
+		return -1
+	}, value)
+}
@@ -38,7 +38,9 @@ type ChatAnalytics struct {
 	Error429Count      int `json:"error_429_count"`
 	OtherErrorsCount   int `json:"other_errors_count"`
 	VQDRefreshCount    int `json:"vqd_refresh_count"`
+	UpstreamRetries    int `json:"upstream_retries"` // requests sent again after a 418, 429 or 503
 	HeaderRefreshCount int `json:"header_refresh_count"`
+	TruncatedAnswers   int `json:"truncated_answers"` // answers cut short by an upstream error or the output limit
 
 	// Content Metrics
 	MessagesTotal       int `json:"messages_total"`
@@ -56,14 +58,22 @@ type ChatAnalytics struct {
 	CommandsUsed map[string]int `json:"commands_used"`
 
 	// Model Changes
-	ModelChanges int    `json:"model_changes"`
-	CurrentModel string `json:"current_model"`
+	ModelChanges int            `json:"model_changes"`
+	CurrentModel string         `json:"current_model"`
+	ServedModels map[string]int `json:"served_models"` // answers by the model the upstream service reported
 
 	// Files and URLs
 	FilesProcessed    int `json:"files_processed"`
 	URLsProcessed     int `json:"urls_processed"`
 	SearchesPerformed int `json:"searches_performed"`
 
+	// Labelled series for the Prometheus exporter
+	chatRequests map[chatSeries]int
+	chatLatency  map[string]*histogram // by model
+	retries      map[string]int        // by upstream status
+	apiRequests  map[apiSeries]int
+	apiLatency   *histogram
+
 	mutex sync.RWMutex
 }
 
@@ -72,15 +82,31 @@ func NewChatAnalytics() *ChatAnalytics {
 	return &ChatAnalytics{
 		SessionStartTime: time.Now(),
 		CommandsUsed:     make(map[string]int),
+		ServedModels:     make(map[string]int),
+		chatRequests:     make(map[chatSeries]int),
+		chatLatency:      make(map[string]*histogram),
+		retries:          make(map[string]int),
+		apiRequests:      make(map[apiSeries]int),
+		apiLatency:       newHistogram(),
 		mutex:            sync.RWMutex{},
 	}
 }
 
-// Chat Interaction Tracking (for CLI usage)
-func (ca *ChatAnalytics) RecordChatInteraction(duration time.Duration, success bool, errorType string) {
+// Chat Interaction Tracking (for CLI usage). Each request is recorded once,
+// with its final outcome; retries are counted by RecordUpstreamRetry.
+func (ca *ChatAnalytics) RecordChatInteraction(model string, duration time.Duration, success bool, errorType string) {
 	ca.mutex.Lock()
 	defer ca.mutex.Unlock()
 
+	if success {
+		errorType = ""
+	}
+	ca.chatRequests[chatSeries{model: model, success: success, errorType: errorType}]++
+	if ca.chatLatency[model] == nil {
+		ca.chatLatency[model] = newHistogram()
+	}
+	ca.chatLatency[model].observe(duration)
+
 	ca.ChatInteractionsTotal++
 	ca.TotalChatResponseTime += duration
 	ca.AverageChatResponseTime = ca.TotalChatResponseTime / time.Duration(ca.ChatInteractionsTotal)
@@ -105,6 +131,12 @@ func (ca *ChatAnalytics) RecordAPICall(duration time.Duration, success bool, err
 	ca.mutex.Lock()
 	defer ca.mutex.Unlock()
 
+	if success {
+		errorType = ""
+	}
+	ca.apiRequests[apiSeries{success: success, errorType: errorType}]++
+	ca.apiLatency.observe(duration)
+
 	ca.APICallsTotal++
 	ca.TotalAPIResponseTime += duration
 	ca.AverageAPIResponseTime = ca.TotalAPIResponseTime / time.Duration(ca.APICallsTotal)
@@ -141,6 +173,25 @@ func (ca *ChatAnalytics) RecordMessage(role string, contentLength int) {
 	}
 }
 
+// RecordMessageRemoved reverses RecordMessage for a message taken back out of
+// the conversation by /retry, /edit or /undo
+func (ca *ChatAnalytics) RecordMessageRemoved(role string, contentLength int) {
+	ca.mutex.Lock()
+	defer ca.mutex.Unlock()
+
+	ca.MessagesTotal = max(ca.MessagesTotal-1, 0)
+	ca.TotalTokensEstimate = max(ca.TotalTokensEstimate-estimateTokens(contentLength), 0)
+
+	switch role {
+	case "user":
+		ca.UserMessages = max(ca.UserMessages-1, 0)
+	case "assistant":
+		ca.AssistantMessages = max(ca.AssistantMessages-1, 0)
+	default:
+		ca.ContextMessages = max(ca.ContextMessages-1, 0)
+	}
+}
+
 // Command Usage Tracking
 func (ca *ChatAnalytics) RecordCommand(command string) {
 	ca.mutex.Lock()
@@ -176,6 +227,16 @@ func (ca *ChatAnalytics) RecordVQDRefresh() {
 	ca.VQDRefreshCount++
 }
 
+// RecordUpstreamRetry records a request sent again after the upstream
+// service answered with the given status
+func (ca *ChatAnalytics) RecordUpstreamRetry(status int) {
+	ca.mutex.Lock()
+	defer ca.mutex.Unlock()
+
+	ca.UpstreamRetries++
+	ca.retries[fmt.Sprint(status)]++
+}
+
 func (ca *ChatAnalytics) RecordHeaderRefresh() {
 	ca.mutex.Lock()
 	defer ca.mutex.Unlock()
@@ -183,6 +244,14 @@ func (ca *ChatAnalytics) RecordHeaderRefresh() {
 	ca.HeaderRefreshCount++
 }
 
+// HeaderRefreshes returns how many times the session tried to get new headers
+func (ca *ChatAnalytics) HeaderRefreshes() int {
+	ca.mutex.RLock()
+	defer ca.mutex.RUnlock()
+
+	return ca.HeaderRefreshCount
+}
+
 // Content Processing Tracking
 func (ca *ChatAnalytics) RecordFileProcessed() {
 	ca.mutex.Lock()
@@ -213,6 +282,26 @@ func (ca *ChatAnalytics) RecordModelChange(newModel string) {
 	ca.CurrentModel = newModel
 }
 
+// RecordServedModel records the model the upstream service says answered,
+// which can be a more precise version than the one requested
+func (ca *ChatAnalytics) RecordServedModel(model string) {
+	ca.mutex.Lock()
+	defer ca.mutex.Unlock()
+
+	if ca.ServedModels == nil {
+		ca.ServedModels = make(map[string]int)
+	}
+	ca.ServedModels[model]++
+}
+
+// RecordTruncatedAnswer records an answer cut short upstream
+func (ca *ChatAnalytics) RecordTruncatedAnswer() {
+	ca.mutex.Lock()
+	defer ca.mutex.Unlock()
+
+	ca.TruncatedAnswers++
+}
+
 // Session Management
 func (ca *ChatAnalytics) EndSession() {
 	ca.mutex.Lock()
@@ -250,6 +339,12 @@ func (ca *ChatAnalytics) DisplayStatistics() {
 		if ca.ChatInteractionsFailed > 0 {
 			ui.Warningln("  Errors: 418=%d, 429=%d, Other=%d", ca.Error418Count, ca.Error429Count, ca.OtherErrorsCount)
 		}
+		if ca.UpstreamRetries > 0 {
+			ui.Warningln("  Retries: %d", ca.UpstreamRetries)
+		}
+		if ca.TruncatedAnswers > 0 {
+			ui.Warningln("  Incomplete answers: %d", ca.TruncatedAnswers)
+		}
 	}
 
 	// REST API Usage (only if there were actual API calls)
@@ -296,6 +391,9 @@ func (ca *ChatAnalytics) DisplayStatistics() {
 		if ca.ModelChanges > 0 {
 			ui.Whiteln("  Changes: %d", ca.ModelChanges)
 		}
+		for served, count := range ca.ServedModels {
+			ui.Whiteln("  Served by %s: %d", served, count)
+		}
 	}
 
 	// Performance Summary
@@ -0,0 +1,314 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"duckduckgo-chat-cli/internal/chat"
+	"duckduckgo-chat-cli/internal/config"
+	"duckduckgo-chat-cli/internal/persistence"
+	"duckduckgo-chat-cli/internal/ui"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultArchivePageSize = 20
+	maxArchivePageSize     = 100
+)
+
+// ArchiveListHandler lists saved conversations
+// @Summary      List saved conversations
+// @Description  List the conversations saved by the history manager, most recent first
+// @Tags         Archive
+// @Produce      json
+// @Param        limit query int false "Maximum number of sessions to return" default(20) minimum(1) maximum(100)
+// @Param        offset query int false "Number of sessions to skip" default(0) minimum(0)
+// @Success      200 {object} APIResponse{data=ArchiveListResponse} "Saved sessions retrieved successfully"
+// @Failure      400 {object} APIResponse{error=APIError} "Invalid paging parameters"
+// @Failure      500 {object} APIResponse{error=APIError} "History storage cannot be read"
+// @Security     ApiKeyAuth
+// @Router       /archive/sessions [get]
+func ArchiveListHandler(chatSession *chat.Chat) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestStartTime := time.Now()
+
+		limit, offset, err := parsePaging(c)
+		if err != nil {
+			recordAPICall(chatSession, requestStartTime, false, "validation")
+			response := NewErrorResponse(ErrorCodeValidation, "Invalid paging parameters", err.Error())
+			c.JSON(http.StatusBadRequest, response)
+			return
+		}
+
+		saved, err := chatSession.HistoryManager.ListSessions()
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			recordAPICall(chatSession, requestStartTime, false, "storage_error")
+			response := NewErrorResponse(ErrorCodeInternal, "Cannot list saved sessions", err.Error())
+			c.JSON(http.StatusInternalServerError, response)
+			return
+		}
+
+		recordAPICall(chatSession, requestStartTime, true, "")
+		successResponse := NewSuccessResponse(archivePage(saved, limit, offset), "Saved sessions retrieved successfully")
+		c.JSON(http.StatusOK, successResponse)
+	}
+}
+
+// ArchiveSearchHandler searches saved conversations
+// @Summary      Search saved conversations
+// @Description  Find the saved conversations whose messages contain the query, case-insensitively
+// @Tags         Archive
+// @Produce      json
+// @Param        q query string true "Text to search for"
+// @Param        limit query int false "Maximum number of sessions to return" default(20) minimum(1) maximum(100)
+// @Param        offset query int false "Number of sessions to skip" default(0) minimum(0)
+// @Success      200 {object} APIResponse{data=ArchiveListResponse} "Search completed successfully"
+// @Failure      400 {object} APIResponse{error=APIError} "Missing query or invalid paging parameters"
+// @Failure      500 {object} APIResponse{error=APIError} "History storage cannot be read"
+// @Security     ApiKeyAuth
+// @Router       /archive/search [get]
+func ArchiveSearchHandler(chatSession *chat.Chat) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestStartTime := time.Now()
+
+		query := strings.TrimSpace(c.Query("q"))
+		if query == "" {
+			recordAPICall(chatSession, requestStartTime, false, "validation")
+			response := NewErrorResponse(ErrorCodeValidation, "Missing search query", "The 'q' query parameter is required")
+			c.JSON(http.StatusBadRequest, response)
+			return
+		}
+
+		limit, offset, err := parsePaging(c)
+		if err != nil {
+			recordAPICall(chatSession, requestStartTime, false, "validation")
+			response := NewErrorResponse(ErrorCodeValidation, "Invalid paging parameters", err.Error())
+			c.JSON(http.StatusBadRequest, response)
+			return
+		}
+
+		matches, err := chatSession.HistoryManager.SearchSessions(query)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			recordAPICall(chatSession, requestStartTime, false, "storage_error")
+			response := NewErrorResponse(ErrorCodeInternal, "Cannot search saved sessions", err.Error())
+			c.JSON(http.StatusInternalServerError, response)
+			return
+		}
+
+		recordAPICall(chatSession, requestStartTime, true, "")
+		successResponse := NewSuccessResponse(archivePage(matches, limit, offset), fmt.Sprintf("%d saved sessions match '%s'", len(matches), query))
+		c.JSON(http.StatusOK, successResponse)
+	}
+}
+
+// ArchiveSessionHandler returns a saved conversation
+// @Summary      Get a saved conversation
+// @Description  Get a saved conversation's summary and full transcript
+// @Tags         Archive
+// @Produce      json
+// @Param        id path string true "Saved session ID"
+// @Success      200 {object} APIResponse{data=ArchiveSessionResponse} "Saved session retrieved successfully"
+// @Failure      404 {object} APIResponse{error=APIError} "Saved session not found"
+// @Security     ApiKeyAuth
+// @Router       /archive/sessions/{id} [get]
+func ArchiveSessionHandler(chatSession *chat.Chat) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestStartTime := time.Now()
+		id := c.Param("id")
+
+		if !isValidArchiveID(id) {
+			recordAPICall(chatSession, requestStartTime, false, "session_not_found")
+			c.JSON(http.StatusNotFound, archiveNotFound(id))
+			return
+		}
+
+		session, err := chatSession.HistoryManager.LoadSession(id)
+		if err != nil {
+			recordAPICall(chatSession, requestStartTime, false, "session_not_found")
+			c.JSON(http.StatusNotFound, archiveNotFound(id))
+			return
+		}
+		summary, err := chatSession.HistoryManager.GetSessionSummary(id)
+		if err != nil {
+			recordAPICall(chatSession, requestStartTime, false, "storage_error")
+			response := NewErrorResponse(ErrorCodeInternal, "Cannot summarize saved session", err.Error())
+			c.JSON(http.StatusInternalServerError, response)
+			return
+		}
+
+		messages := make([]MessageResponse, len(session.Messages))
+		for i, msg := range session.Messages {
+			id := msg.ID
+			if id == "" {
+				id = fmt.Sprintf("msg_%d", i) // saved before messages had an ID
+			}
+			messages[i] = MessageResponse{
+				ID:        id,
+				Role:      msg.Role,
+				Content:   msg.Content,
+				Timestamp: msg.Timestamp,
+				Model:     msg.Model,
+				LatencyMs: msg.Latency.Milliseconds(),
+				Source:    msg.Source,
+			}
+		}
+
+		topics := summary.KeyTopics
+		if topics == nil {
+			topics = []string{}
+		}
+
+		recordAPICall(chatSession, requestStartTime, true, "")
+		successResponse := NewSuccessResponse(ArchiveSessionResponse{
+			ArchivedSession: archivedSession(*session),
+			Summary: ArchiveSummary{
+				DurationSeconds: int64(summary.Duration.Seconds()),
+				FirstMessage:    summary.FirstMessage,
+				LastMessage:     summary.LastMessage,
+				KeyTopics:       topics,
+			},
+			Messages: messages,
+		}, "Saved session retrieved successfully")
+		c.JSON(http.StatusOK, successResponse)
+	}
+}
+
+// ArchiveRestoreHandler loads a saved conversation into the live chat
+// @Summary      Restore a saved conversation
+// @Description  Replace the live conversation with a saved one, like /load. The current conversation is saved first.
+// @Tags         Archive
+// @Produce      json
+// @Param        id path string true "Saved session ID"
+// @Success      200 {object} APIResponse "Saved session restored successfully"
+// @Failure      404 {object} APIResponse{error=APIError} "Saved session not found"
+// @Failure      503 {object} APIResponse{error=APIError} "Session busy, retry after the Retry-After delay"
+// @Security     ApiKeyAuth
+// @Router       /archive/sessions/{id}/restore [post]
+func ArchiveRestoreHandler(chatSession *chat.Chat, cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestStartTime := time.Now()
+		id := c.Param("id")
+
+		if !isValidArchiveID(id) {
+			recordAPICall(chatSession, requestStartTime, false, "session_not_found")
+			c.JSON(http.StatusNotFound, archiveNotFound(id))
+			return
+		}
+
+		if !acquireTurn(c, chatSession) {
+			return
+		}
+		err := chatSession.LoadSavedSession(id)
+		chatSession.Release()
+
+		if err != nil {
+			recordAPICall(chatSession, requestStartTime, false, "session_not_found")
+			c.JSON(http.StatusNotFound, archiveNotFound(id))
+			return
+		}
+
+		if cfg.API.LogRequests {
+			ui.APILog("Restored saved session %s for %s", id, c.ClientIP())
+		}
+
+		state := chatSession.State()
+		recordAPICall(chatSession, requestStartTime, true, "")
+		successResponse := NewSuccessResponse(map[string]interface{}{
+			"session_id":    state.SessionID,
+			"current_model": string(state.Model),
+			"message_count": state.MessageCount,
+		}, fmt.Sprintf("Session %s restored successfully", id))
+		c.JSON(http.StatusOK, successResponse)
+	}
+}
+
+// ArchiveStatsHandler returns history storage statistics
+// @Summary      Get history storage statistics
+// @Description  Get the number and size of saved conversations
+// @Tags         Archive
+// @Produce      json
+// @Success      200 {object} APIResponse{data=ArchiveStatsResponse} "Storage statistics retrieved successfully"
+// @Failure      500 {object} APIResponse{error=APIError} "History storage cannot be read"
+// @Security     ApiKeyAuth
+// @Router       /archive/stats [get]
+func ArchiveStatsHandler(chatSession *chat.Chat) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestStartTime := time.Now()
+
+		response := ArchiveStatsResponse{}
+		stats, err := chatSession.HistoryManager.GetStorageStats()
+		switch {
+		case err == nil:
+			response.TotalSessions = stats.TotalSessions
+			response.CompressedSessions = stats.CompressedSessions
+			response.TotalSizeBytes = stats.TotalSizeBytes
+			if stats.TotalSessions > 0 {
+				response.OldestSession = &stats.OldestSession
+				response.NewestSession = &stats.NewestSession
+			}
+		case !errors.Is(err, fs.ErrNotExist):
+			recordAPICall(chatSession, requestStartTime, false, "storage_error")
+			errorResponse := NewErrorResponse(ErrorCodeInternal, "Cannot read storage statistics", err.Error())
+			c.JSON(http.StatusInternalServerError, errorResponse)
+			return
+		}
+
+		recordAPICall(chatSession, requestStartTime, true, "")
+		successResponse := NewSuccessResponse(response, "Storage statistics retrieved successfully")
+		c.JSON(http.StatusOK, successResponse)
+	}
+}
+
+// parsePaging reads the limit and offset query parameters
+func parsePaging(c *gin.Context) (int, int, error) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultArchivePageSize)))
+	if err != nil || limit < 1 || limit > maxArchivePageSize {
+		return 0, 0, fmt.Errorf("limit must be a number between 1 and %d", maxArchivePageSize)
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		return 0, 0, fmt.Errorf("offset must be a positive number")
+	}
+	return limit, offset, nil
+}
+
+// archivePage returns one page of saved sessions
+func archivePage(saved []persistence.ConversationSession, limit, offset int) ArchiveListResponse {
+	page := ArchiveListResponse{
+		Sessions: []ArchivedSession{},
+		Total:    len(saved),
+		Limit:    limit,
+		Offset:   offset,
+	}
+	for i := offset; i < len(saved) && i < offset+limit; i++ {
+		page.Sessions = append(page.Sessions, archivedSession(saved[i]))
+	}
+	return page
+}
+
+func archivedSession(session persistence.ConversationSession) ArchivedSession {
+	return ArchivedSession{
+		ID:           session.ID,
+		Model:        session.Model,
+		StartTime:    session.StartTime,
+		EndTime:      session.EndTime,
+		MessageCount: session.Analytics.MessageCount,
+		Compressed:   session.Compressed,
+	}
+}
+
+// isValidArchiveID rejects IDs that would point outside the history directory
+func isValidArchiveID(id string) bool {
+	return id != "" && id == filepath.Base(id) && !strings.Contains(id, "..")
+}
+
+func archiveNotFound(id string) APIResponse {
+	return NewErrorResponse(ErrorCodeNotFound, "Saved session not found", fmt.Sprintf("No saved session with ID '%s'", id))
+}
@@ -0,0 +1,203 @@
+package api
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"duckduckgo-chat-cli/internal/config"
+	"duckduckgo-chat-cli/internal/ui"
+
+	"github.com/gin-gonic/gin"
+)
+
+// apiKeyContextKey is the gin context key holding the name of the caller's API key
+const apiKeyContextKey = "api_key_name"
+
+// authMiddleware rejects requests without a valid API key. Keys are read from
+// the configuration on every request, so keys added or removed from /config
+// apply immediately. When no key is configured, authentication is disabled.
+func authMiddleware(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		keys := config.APIKeys(cfg)
+		if len(keys) == 0 {
+			c.Next()
+			return
+		}
+
+		provided := extractAPIKey(c.Request)
+		if provided == "" {
+			abortUnauthorized(c, "Missing API key", "Send the key as 'Authorization: Bearer <key>' or in the 'X-API-Key' header")
+			return
+		}
+
+		for _, key := range keys {
+			if subtle.ConstantTimeCompare([]byte(provided), []byte(key.Key)) == 1 {
+				c.Set(apiKeyContextKey, key.Name)
+				c.Next()
+				return
+			}
+		}
+
+		if cfg.API.LogRequests {
+			ui.APILog("Rejected request from %s: invalid API key", c.ClientIP())
+		}
+		abortUnauthorized(c, "Invalid API key", "The provided API key is not recognized")
+	}
+}
+
+// extractAPIKey reads the key from the Authorization bearer token or the X-API-Key header
+func extractAPIKey(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		if token, ok := strings.CutPrefix(auth, "Bearer "); ok {
+			return strings.TrimSpace(token)
+		}
+	}
+	return strings.TrimSpace(r.Header.Get("X-API-Key"))
+}
+
+// abortUnauthorized stops the request with a 401 in the envelope the route's clients expect
+func abortUnauthorized(c *gin.Context, message, details string) {
+	c.Header("WWW-Authenticate", `Bearer realm="duckchat"`)
+	if isOpenAIRoute(c) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, newOpenAIError("invalid_request_error", message))
+		return
+	}
+	if isOllamaRoute(c) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, OllamaErrorResponse{Error: strings.ToLower(message)})
+		return
+	}
+	c.AbortWithStatusJSON(http.StatusUnauthorized, NewErrorResponse(ErrorCodeUnauthorized, message, details))
+}
+
+// isOpenAIRoute reports whether the request targets the OpenAI-compatible routes
+func isOpenAIRoute(c *gin.Context) bool {
+	return strings.HasPrefix(c.Request.URL.Path, "/v1/")
+}
+
+// isOllamaRoute reports whether the request targets the Ollama-compatible routes
+func isOllamaRoute(c *gin.Context) bool {
+	path := c.Request.URL.Path
+	return strings.HasPrefix(path, "/api/") && !strings.HasPrefix(path, "/api/v1/")
+}
+
+// tokenBucket holds the remaining request budget of one client
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// bucketIdleTTL is how long a bucket is kept without requests. By then it
+// has refilled, so dropping it changes nothing for the client.
+const bucketIdleTTL = time.Minute
+
+// rateLimiter implements per-client token buckets. Each bucket holds up to
+// one minute worth of requests and refills continuously.
+type rateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	swept   time.Time
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{buckets: make(map[string]*tokenBucket)}
+}
+
+// allow takes a token from the client's bucket. When the bucket is empty it
+// returns false and how long the client should wait before retrying.
+func (rl *rateLimiter) allow(client string, perMinute int) (bool, int, time.Duration) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := time.Now()
+	capacity := float64(perMinute)
+	rate := capacity / 60 // tokens per second
+	rl.sweep(now)
+
+	bucket, ok := rl.buckets[client]
+	if !ok {
+		bucket = &tokenBucket{tokens: capacity, last: now}
+		rl.buckets[client] = bucket
+	}
+
+	bucket.tokens = math.Min(capacity, bucket.tokens+now.Sub(bucket.last).Seconds()*rate)
+	bucket.last = now
+
+	if bucket.tokens < 1 {
+		wait := time.Duration((1 - bucket.tokens) / rate * float64(time.Second))
+		return false, 0, wait
+	}
+
+	bucket.tokens--
+	return true, int(bucket.tokens), 0
+}
+
+// sweep drops the buckets of clients idle for longer than bucketIdleTTL, at
+// most once per bucketIdleTTL. The caller must hold rl.mu.
+func (rl *rateLimiter) sweep(now time.Time) {
+	if now.Sub(rl.swept) < bucketIdleTTL {
+		return
+	}
+	rl.swept = now
+	for client, bucket := range rl.buckets {
+		if now.Sub(bucket.last) >= bucketIdleTTL {
+			delete(rl.buckets, client)
+		}
+	}
+}
+
+// rateLimitMiddleware applies the caller's rate limit: the limit of its API
+// key if it has one, the default limit otherwise. Without API keys, clients
+// are told apart by IP address.
+func rateLimitMiddleware(cfg *config.Config) gin.HandlerFunc {
+	limiter := newRateLimiter()
+
+	return func(c *gin.Context) {
+		limit := *cfg.API.RateLimit
+		client := "ip:" + c.ClientIP()
+
+		if name := c.GetString(apiKeyContextKey); name != "" {
+			client = "key:" + name
+			for _, key := range config.APIKeys(cfg) {
+				if key.Name == name && key.RateLimit > 0 {
+					limit = key.RateLimit
+				}
+			}
+		}
+
+		if limit <= 0 {
+			c.Next()
+			return
+		}
+
+		allowed, remaining, retryAfter := limiter.allow(client, limit)
+		c.Header("X-RateLimit-Limit", strconv.Itoa(limit))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		if allowed {
+			c.Next()
+			return
+		}
+
+		seconds := int(math.Ceil(retryAfter.Seconds()))
+		c.Header("Retry-After", strconv.Itoa(seconds))
+		if cfg.API.LogRequests {
+			ui.APILog("Rate limit exceeded for %s", client)
+		}
+
+		message := fmt.Sprintf("Rate limit of %d requests per minute exceeded", limit)
+		if isOpenAIRoute(c) {
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, newOpenAIError("rate_limit_error", message))
+			return
+		}
+		if isOllamaRoute(c) {
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, OllamaErrorResponse{Error: strings.ToLower(message)})
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, NewErrorResponse(ErrorCodeRateLimit, message, fmt.Sprintf("Retry in %d seconds", seconds)))
+	}
+}
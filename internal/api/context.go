@@ -0,0 +1,404 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"duckduckgo-chat-cli/internal/chat"
+	"duckduckgo-chat-cli/internal/chatcontext"
+	"duckduckgo-chat-cli/internal/config"
+	"duckduckgo-chat-cli/internal/scrape"
+	"duckduckgo-chat-cli/internal/ui"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxContextFileSize limits uploaded context files
+const maxContextFileSize = 10 << 20 // 10 MB
+
+// ContextHandler lists the context loaded in the conversation
+// @Summary      List loaded context
+// @Description  List the files, webpages, search results and projects loaded in the conversation
+// @Tags         Context
+// @Produce      json
+// @Success      200 {object} APIResponse{data=ContextResponse} "Context retrieved successfully"
+// @Security     ApiKeyAuth
+// @Router       /context [get]
+func ContextHandler(chatSession *chat.Chat) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestStartTime := time.Now()
+
+		response := ContextResponse{Items: []ContextItem{}}
+		for i, msg := range chatSession.MessagesSnapshot() {
+			if msg.Role != "user" {
+				continue
+			}
+			item, ok := chatcontext.Describe(msg.Content)
+			if !ok {
+				continue
+			}
+			response.Items = append(response.Items, ContextItem{
+				Index:      i,
+				Kind:       item.Kind,
+				Source:     item.Source,
+				Characters: len(msg.Content),
+			})
+			response.TotalCharacters += len(msg.Content)
+		}
+		response.TotalItems = len(response.Items)
+
+		recordAPICall(chatSession, requestStartTime, true, "")
+		successResponse := NewSuccessResponse(response, "Context retrieved successfully")
+		c.JSON(http.StatusOK, successResponse)
+	}
+}
+
+// FileContextHandler loads an uploaded file as context
+// @Summary      Load a file as context
+// @Description  Add a file to the conversation, like /file. Send it as multipart form data in the "file" field,
+// @Description  or as the raw request body with its name in the "name" query parameter. Files are limited to 10 MB.
+// @Tags         Context
+// @Accept       multipart/form-data,application/octet-stream,text/plain
+// @Produce      json
+// @Param        file formData file false "File to load"
+// @Param        name query string false "File name when sending the raw body" default(upload.txt)
+// @Success      201 {object} APIResponse{data=ContextItem} "File loaded successfully"
+// @Failure      400 {object} APIResponse{error=APIError} "Missing or unreadable file"
+// @Failure      413 {object} APIResponse{error=APIError} "File too large"
+// @Failure      503 {object} APIResponse{error=APIError} "Session busy, retry after the Retry-After delay"
+// @Security     ApiKeyAuth
+// @Router       /context/file [post]
+func FileContextHandler(chatSession *chat.Chat, cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestStartTime := time.Now()
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxContextFileSize)
+
+		name, content, err := readUploadedFile(c)
+		if err != nil {
+			status := http.StatusBadRequest
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				status = http.StatusRequestEntityTooLarge
+			}
+			recordAPICall(chatSession, requestStartTime, false, "validation")
+			response := NewErrorResponse(ErrorCodeValidation, "Cannot read uploaded file", err.Error())
+			c.JSON(status, response)
+			return
+		}
+
+		if !acquireTurn(c, chatSession) {
+			return
+		}
+		chatSession.AddFileContext(name, content)
+		index := len(chatSession.Messages) - 1
+		chatSession.Release()
+
+		if cfg.API.LogRequests {
+			ui.APILog("Loaded file context '%s' (%d bytes) from %s", name, len(content), c.ClientIP())
+		}
+
+		item := ContextItem{Index: index, Kind: "file", Source: name, Characters: len(chatcontext.FormatFile(name, content))}
+		recordAPICall(chatSession, requestStartTime, true, "")
+		successResponse := NewSuccessResponse(item, fmt.Sprintf("File '%s' added to the context", name))
+		c.JSON(http.StatusCreated, successResponse)
+	}
+}
+
+// URLContextHandler loads a webpage as context
+// @Summary      Load a webpage as context
+// @Description  Scrape a webpage and add its content to the conversation, like /url
+// @Tags         Context
+// @Accept       json
+// @Produce      json
+// @Param        request body URLContextRequest true "Webpage to load"
+// @Success      201 {object} APIResponse{data=ContextItem} "Webpage loaded successfully"
+// @Failure      400 {object} APIResponse{error=APIError} "Invalid request"
+// @Failure      502 {object} APIResponse{error=APIError} "Webpage could not be retrieved"
+// @Failure      503 {object} APIResponse{error=APIError} "Session busy, retry after the Retry-After delay"
+// @Security     ApiKeyAuth
+// @Router       /context/url [post]
+func URLContextHandler(chatSession *chat.Chat, cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestStartTime := time.Now()
+
+		var req URLContextRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			recordAPICall(chatSession, requestStartTime, false, "validation")
+			response := NewErrorResponse(ErrorCodeValidation, "Invalid request payload", err.Error())
+			c.JSON(http.StatusBadRequest, response)
+			return
+		}
+
+		// Scrape before taking the session's turn so other requests are not
+		// held up, and so a client disconnect stops Chrome.
+		result, err := scrape.WebContent(c.Request.Context(), req.URL)
+		if err != nil {
+			recordAPICall(chatSession, requestStartTime, false, "scrape_error")
+			response := NewErrorResponse(ErrorCodeContextError, "Cannot retrieve webpage", err.Error())
+			c.JSON(http.StatusBadGateway, response)
+			return
+		}
+
+		if !acquireTurn(c, chatSession) {
+			return
+		}
+		chatSession.AddWebpageContext(result.URL, result.Content)
+		index := len(chatSession.Messages) - 1
+		chatSession.Release()
+
+		if cfg.API.LogRequests {
+			ui.APILog("Loaded URL context '%s' for %s", result.URL, c.ClientIP())
+		}
+
+		recordAPICall(chatSession, requestStartTime, true, "")
+		successResponse := NewSuccessResponse(ContextItem{
+			Index:      index,
+			Kind:       "url",
+			Source:     result.URL,
+			Characters: len(chatcontext.FormatURL(result.URL, result.Content)),
+		}, fmt.Sprintf("Webpage '%s' added to the context", result.URL))
+		c.JSON(http.StatusCreated, successResponse)
+	}
+}
+
+// SearchContextHandler loads web search results as context
+// @Summary      Load search results as context
+// @Description  Search the web and add the results to the conversation, like /search
+// @Tags         Context
+// @Accept       json
+// @Produce      json
+// @Param        request body SearchContextRequest true "Search query"
+// @Success      201 {object} APIResponse{data=ContextItem} "Search results loaded successfully"
+// @Failure      400 {object} APIResponse{error=APIError} "Invalid request"
+// @Failure      502 {object} APIResponse{error=APIError} "Search failed or returned no results"
+// @Failure      503 {object} APIResponse{error=APIError} "Session busy, retry after the Retry-After delay"
+// @Security     ApiKeyAuth
+// @Router       /context/search [post]
+func SearchContextHandler(chatSession *chat.Chat, cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestStartTime := time.Now()
+
+		var req SearchContextRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			recordAPICall(chatSession, requestStartTime, false, "validation")
+			response := NewErrorResponse(ErrorCodeValidation, "Invalid request payload", err.Error())
+			c.JSON(http.StatusBadRequest, response)
+			return
+		}
+
+		results, count, err := chat.SearchWeb(c.Request.Context(), req.Query, cfg)
+		if err != nil {
+			recordAPICall(chatSession, requestStartTime, false, "search_error")
+			response := NewErrorResponse(ErrorCodeContextError, "Search failed", err.Error())
+			c.JSON(http.StatusBadGateway, response)
+			return
+		}
+
+		if !acquireTurn(c, chatSession) {
+			return
+		}
+		chatSession.AddSearchResults(req.Query, results)
+		index := len(chatSession.Messages) - 1
+		chatSession.Release()
+
+		if cfg.API.LogRequests {
+			ui.APILog("Loaded %d search results for '%s' from %s", count, req.Query, c.ClientIP())
+		}
+
+		recordAPICall(chatSession, requestStartTime, true, "")
+		successResponse := NewSuccessResponse(ContextItem{
+			Index:      index,
+			Kind:       "search",
+			Source:     req.Query,
+			Characters: len(chatcontext.FormatSearch(req.Query, results)),
+		}, fmt.Sprintf("%d search results added to the context", count))
+		c.JSON(http.StatusCreated, successResponse)
+	}
+}
+
+// LibraryContextHandler loads library files as context
+// @Summary      Load library files as context
+// @Description  Add files from a configured library to the conversation, like /library load. The library is chosen
+// @Description  by name or number; "files" lists paths relative to the library and defaults to every supported file.
+// @Description  The files are limited to 10 MB in total.
+// @Tags         Context
+// @Accept       json
+// @Produce      json
+// @Param        request body LibraryContextRequest true "Library and files to load"
+// @Success      201 {object} APIResponse{data=ContextResponse} "Library files loaded successfully"
+// @Failure      400 {object} APIResponse{error=APIError} "Invalid request, unknown library or file, or library system disabled"
+// @Failure      413 {object} APIResponse{error=APIError} "Files too large"
+// @Failure      503 {object} APIResponse{error=APIError} "Session busy, retry after the Retry-After delay"
+// @Security     ApiKeyAuth
+// @Router       /context/library [post]
+func LibraryContextHandler(chatSession *chat.Chat, cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestStartTime := time.Now()
+
+		var req LibraryContextRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			recordAPICall(chatSession, requestStartTime, false, "validation")
+			response := NewErrorResponse(ErrorCodeValidation, "Invalid request payload", err.Error())
+			c.JSON(http.StatusBadRequest, response)
+			return
+		}
+
+		files, err := chat.LibraryFiles(cfg, req.Library, req.Files)
+		if err == nil && len(files) == 0 {
+			err = fmt.Errorf("no supported files in library: %s", req.Library)
+		}
+		if err != nil {
+			recordAPICall(chatSession, requestStartTime, false, "validation")
+			response := NewErrorResponse(ErrorCodeValidation, "Cannot load library", err.Error())
+			c.JSON(http.StatusBadRequest, response)
+			return
+		}
+
+		// Read every file before taking the session's turn
+		contents := make([][]byte, len(files))
+		total := 0
+		for i, file := range files {
+			content, err := os.ReadFile(file)
+			if err != nil {
+				recordAPICall(chatSession, requestStartTime, false, "context_error")
+				response := NewErrorResponse(ErrorCodeContextError, "Cannot read library file", err.Error())
+				c.JSON(http.StatusInternalServerError, response)
+				return
+			}
+			total += len(content)
+			if total > maxContextFileSize {
+				recordAPICall(chatSession, requestStartTime, false, "validation")
+				response := NewErrorResponse(ErrorCodeValidation, "Library files too large", "Select fewer files with the 'files' field")
+				c.JSON(http.StatusRequestEntityTooLarge, response)
+				return
+			}
+			contents[i] = content
+		}
+
+		if !acquireTurn(c, chatSession) {
+			return
+		}
+		first := len(chatSession.Messages)
+		for i, file := range files {
+			chatSession.AddLibraryFile(file, contents[i])
+		}
+		chatSession.Release()
+
+		if cfg.API.LogRequests {
+			ui.APILog("Loaded %d library files from '%s' for %s", len(files), req.Library, c.ClientIP())
+		}
+
+		response := ContextResponse{Items: make([]ContextItem, 0, len(files))}
+		for i, file := range files {
+			characters := len(chatcontext.FormatFile(file, contents[i]))
+			response.Items = append(response.Items, ContextItem{Index: first + i, Kind: "file", Source: file, Characters: characters})
+			response.TotalCharacters += characters
+		}
+		response.TotalItems = len(response.Items)
+
+		recordAPICall(chatSession, requestStartTime, true, "")
+		successResponse := NewSuccessResponse(response, fmt.Sprintf("%d library files added to the context", len(files)))
+		c.JSON(http.StatusCreated, successResponse)
+	}
+}
+
+// PMPContextHandler loads a project prompt generated by PMP as context
+// @Summary      Load a project as context
+// @Description  Run PMP (Prompt My Project) and add the generated prompt to the conversation, like /pmp. The project
+// @Description  must be a configured library or a directory inside one, so API clients cannot read arbitrary paths,
+// @Description  and PMP options are not accepted. PMP must already be installed on the server.
+// @Tags         Context
+// @Accept       json
+// @Produce      json
+// @Param        request body PMPContextRequest true "Project to load"
+// @Success      201 {object} APIResponse{data=ContextItem} "Project loaded successfully"
+// @Failure      400 {object} APIResponse{error=APIError} "Invalid request, unknown library or path, or library system disabled"
+// @Failure      500 {object} APIResponse{error=APIError} "PMP is not installed or failed"
+// @Failure      503 {object} APIResponse{error=APIError} "Session busy, retry after the Retry-After delay"
+// @Security     ApiKeyAuth
+// @Router       /context/pmp [post]
+func PMPContextHandler(chatSession *chat.Chat, cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestStartTime := time.Now()
+
+		var req PMPContextRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			recordAPICall(chatSession, requestStartTime, false, "validation")
+			response := NewErrorResponse(ErrorCodeValidation, "Invalid request payload", err.Error())
+			c.JSON(http.StatusBadRequest, response)
+			return
+		}
+
+		path, err := chat.LibraryPath(cfg, req.Library, req.Path)
+		if err != nil {
+			recordAPICall(chatSession, requestStartTime, false, "validation")
+			response := NewErrorResponse(ErrorCodeValidation, "Cannot load project", err.Error())
+			c.JSON(http.StatusBadRequest, response)
+			return
+		}
+
+		prompt, err := chat.GenerateProjectPrompt(c.Request.Context(), path)
+		if err != nil {
+			recordAPICall(chatSession, requestStartTime, false, "pmp_error")
+			response := NewErrorResponse(ErrorCodeContextError, "Cannot generate project prompt", err.Error())
+			c.JSON(http.StatusInternalServerError, response)
+			return
+		}
+
+		if !acquireTurn(c, chatSession) {
+			return
+		}
+		chatSession.AddProjectContext(path, prompt)
+		index := len(chatSession.Messages) - 1
+		chatSession.Release()
+
+		if cfg.API.LogRequests {
+			ui.APILog("Loaded project context '%s' (%d characters) for %s", path, len(prompt), c.ClientIP())
+		}
+
+		recordAPICall(chatSession, requestStartTime, true, "")
+		successResponse := NewSuccessResponse(ContextItem{
+			Index:      index,
+			Kind:       "project",
+			Source:     path,
+			Characters: len(chatcontext.FormatProject(path, prompt)),
+		}, fmt.Sprintf("Project '%s' added to the context", path))
+		c.JSON(http.StatusCreated, successResponse)
+	}
+}
+
+// readUploadedFile returns the name and content of the uploaded file, sent
+// either as a multipart "file" field or as the raw request body
+func readUploadedFile(c *gin.Context) (string, []byte, error) {
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			return "", nil, fmt.Errorf("multipart field 'file' is required: %w", err)
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			return "", nil, err
+		}
+		defer file.Close()
+
+		content, err := io.ReadAll(file)
+		if err != nil {
+			return "", nil, err
+		}
+		return fileHeader.Filename, content, nil
+	}
+
+	content, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(content) == 0 {
+		return "", nil, fmt.Errorf("request body is empty")
+	}
+	return c.DefaultQuery("name", "upload.txt"), content, nil
+}
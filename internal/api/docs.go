@@ -5,7 +5,10 @@
 // The API allows you to:
 // - Send messages to various AI models
 // - Retrieve chat history
+// - Load files, webpages and search results as context
 // - Manage AI models
+// - Run isolated sessions for several clients
+// - Browse, search and restore saved conversations
 // - Monitor system health
 //
 // All responses follow a consistent format with success/error indicators and standardized error codes.
@@ -30,11 +33,17 @@
 //	@securityDefinitions.apikey	ApiKeyAuth
 //	@in							header
 //	@name						Authorization
-//	@description				API key authorization. Example: "Bearer {api_key}"
+//	@description				API key authorization. Example: "Bearer {api_key}". The key can also be sent in the X-API-Key header.
 //
 //	@tag.name					Chat
 //	@tag.description			Chat operations for sending messages and managing conversation
 //
+//	@tag.name					Context
+//	@tag.description			Load files, webpages and search results into the conversation
+//
+//	@tag.name					Archive
+//	@tag.description			Saved conversations from the history manager
+//
 //	@tag.name					Models
 //	@tag.description			AI model management and information
 //
@@ -42,7 +51,7 @@
 //	@tag.description			System health and status monitoring
 //
 //	@tag.name					Session
-//	@tag.description			Session management and information
+//	@tag.description			Session management and information, including isolated per-client sessions
 //
 //	@externalDocs.description	duckduckGO-chat-cli Documentation
 //	@externalDocs.url			https://github.com/benoitpetit/duckduckGO-chat-cli/blob/main/README.md
@@ -1,7 +1,9 @@
 package api
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
 	"time"
@@ -18,82 +20,225 @@ var startTime = time.Now()
 
 // ChatHandler handles chat requests
 // @Summary      Send a chat message
-// @Description  Send a message to the AI and receive a response
+// @Description  Send a message to the AI and receive a response.
+// @Description  With stream=true the answer is sent as Server-Sent Events: one "chunk" event per
+// @Description  partial response ({"content"}), "metadata" once the upstream service names the model
+// @Description  ({"model","id"}), "tool" for other upstream actions ({"action","data"}) and "error" if
+// @Description  the answer fails once started ({"error"}), then a "done" event carrying the full
+// @Description  ChatResponse and its metadata, including whether the answer was truncated.
 // @Tags         Chat
 // @Accept       json
-// @Produce      json
+// @Produce      json,text/event-stream
 // @Param        request body ChatRequest true "Chat message request"
+// @Param        stream query bool false "Stream the response as Server-Sent Events"
 // @Success      200 {object} APIResponse{data=ChatResponse} "Successful chat response"
-// @Failure      400 {object} APIResponse{error=APIError} "Invalid request"
+// @Failure      400 {object} APIResponse{error=APIError} "Invalid request or unknown model"
 // @Failure      500 {object} APIResponse{error=APIError} "Internal server error"
+// @Failure      503 {object} APIResponse{error=APIError} "Session busy, retry after the Retry-After delay"
+// @Security     ApiKeyAuth
 // @Router       /chat [post]
 func ChatHandler(chatSession *chat.Chat, cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var req ChatRequest
-		if err := c.ShouldBindJSON(&req); err != nil {
-			response := NewErrorResponse(ErrorCodeValidation, "Invalid request payload", err.Error())
-			c.JSON(http.StatusBadRequest, response)
-			return
-		}
+		handleChat(c, chatSession, cfg, false)
+	}
+}
+
+// handleChat processes a chat request against the given session. It is shared
+// by the default session endpoint and the per-client session endpoints; model
+// changes on isolated sessions stay off the terminal and the shared analytics.
+func handleChat(c *gin.Context, chatSession *chat.Chat, cfg *config.Config, isolated bool) {
+	var req ChatRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response := NewErrorResponse(ErrorCodeValidation, "Invalid request payload", err.Error())
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	if req.Message == "" {
+		response := NewErrorResponse(ErrorCodeValidation, "Message cannot be empty", "Field 'message' is required and cannot be empty")
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
 
-		if req.Message == "" {
-			response := NewErrorResponse(ErrorCodeValidation, "Message cannot be empty", "Field 'message' is required and cannot be empty")
+	var newModel models.Model
+	if req.Model != "" {
+		model, ok := chat.ResolveModelName(chatSession, req.Model)
+		if !ok {
+			if chatSession.Analytics != nil {
+				chatSession.Analytics.RecordAPICall(0, false, "model_not_found")
+			}
+			response := NewErrorResponse(ErrorCodeModelNotFound, "Model not found", fmt.Sprintf("Model '%s' is not available", req.Model))
 			c.JSON(http.StatusBadRequest, response)
 			return
 		}
+		newModel = model
+	}
 
-		// Change model if specified
-		if req.Model != "" {
-			if newModel := models.GetModel(req.Model); newModel != chatSession.Model {
-				chatSession.ChangeModel(newModel)
-			}
+	// Wait for any CLI or API exchange in progress on this session
+	if !acquireTurn(c, chatSession) {
+		return
+	}
+	defer chatSession.Release()
+
+	// Change model if specified
+	if newModel != "" && newModel != chatSession.Model {
+		if isolated {
+			chatSession.SetModel(newModel)
+		} else {
+			chatSession.ChangeModel(newModel)
 		}
+	}
 
-		// Log the request if enabled
-		if cfg.API.LogRequests {
-			ui.APILog("Received chat request from %s: '%s'", c.ClientIP(), req.Message)
+	// Log the request if enabled
+	if cfg.API.LogRequests {
+		ui.APILog("Received chat request from %s: '%s'", c.ClientIP(), req.Message)
+	}
+
+	if req.Stream || c.Query("stream") == "true" {
+		streamChatResponse(c, chatSession, req.Message, cfg)
+		return
+	}
+
+	clearWriteDeadline(c)
+
+	// Process the chat message
+	startTime := time.Now()
+	response, err := chat.ProcessInputAndReturn(chatSession, req.Message, cfg)
+	processingTime := time.Since(startTime)
+
+	// Track API call in analytics
+	if chatSession.Analytics != nil {
+		chatSession.Analytics.RecordAPICall(processingTime, err == nil, "")
+	}
+
+	if err != nil {
+		errorResponse := NewErrorResponse(ErrorCodeChatError, "Error processing chat message", err.Error())
+		c.JSON(http.StatusInternalServerError, errorResponse)
+		return
+	}
+
+	// Create response with metadata
+	chatResponse := ChatResponse{
+		Response:  response,
+		Model:     string(chatSession.Model),
+		MessageID: lastAnswerID(chatSession),
+		Metadata: ChatMetadata{
+			ProcessingTime:    processingTime.Milliseconds(),
+			TokensEstimate:    estimateTokens(response),
+			ConversationCount: len(chatSession.Messages),
+		},
+	}
+
+	// Log the response if enabled
+	if cfg.API.LogRequests {
+		responseSnippet := response
+		if len(responseSnippet) > 100 {
+			responseSnippet = responseSnippet[:100] + "..."
 		}
+		ui.APILog("Sending response snippet to %s: '%s'", c.ClientIP(), responseSnippet)
+	}
 
-		// Process the chat message
-		startTime := time.Now()
-		response, err := chat.ProcessInputAndReturn(chatSession, req.Message, cfg)
-		processingTime := time.Since(startTime)
+	successResponse := NewSuccessResponse(chatResponse, "Chat message processed successfully")
+	c.JSON(http.StatusOK, successResponse)
+}
 
-		// Track API call in analytics
+// acquireTurn waits for the session's turn. When the queue is full it answers
+// 503 with a Retry-After hint and returns false; the caller must Release the
+// turn when it returns true.
+func acquireTurn(c *gin.Context, chatSession *chat.Chat) bool {
+	err := chatSession.Acquire(c.Request.Context())
+	if err == nil {
+		return true
+	}
+
+	if chatSession.Analytics != nil {
+		chatSession.Analytics.RecordAPICall(0, false, "session_busy")
+	}
+	if errors.Is(err, chat.ErrQueueFull) {
+		// Rough estimate: a few seconds per exchange ahead in the queue
+		c.Header("Retry-After", strconv.Itoa(5*chatSession.QueueDepth()))
+		response := NewErrorResponse(ErrorCodeSessionBusy, "Session is busy", fmt.Sprintf("%d requests are already running or waiting on this session", chatSession.QueueDepth()))
+		c.JSON(http.StatusServiceUnavailable, response)
+	}
+	// Otherwise the client went away while waiting: nothing to answer
+	return false
+}
+
+// streamChatResponse writes the answer as Server-Sent Events. The upstream
+// request is bound to the client's request context, so it is cancelled as
+// soon as the client disconnects.
+func streamChatResponse(c *gin.Context, chatSession *chat.Chat, message string, cfg *config.Config) {
+	startTime := time.Now()
+	stream, err := chat.ProcessInputStream(c.Request.Context(), chatSession, message, cfg)
+	if err != nil {
 		if chatSession.Analytics != nil {
-			chatSession.Analytics.RecordAPICall(processingTime, err == nil, "")
+			chatSession.Analytics.RecordAPICall(time.Since(startTime), false, "")
 		}
+		errorResponse := NewErrorResponse(ErrorCodeChatError, "Error processing chat message", err.Error())
+		c.JSON(http.StatusInternalServerError, errorResponse)
+		return
+	}
 
-		if err != nil {
-			errorResponse := NewErrorResponse(ErrorCodeChatError, "Error processing chat message", err.Error())
-			c.JSON(http.StatusInternalServerError, errorResponse)
-			return
-		}
+	clearWriteDeadline(c)
 
-		// Create response with metadata
-		chatResponse := ChatResponse{
-			Response:  response,
-			Model:     string(chatSession.Model),
-			MessageID: generateMessageID(len(chatSession.Messages)),
-			Metadata: ChatMetadata{
-				ProcessingTime:    processingTime.Milliseconds(),
-				TokensEstimate:    estimateTokens(response),
-				ConversationCount: len(chatSession.Messages),
-			},
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+
+	result := &chat.StreamResult{}
+	c.Stream(func(w io.Writer) bool {
+		event, ok := <-stream
+		if !ok {
+			return false
+		}
+		result.Add(event)
+		switch event.Type {
+		case chat.EventDelta:
+			c.SSEvent("chunk", ChatChunk{Content: event.Content})
+		case chat.EventMetadata:
+			c.SSEvent("metadata", ChatStreamMetadata{Model: event.Model, ID: event.ID})
+		case chat.EventTool:
+			c.SSEvent("tool", ChatStreamTool{Action: event.Action, Data: event.Data})
+		case chat.EventError:
+			c.SSEvent("error", ChatStreamError{Error: event.Err.Error()})
 		}
+		return true
+	})
+
+	// Wait for the history to be updated; after a disconnect this returns as
+	// soon as the upstream request has been aborted
+	for range stream {
+	}
+	processingTime := time.Since(startTime)
 
-		// Log the response if enabled
+	if chatSession.Analytics != nil {
+		chatSession.Analytics.RecordAPICall(processingTime, c.Request.Context().Err() == nil && result.Err == nil, "")
+	}
+
+	if c.Request.Context().Err() != nil {
 		if cfg.API.LogRequests {
-			responseSnippet := response
-			if len(responseSnippet) > 100 {
-				responseSnippet = responseSnippet[:100] + "..."
-			}
-			ui.APILog("Sending response snippet to %s: '%s'", c.ClientIP(), responseSnippet)
+			ui.APILog("Client %s disconnected, generation cancelled", c.ClientIP())
 		}
-
-		successResponse := NewSuccessResponse(chatResponse, "Chat message processed successfully")
-		c.JSON(http.StatusOK, successResponse)
+		return
 	}
+
+	// The done event follows an error event too, so that clients always get
+	// the final metadata
+	finalResponse := result.Content()
+	c.SSEvent("done", ChatResponse{
+		Response:  finalResponse,
+		Model:     string(chatSession.Model),
+		MessageID: lastAnswerID(chatSession),
+		Metadata: ChatMetadata{
+			ProcessingTime:    processingTime.Milliseconds(),
+			TokensEstimate:    estimateTokens(finalResponse),
+			ConversationCount: len(chatSession.Messages),
+			ServedModel:       result.Model,
+			Truncated:         result.Truncated(),
+			FinishReason:      result.FinishReason,
+		},
+	})
+	c.Writer.Flush()
 }
 
 // HistoryHandler handles chat history requests
@@ -105,6 +250,7 @@ func ChatHandler(chatSession *chat.Chat, cfg *config.Config) gin.HandlerFunc {
 // @Param        offset query int false "Number of messages to skip" default(0)
 // @Success      200 {object} APIResponse{data=HistoryResponse} "Chat history retrieved successfully"
 // @Failure      400 {object} APIResponse{error=APIError} "Invalid query parameters"
+// @Security     ApiKeyAuth
 // @Router       /history [get]
 func HistoryHandler(chatSession *chat.Chat) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -131,7 +277,8 @@ func HistoryHandler(chatSession *chat.Chat) gin.HandlerFunc {
 		}
 
 		// Apply pagination
-		messages := chatSession.Messages
+		state := chatSession.State()
+		messages := chatSession.MessagesSnapshot()
 		totalMessages := len(messages)
 
 		if offset >= totalMessages {
@@ -145,7 +292,7 @@ func HistoryHandler(chatSession *chat.Chat) gin.HandlerFunc {
 		}
 
 		// Convert to response format
-		historyResponse := ConvertChatMessagesToResponse(messages, chatSession.SessionID, chatSession.Model)
+		historyResponse := ConvertChatMessagesToResponse(messages, state.SessionID, state.Model)
 		historyResponse.TotalMessages = totalMessages // Set the actual total
 
 		// Track API call in analytics
@@ -160,20 +307,30 @@ func HistoryHandler(chatSession *chat.Chat) gin.HandlerFunc {
 
 // ModelsHandler handles available models requests
 // @Summary      Get available models
-// @Description  Retrieve list of all available AI models
+// @Description  Retrieve the models of the configured provider: the DuckDuckGo models, or the models listed by an OpenAI-compatible server
 // @Tags         Models
 // @Produce      json
 // @Success      200 {object} APIResponse{data=ModelsResponse} "Available models retrieved successfully"
+// @Failure      502 {object} APIResponse{error=APIError} "The provider could not list its models"
+// @Security     ApiKeyAuth
 // @Router       /models [get]
 func ModelsHandler(chatSession *chat.Chat) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		startTime := time.Now()
 
-		availableModels := GetAvailableModels()
+		availableModels, err := providerModels(c.Request.Context(), chatSession)
+		if err != nil {
+			if chatSession.Analytics != nil {
+				chatSession.Analytics.RecordAPICall(time.Since(startTime), false, "chat_error")
+			}
+			response := NewErrorResponse(ErrorCodeChatError, "Cannot list models", err.Error())
+			c.JSON(http.StatusBadGateway, response)
+			return
+		}
 
 		modelsResponse := ModelsResponse{
 			Models:       availableModels,
-			CurrentModel: string(chatSession.Model),
+			CurrentModel: string(chatSession.CurrentModel()),
 			TotalModels:  len(availableModels),
 		}
 
@@ -196,6 +353,7 @@ func ModelsHandler(chatSession *chat.Chat) gin.HandlerFunc {
 // @Param        request body ModelChangeRequest true "Model change request"
 // @Success      200 {object} APIResponse{data=ModelInfo} "Model changed successfully"
 // @Failure      400 {object} APIResponse{error=APIError} "Invalid request or model not found"
+// @Security     ApiKeyAuth
 // @Router       /models [post]
 func ModelChangeHandler(chatSession *chat.Chat) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -213,14 +371,17 @@ func ModelChangeHandler(chatSession *chat.Chat) gin.HandlerFunc {
 		}
 
 		// Validate model exists
-		newModel := models.GetModel(req.Model)
-		availableModels := GetAvailableModels()
-
 		var modelInfo *ModelInfo
-		for _, model := range availableModels {
-			if model.ID == req.Model {
-				modelInfo = &model
-				break
+		newModel, ok := chat.ResolveModelName(chatSession, req.Model)
+		if ok && chatSession.Provider.Name() != config.ProviderDuckDuckGo {
+			// The server may not list every model it serves
+			modelInfo = &ModelInfo{ID: string(newModel), Name: string(newModel), UpstreamID: string(newModel)}
+		} else if ok {
+			for _, model := range GetAvailableModels() {
+				if model.UpstreamID == string(newModel) {
+					modelInfo = &model
+					break
+				}
 			}
 		}
 
@@ -235,7 +396,11 @@ func ModelChangeHandler(chatSession *chat.Chat) gin.HandlerFunc {
 		}
 
 		// Change the model
+		if !acquireTurn(c, chatSession) {
+			return
+		}
 		chatSession.ChangeModel(newModel)
+		chatSession.Release()
 
 		// Track successful API call
 		if chatSession.Analytics != nil {
@@ -283,11 +448,16 @@ func HealthHandler() gin.HandlerFunc {
 // @Tags         Chat
 // @Produce      json
 // @Success      200 {object} APIResponse "Chat history cleared successfully"
+// @Security     ApiKeyAuth
 // @Router       /history [delete]
 func ClearHistoryHandler(chatSession *chat.Chat, cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		requestStartTime := time.Now()
+		if !acquireTurn(c, chatSession) {
+			return
+		}
 		chatSession.Clear(cfg)
+		chatSession.Release()
 
 		// Track API call in analytics
 		if chatSession.Analytics != nil {
@@ -305,16 +475,19 @@ func ClearHistoryHandler(chatSession *chat.Chat, cfg *config.Config) gin.Handler
 // @Tags         Session
 // @Produce      json
 // @Success      200 {object} APIResponse "Session information retrieved successfully"
+// @Security     ApiKeyAuth
 // @Router       /session [get]
 func SessionInfoHandler(chatSession *chat.Chat) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		requestStartTime := time.Now()
+		state := chatSession.State()
 		sessionInfo := map[string]interface{}{
-			"session_id":    chatSession.SessionID,
-			"current_model": string(chatSession.Model),
-			"message_count": len(chatSession.Messages),
-			"last_vqd":      chatSession.NewVqd,
-			"retry_count":   chatSession.RetryCount,
+			"session_id":    state.SessionID,
+			"current_model": string(state.Model),
+			"message_count": state.MessageCount,
+			"last_vqd":      state.NewVqd,
+			"retry_count":   state.RetryCount,
+			"queue_depth":   state.QueueDepth,
 		}
 
 		// Track API call in analytics
@@ -0,0 +1,28 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+
+	"duckduckgo-chat-cli/internal/chat"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MetricsHandler serves GET /metrics in the Prometheus text format
+func MetricsHandler(chatSession *chat.Chat, store *sessionStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var buf bytes.Buffer
+		if chatSession.Analytics != nil {
+			chatSession.Analytics.WritePrometheus(&buf)
+		}
+
+		fmt.Fprintf(&buf, "# HELP duckchat_session_queue_depth Requests running or waiting on the main chat session.\n# TYPE duckchat_session_queue_depth gauge\n")
+		fmt.Fprintf(&buf, "duckchat_session_queue_depth %d\n", chatSession.QueueDepth())
+		fmt.Fprintf(&buf, "# HELP duckchat_api_sessions Isolated API sessions currently open.\n# TYPE duckchat_api_sessions gauge\n")
+		fmt.Fprintf(&buf, "duckchat_api_sessions %d\n", store.count())
+
+		c.Data(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", buf.Bytes())
+	}
+}
@@ -0,0 +1,327 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"duckduckgo-chat-cli/internal/chat"
+	"duckduckgo-chat-cli/internal/config"
+	"duckduckgo-chat-cli/internal/models"
+	"duckduckgo-chat-cli/internal/ui"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Ollama-compatible types. These mirror the subset of the Ollama API used by
+// frontends such as Open WebUI and Continue.
+
+// ollamaVersion is the Ollama version reported to clients that check it
+const ollamaVersion = "0.5.0"
+
+// OllamaChatRequest represents an Ollama /api/chat request
+type OllamaChatRequest struct {
+	Model    string          `json:"model" binding:"required"`
+	Messages []OllamaMessage `json:"messages"`
+	Stream   *bool           `json:"stream"`
+}
+
+// OllamaGenerateRequest represents an Ollama /api/generate request
+type OllamaGenerateRequest struct {
+	Model  string `json:"model" binding:"required"`
+	Prompt string `json:"prompt"`
+	System string `json:"system"`
+	Stream *bool  `json:"stream"`
+}
+
+// OllamaMessage represents a single message in an Ollama conversation
+type OllamaMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// OllamaResponse represents a streamed chunk or the final object of an
+// /api/chat or /api/generate response. Message is set for /api/chat,
+// Response for /api/generate.
+type OllamaResponse struct {
+	Model              string         `json:"model"`
+	CreatedAt          time.Time      `json:"created_at"`
+	Message            *OllamaMessage `json:"message,omitempty"`
+	Response           *string        `json:"response,omitempty"`
+	Done               bool           `json:"done"`
+	DoneReason         string         `json:"done_reason,omitempty"`
+	TotalDuration      int64          `json:"total_duration,omitempty"`
+	PromptEvalCount    int            `json:"prompt_eval_count,omitempty"`
+	EvalCount          int            `json:"eval_count,omitempty"`
+	EvalDuration       int64          `json:"eval_duration,omitempty"`
+	PromptEvalDuration int64          `json:"prompt_eval_duration,omitempty"`
+}
+
+// OllamaModel represents a model entry in the /api/tags list
+type OllamaModel struct {
+	Name       string             `json:"name"`
+	Model      string             `json:"model"`
+	ModifiedAt time.Time          `json:"modified_at"`
+	Size       int64              `json:"size"`
+	Digest     string             `json:"digest"`
+	Details    OllamaModelDetails `json:"details"`
+}
+
+// OllamaModelDetails describes a model in the /api/tags list
+type OllamaModelDetails struct {
+	Format            string `json:"format"`
+	Family            string `json:"family"`
+	ParameterSize     string `json:"parameter_size"`
+	QuantizationLevel string `json:"quantization_level"`
+}
+
+// OllamaTagsResponse represents the /api/tags response
+type OllamaTagsResponse struct {
+	Models []OllamaModel `json:"models"`
+}
+
+// OllamaErrorResponse represents an error in the Ollama envelope
+type OllamaErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// OllamaChatHandler serves POST /api/chat
+func OllamaChatHandler(chatSession *chat.Chat, cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestStartTime := time.Now()
+
+		var req OllamaChatRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			recordAPICall(chatSession, requestStartTime, false, "validation")
+			c.JSON(http.StatusBadRequest, OllamaErrorResponse{Error: "invalid request payload: " + err.Error()})
+			return
+		}
+
+		model, ok := resolveOllamaModel(c, chatSession, requestStartTime, req.Model)
+		if !ok {
+			return
+		}
+
+		converted := make([]OpenAIMessage, len(req.Messages))
+		for i, msg := range req.Messages {
+			converted[i] = OpenAIMessage{Role: msg.Role, Content: OpenAIContent(msg.Content)}
+		}
+		messages, prompt := convertOpenAIMessages(converted)
+
+		// Frontends send an empty conversation to preload a model
+		if prompt == "" {
+			recordAPICall(chatSession, requestStartTime, true, "")
+			c.JSON(http.StatusOK, OllamaResponse{
+				Model:      req.Model,
+				CreatedAt:  time.Now().UTC(),
+				Message:    &OllamaMessage{Role: "assistant"},
+				Done:       true,
+				DoneReason: "load",
+			})
+			return
+		}
+
+		serveOllama(c, chatSession, cfg, requestStartTime, req.Model, model, req.Stream, messages, prompt, true)
+	}
+}
+
+// OllamaGenerateHandler serves POST /api/generate
+func OllamaGenerateHandler(chatSession *chat.Chat, cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestStartTime := time.Now()
+
+		var req OllamaGenerateRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			recordAPICall(chatSession, requestStartTime, false, "validation")
+			c.JSON(http.StatusBadRequest, OllamaErrorResponse{Error: "invalid request payload: " + err.Error()})
+			return
+		}
+
+		model, ok := resolveOllamaModel(c, chatSession, requestStartTime, req.Model)
+		if !ok {
+			return
+		}
+
+		// Frontends send an empty prompt to preload a model
+		if strings.TrimSpace(req.Prompt) == "" {
+			empty := ""
+			recordAPICall(chatSession, requestStartTime, true, "")
+			c.JSON(http.StatusOK, OllamaResponse{
+				Model:      req.Model,
+				CreatedAt:  time.Now().UTC(),
+				Response:   &empty,
+				Done:       true,
+				DoneReason: "load",
+			})
+			return
+		}
+
+		// DuckDuckGo has no system role, so the system prompt goes first as a user message
+		var messages []chat.Message
+		if strings.TrimSpace(req.System) != "" {
+			messages = append(messages, chat.Message{Role: "user", Content: req.System})
+		}
+		messages = append(messages, chat.Message{Role: "user", Content: req.Prompt})
+
+		serveOllama(c, chatSession, cfg, requestStartTime, req.Model, model, req.Stream, messages, req.Prompt, false)
+	}
+}
+
+// OllamaTagsHandler serves GET /api/tags
+func OllamaTagsHandler(chatSession *chat.Chat) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestStartTime := time.Now()
+
+		availableModels, err := providerModels(c.Request.Context(), chatSession)
+		if err != nil {
+			recordAPICall(chatSession, requestStartTime, false, "chat_error")
+			c.JSON(http.StatusBadGateway, OllamaErrorResponse{Error: err.Error()})
+			return
+		}
+
+		response := OllamaTagsResponse{Models: []OllamaModel{}}
+		for _, model := range availableModels {
+			digest := sha256.Sum256([]byte(model.UpstreamID))
+			name := model.ID
+			if !strings.Contains(name, ":") {
+				name += ":latest"
+			}
+			response.Models = append(response.Models, OllamaModel{
+				Name:       name,
+				Model:      name,
+				ModifiedAt: startTime.UTC(),
+				Digest:     hex.EncodeToString(digest[:]),
+				Details: OllamaModelDetails{
+					Format: chatSession.Provider.Name(),
+					Family: model.Name,
+				},
+			})
+		}
+
+		recordAPICall(chatSession, requestStartTime, true, "")
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// OllamaVersionHandler serves GET /api/version, which frontends use to detect an Ollama server
+func OllamaVersionHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"version": ollamaVersion})
+	}
+}
+
+// serveOllama sends the conversation upstream and writes the answer either as
+// Ollama's newline-delimited JSON stream or as a single object. Ollama streams
+// unless the request sets "stream": false.
+func serveOllama(c *gin.Context, chatSession *chat.Chat, cfg *config.Config, requestStartTime time.Time, modelName string, model models.Model, streamRequested *bool, messages []chat.Message, prompt string, chatFormat bool) {
+	streaming := streamRequested == nil || *streamRequested
+
+	if cfg.API.LogRequests {
+		ui.APILog("Received Ollama request from %s (model: %s, messages: %d, stream: %t)", c.ClientIP(), modelName, len(messages), streaming)
+	}
+
+	session := chatSession.Fork(messages, model)
+	stream, err := session.FetchStream(c.Request.Context(), prompt)
+	if err != nil {
+		recordAPICall(chatSession, requestStartTime, false, "chat_error")
+		c.JSON(http.StatusBadGateway, OllamaErrorResponse{Error: err.Error()})
+		return
+	}
+
+	response := func(content string, done bool) OllamaResponse {
+		r := OllamaResponse{Model: modelName, CreatedAt: time.Now().UTC(), Done: done}
+		if chatFormat {
+			r.Message = &OllamaMessage{Role: "assistant", Content: content}
+		} else {
+			r.Response = &content
+		}
+		return r
+	}
+
+	promptTokens := 0
+	for _, msg := range messages {
+		promptTokens += estimateTokens(msg.Content)
+	}
+	result := &chat.StreamResult{}
+	final := func(content string) OllamaResponse {
+		r := response(content, true)
+		r.DoneReason = answerFinishReason(result)
+		r.TotalDuration = time.Since(requestStartTime).Nanoseconds()
+		r.EvalDuration = r.TotalDuration
+		r.PromptEvalCount = promptTokens
+		r.EvalCount = estimateTokens(result.Content())
+		return r
+	}
+
+	clearWriteDeadline(c)
+
+	if !streaming {
+		for event := range stream {
+			result.Add(event)
+		}
+		if result.Content() == "" && result.Err != nil {
+			recordAPICall(chatSession, requestStartTime, false, "stream_error")
+			c.JSON(http.StatusBadGateway, OllamaErrorResponse{Error: result.Err.Error()})
+			return
+		}
+		recordAPICall(chatSession, requestStartTime, true, "")
+		c.JSON(http.StatusOK, final(result.Content()))
+		return
+	}
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Status(http.StatusOK)
+
+	// An error once the answer has started ends the stream with an error
+	// object, as Ollama does
+	c.Stream(func(w io.Writer) bool {
+		event, ok := <-stream
+		if !ok {
+			writeNDJSON(w, final(""))
+			return false
+		}
+		result.Add(event)
+		switch event.Type {
+		case chat.EventDelta:
+			writeNDJSON(w, response(event.Content, false))
+		case chat.EventError:
+			writeNDJSON(w, OllamaErrorResponse{Error: event.Err.Error()})
+			return false
+		}
+		return true
+	})
+
+	for event := range stream {
+		result.Add(event)
+	}
+	if result.Err != nil {
+		recordAPICall(chatSession, requestStartTime, false, "stream_error")
+		return
+	}
+	recordAPICall(chatSession, requestStartTime, true, "")
+}
+
+// resolveOllamaModel maps an Ollama model name such as "llama:latest" onto a
+// model, answering 404 when it is unknown
+func resolveOllamaModel(c *gin.Context, chatSession *chat.Chat, requestStartTime time.Time, name string) (models.Model, bool) {
+	model, ok := chat.ResolveModelName(chatSession, strings.TrimSuffix(name, ":latest"))
+	if !ok {
+		recordAPICall(chatSession, requestStartTime, false, "model_not_found")
+		c.JSON(http.StatusNotFound, OllamaErrorResponse{Error: fmt.Sprintf("model '%s' not found", name)})
+	}
+	return model, ok
+}
+
+// writeNDJSON writes a single newline-delimited JSON object
+func writeNDJSON(w io.Writer, payload interface{}) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "%s\n", data)
+}
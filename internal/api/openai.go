@@ -0,0 +1,351 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"duckduckgo-chat-cli/internal/chat"
+	"duckduckgo-chat-cli/internal/config"
+	"duckduckgo-chat-cli/internal/ui"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OpenAI-compatible types. These mirror the subset of the OpenAI chat
+// completions schema used by common clients (SDKs, editors, LangChain).
+
+// OpenAIChatRequest represents an OpenAI chat completion request
+type OpenAIChatRequest struct {
+	Model    string          `json:"model"`
+	Messages []OpenAIMessage `json:"messages" binding:"required"`
+	Stream   bool            `json:"stream"`
+}
+
+// OpenAIMessage represents a single message in an OpenAI conversation
+type OpenAIMessage struct {
+	Role    string        `json:"role"`
+	Content OpenAIContent `json:"content"`
+}
+
+// OpenAIContent holds message content, which clients may send either as a
+// plain string or as an array of typed parts ({"type":"text","text":"..."}).
+type OpenAIContent string
+
+// UnmarshalJSON accepts both the string and the content-parts forms
+func (oc *OpenAIContent) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*oc = OpenAIContent(text)
+		return nil
+	}
+
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(data, &parts); err != nil {
+		return fmt.Errorf("content must be a string or an array of content parts")
+	}
+
+	var sb strings.Builder
+	for _, part := range parts {
+		if part.Type != "text" {
+			continue
+		}
+		if sb.Len() > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(part.Text)
+	}
+	*oc = OpenAIContent(sb.String())
+	return nil
+}
+
+// OpenAIChatCompletion represents a complete (non-streamed) chat completion
+type OpenAIChatCompletion struct {
+	ID      string         `json:"id"`
+	Object  string         `json:"object"`
+	Created int64          `json:"created"`
+	Model   string         `json:"model"`
+	Choices []OpenAIChoice `json:"choices"`
+	Usage   *OpenAIUsage   `json:"usage,omitempty"`
+}
+
+// OpenAIChoice represents a completion choice or a streamed chunk choice
+type OpenAIChoice struct {
+	Index        int            `json:"index"`
+	Message      *OpenAIMessage `json:"message,omitempty"`
+	Delta        *OpenAIDelta   `json:"delta,omitempty"`
+	FinishReason *string        `json:"finish_reason"`
+}
+
+// OpenAIDelta represents the incremental content of a streamed chunk
+type OpenAIDelta struct {
+	Role    string `json:"role,omitempty"`
+	Content string `json:"content,omitempty"`
+}
+
+// OpenAIUsage reports estimated token usage
+type OpenAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// OpenAIModel represents a model entry in the OpenAI models list
+type OpenAIModel struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Created int64  `json:"created"`
+	OwnedBy string `json:"owned_by"`
+}
+
+// OpenAIModelList represents the OpenAI models list response
+type OpenAIModelList struct {
+	Object string        `json:"object"`
+	Data   []OpenAIModel `json:"data"`
+}
+
+// OpenAIErrorResponse represents an error in the OpenAI envelope
+type OpenAIErrorResponse struct {
+	Error OpenAIError `json:"error"`
+}
+
+// OpenAIError holds OpenAI-style error details
+type OpenAIError struct {
+	Message string `json:"message"`
+	Type    string `json:"type"`
+	Code    string `json:"code,omitempty"`
+}
+
+// OpenAIChatCompletionsHandler serves POST /v1/chat/completions
+func OpenAIChatCompletionsHandler(chatSession *chat.Chat, cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestStartTime := time.Now()
+
+		var req OpenAIChatRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			recordAPICall(chatSession, requestStartTime, false, "validation")
+			c.JSON(http.StatusBadRequest, newOpenAIError("invalid_request_error", "Invalid request payload: "+err.Error()))
+			return
+		}
+
+		messages, prompt := convertOpenAIMessages(req.Messages)
+		if prompt == "" {
+			recordAPICall(chatSession, requestStartTime, false, "validation")
+			c.JSON(http.StatusBadRequest, newOpenAIError("invalid_request_error", "messages must contain at least one user message with content"))
+			return
+		}
+
+		modelName := req.Model
+		if modelName == "" {
+			modelName = string(chatSession.CurrentModel())
+		}
+		model, ok := chat.ResolveModelName(chatSession, modelName)
+		if !ok {
+			recordAPICall(chatSession, requestStartTime, false, "model_not_found")
+			openAIErr := newOpenAIError("invalid_request_error", fmt.Sprintf("The model '%s' does not exist", modelName))
+			openAIErr.Error.Code = "model_not_found"
+			c.JSON(http.StatusNotFound, openAIErr)
+			return
+		}
+		session := chatSession.Fork(messages, model)
+
+		if cfg.API.LogRequests {
+			ui.APILog("Received OpenAI chat completion from %s (model: %s, messages: %d, stream: %t)", c.ClientIP(), modelName, len(messages), req.Stream)
+		}
+
+		stream, err := session.FetchStream(c.Request.Context(), prompt)
+		if err != nil {
+			recordAPICall(chatSession, requestStartTime, false, "chat_error")
+			c.JSON(http.StatusBadGateway, newOpenAIError("upstream_error", err.Error()))
+			return
+		}
+
+		completionID := fmt.Sprintf("chatcmpl-%d", time.Now().UnixNano())
+		created := time.Now().Unix()
+
+		if req.Stream {
+			result := streamOpenAIChunks(c, stream, completionID, created, modelName)
+			if result.Err != nil {
+				recordAPICall(chatSession, requestStartTime, false, "stream_error")
+				return
+			}
+			recordAPICall(chatSession, requestStartTime, true, "")
+			return
+		}
+
+		clearWriteDeadline(c)
+		result := &chat.StreamResult{}
+		for event := range stream {
+			result.Add(event)
+		}
+		content := result.Content()
+		if content == "" && result.Err != nil {
+			recordAPICall(chatSession, requestStartTime, false, "stream_error")
+			c.JSON(http.StatusBadGateway, newOpenAIError("upstream_error", result.Err.Error()))
+			return
+		}
+
+		promptTokens := 0
+		for _, msg := range messages {
+			promptTokens += estimateTokens(msg.Content)
+		}
+		completionTokens := estimateTokens(content)
+		finishReason := answerFinishReason(result)
+
+		completion := OpenAIChatCompletion{
+			ID:      completionID,
+			Object:  "chat.completion",
+			Created: created,
+			Model:   modelName,
+			Choices: []OpenAIChoice{
+				{
+					Index:        0,
+					Message:      &OpenAIMessage{Role: "assistant", Content: OpenAIContent(content)},
+					FinishReason: &finishReason,
+				},
+			},
+			Usage: &OpenAIUsage{
+				PromptTokens:     promptTokens,
+				CompletionTokens: completionTokens,
+				TotalTokens:      promptTokens + completionTokens,
+			},
+		}
+
+		recordAPICall(chatSession, requestStartTime, true, "")
+		c.JSON(http.StatusOK, completion)
+	}
+}
+
+// OpenAIModelsHandler serves GET /v1/models
+func OpenAIModelsHandler(chatSession *chat.Chat) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestStartTime := time.Now()
+
+		availableModels, err := providerModels(c.Request.Context(), chatSession)
+		if err != nil {
+			recordAPICall(chatSession, requestStartTime, false, "chat_error")
+			c.JSON(http.StatusBadGateway, newOpenAIError("upstream_error", err.Error()))
+			return
+		}
+		data := make([]OpenAIModel, len(availableModels))
+		for i, model := range availableModels {
+			data[i] = OpenAIModel{
+				ID:      model.ID,
+				Object:  "model",
+				Created: startTime.Unix(),
+				OwnedBy: chatSession.Provider.Name(),
+			}
+		}
+
+		recordAPICall(chatSession, requestStartTime, true, "")
+		c.JSON(http.StatusOK, OpenAIModelList{Object: "list", Data: data})
+	}
+}
+
+// answerFinishReason maps the end of an answer onto the OpenAI and Ollama
+// finish reasons: "length" for an answer cut short, "stop" otherwise
+func answerFinishReason(result *chat.StreamResult) string {
+	if result.Truncated() {
+		return chat.FinishLength
+	}
+	return chat.FinishStop
+}
+
+// streamOpenAIChunks writes the upstream stream as OpenAI chat.completion.chunk
+// events. An error once the answer has started is sent as an error object, as
+// OpenAI does, instead of the final chunk.
+func streamOpenAIChunks(c *gin.Context, stream <-chan chat.StreamEvent, completionID string, created int64, modelName string) *chat.StreamResult {
+	clearWriteDeadline(c)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+
+	chunk := func(delta OpenAIDelta, finishReason *string) OpenAIChatCompletion {
+		return OpenAIChatCompletion{
+			ID:      completionID,
+			Object:  "chat.completion.chunk",
+			Created: created,
+			Model:   modelName,
+			Choices: []OpenAIChoice{{Index: 0, Delta: &delta, FinishReason: finishReason}},
+		}
+	}
+
+	result := &chat.StreamResult{}
+	writeSSEData(c.Writer, chunk(OpenAIDelta{Role: "assistant"}, nil))
+	c.Stream(func(w io.Writer) bool {
+		event, ok := <-stream
+		if !ok {
+			if c.Request.Context().Err() == nil {
+				finishReason := answerFinishReason(result)
+				writeSSEData(w, chunk(OpenAIDelta{}, &finishReason))
+				fmt.Fprint(w, "data: [DONE]\n\n")
+			}
+			return false
+		}
+		result.Add(event)
+		switch event.Type {
+		case chat.EventDelta:
+			writeSSEData(w, chunk(OpenAIDelta{Content: event.Content}, nil))
+		case chat.EventError:
+			writeSSEData(w, newOpenAIError("upstream_error", event.Err.Error()))
+			return false
+		}
+		return true
+	})
+
+	for event := range stream {
+		result.Add(event)
+	}
+	return result
+}
+
+// writeSSEData writes a single Server-Sent Events data line with a JSON payload
+func writeSSEData(w io.Writer, payload interface{}) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "data: %s\n\n", data)
+}
+
+// convertOpenAIMessages maps OpenAI messages onto chat messages and returns the
+// content of the last user message. DuckDuckGo only knows the user and
+// assistant roles, so system and tool messages are sent as user messages.
+func convertOpenAIMessages(messages []OpenAIMessage) ([]chat.Message, string) {
+	result := make([]chat.Message, 0, len(messages))
+	prompt := ""
+	for _, msg := range messages {
+		content := string(msg.Content)
+		if strings.TrimSpace(content) == "" {
+			continue
+		}
+
+		role := "user"
+		if msg.Role == "assistant" {
+			role = "assistant"
+		} else {
+			prompt = content
+		}
+		result = append(result, chat.Message{Role: role, Content: content})
+	}
+	return result, prompt
+}
+
+// newOpenAIError creates an OpenAI-style error response
+func newOpenAIError(errorType, message string) OpenAIErrorResponse {
+	return OpenAIErrorResponse{Error: OpenAIError{Message: message, Type: errorType}}
+}
+
+// recordAPICall tracks an API call in the session analytics
+func recordAPICall(chatSession *chat.Chat, requestStartTime time.Time, success bool, errorType string) {
+	if chatSession.Analytics != nil {
+		chatSession.Analytics.RecordAPICall(time.Since(requestStartTime), success, errorType)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"duckduckgo-chat-cli/internal/chat"
@@ -20,6 +21,7 @@ import (
 
 var server *http.Server
 var router *gin.Engine
+var sessions *sessionStore
 
 // IsRunning checks if the API server is currently active.
 func IsRunning() bool {
@@ -33,6 +35,51 @@ func StartServer(chatSession *chat.Chat, cfg *config.Config, port int) {
 		return
 	}
 
+	newServer(chatSession, cfg, port)
+
+	go func() {
+		printServerInfo(port)
+
+		if err := server.ListenAndServe(); err != http.ErrServerClosed {
+			ui.Errorln("API server error: %v", err)
+			server = nil
+		}
+	}()
+}
+
+// Serve runs the API server in the foreground until ctx is cancelled, then
+// shuts it down gracefully with StopServer. It returns an error if the server
+// cannot listen on the port. Serve is used by the headless serve mode.
+func Serve(ctx context.Context, chatSession *chat.Chat, cfg *config.Config, port int) error {
+	if server != nil {
+		return fmt.Errorf("API server is already running")
+	}
+
+	srv := newServer(chatSession, cfg, port)
+	printServerInfo(port)
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errChan:
+		server = nil
+		router = nil
+		if sessions != nil {
+			sessions.close()
+			sessions = nil
+		}
+		return fmt.Errorf("API server error: %w", err)
+	case <-ctx.Done():
+		StopServer()
+		return nil
+	}
+}
+
+// newServer configures the router and the HTTP server for the given port
+func newServer(chatSession *chat.Chat, cfg *config.Config, port int) *http.Server {
 	// Set Gin mode based on environment
 	if os.Getenv("DEBUG") != "true" {
 		gin.SetMode(gin.ReleaseMode)
@@ -44,20 +91,25 @@ func StartServer(chatSession *chat.Chat, cfg *config.Config, port int) {
 		Addr:         fmt.Sprintf(":%d", port),
 		Handler:      router,
 		ReadTimeout:  30 * time.Second,
-		WriteTimeout: 30 * time.Second,
+		WriteTimeout: 30 * time.Second, // lifted by clearWriteDeadline for chat answers
 		IdleTimeout:  120 * time.Second,
 	}
+	return server
+}
 
-	go func() {
-		ui.Systemln("Starting enhanced API server on port %d", port)
-		ui.Systemln("API Documentation available at: http://localhost:%d/doc/index.html", port)
-		ui.Systemln("API Base URL: http://localhost:%d/api/v1", port)
+// clearWriteDeadline lifts the server's WriteTimeout for the current request.
+// A chat answer, with its retries and backoff, can take longer than that to
+// complete, whether it is streamed or collected before being written.
+func clearWriteDeadline(c *gin.Context) {
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+}
 
-		if err := server.ListenAndServe(); err != http.ErrServerClosed {
-			ui.Errorln("API server error: %v", err)
-			server = nil
-		}
-	}()
+func printServerInfo(port int) {
+	ui.Systemln("Starting enhanced API server on port %d", port)
+	ui.Systemln("API Documentation available at: http://localhost:%d/doc/index.html", port)
+	ui.Systemln("API Base URL: http://localhost:%d/api/v1", port)
+	ui.Systemln("OpenAI-compatible Base URL: http://localhost:%d/v1", port)
+	ui.Systemln("Ollama-compatible URL: http://localhost:%d", port)
 }
 
 // StopServer gracefully shuts down the API server.
@@ -78,18 +130,27 @@ func StopServer() {
 	}
 	server = nil
 	router = nil
+	if sessions != nil {
+		sessions.close()
+		sessions = nil
+	}
 }
 
 // setupRouter configures the Gin router with all routes and middleware
 func setupRouter(chatSession *chat.Chat, cfg *config.Config) *gin.Engine {
 	router := gin.New()
+	sessions = newSessionStore(chatSession, cfg)
 
 	// Add middleware conditionally
 	if cfg.API.ShowGinLogs {
 		router.Use(gin.Logger())
 	}
 	router.Use(gin.Recovery())
-	router.Use(corsMiddleware())
+	router.Use(corsMiddleware(cfg))
+
+	if len(config.APIKeys(cfg)) == 0 {
+		ui.Warningln("No API keys configured: anyone who can reach the server can use this session. Add keys in /config > API Settings.")
+	}
 
 	// API root with basic info
 	router.GET("/", func(c *gin.Context) {
@@ -98,14 +159,28 @@ func setupRouter(chatSession *chat.Chat, cfg *config.Config) *gin.Engine {
 			"version": "1.0.0",
 			"docs":    "/doc/index.html",
 			"api":     "/api/v1",
+			"openai":  "/v1",
+			"ollama":  "/api",
+			"metrics": "/metrics",
 		})
 	})
 
 	// Swagger documentation route
 	router.GET("/doc/*any", ginSwagger.WrapHandler(swaggerfiles.Handler))
 
+	// Health endpoint stays public so monitors don't need a key
+	router.GET("/api/v1/health", HealthHandler())
+
+	// The routes below that live outside /api/v1 (metrics, OpenAI and Ollama)
+	// are not part of the Swagger spec. Their chat handlers run upstream
+	// requests on the client's request context, so a client going away aborts
+	// the request and closes the stream shortly after.
+
+	// Prometheus metrics: authenticated, but never rate limited so scrapes don't fail
+	router.GET("/metrics", authMiddleware(cfg), MetricsHandler(chatSession, sessions))
+
 	// API v1 routes
-	v1 := router.Group("/api/v1")
+	v1 := router.Group("/api/v1", authMiddleware(cfg), rateLimitMiddleware(cfg))
 	{
 		// Chat endpoints
 		v1.POST("/chat", ChatHandler(chatSession, cfg))
@@ -116,23 +191,74 @@ func setupRouter(chatSession *chat.Chat, cfg *config.Config) *gin.Engine {
 		v1.GET("/models", ModelsHandler(chatSession))
 		v1.POST("/models", ModelChangeHandler(chatSession))
 
+		// Context endpoints
+		v1.GET("/context", ContextHandler(chatSession))
+		v1.POST("/context/file", FileContextHandler(chatSession, cfg))
+		v1.POST("/context/url", URLContextHandler(chatSession, cfg))
+		v1.POST("/context/search", SearchContextHandler(chatSession, cfg))
+		v1.POST("/context/library", LibraryContextHandler(chatSession, cfg))
+		v1.POST("/context/pmp", PMPContextHandler(chatSession, cfg))
+
 		// Session endpoints
 		v1.GET("/session", SessionInfoHandler(chatSession))
 
-		// Health endpoint
-		v1.GET("/health", HealthHandler())
+		// Isolated per-client sessions
+		v1.POST("/sessions", CreateSessionHandler(sessions, cfg))
+		v1.GET("/sessions/:id", GetSessionHandler(sessions))
+		v1.DELETE("/sessions/:id", DeleteSessionHandler(sessions, cfg))
+		v1.POST("/sessions/:id/chat", SessionChatHandler(sessions, cfg))
+
+		// Saved conversations from the history manager
+		v1.GET("/archive/sessions", ArchiveListHandler(chatSession))
+		v1.GET("/archive/sessions/:id", ArchiveSessionHandler(chatSession))
+		v1.POST("/archive/sessions/:id/restore", ArchiveRestoreHandler(chatSession, cfg))
+		v1.GET("/archive/search", ArchiveSearchHandler(chatSession))
+		v1.GET("/archive/stats", ArchiveStatsHandler(chatSession))
+	}
+
+	// OpenAI-compatible routes, so any OpenAI client can use duckchat as its base URL
+	openai := router.Group("/v1", authMiddleware(cfg), rateLimitMiddleware(cfg))
+	{
+		openai.POST("/chat/completions", OpenAIChatCompletionsHandler(chatSession, cfg))
+		openai.GET("/models", OpenAIModelsHandler(chatSession))
+	}
+
+	// Ollama-compatible routes, so Ollama frontends can use duckchat as their server
+	router.GET("/api/version", OllamaVersionHandler())
+	ollama := router.Group("/api", authMiddleware(cfg), rateLimitMiddleware(cfg))
+	{
+		ollama.POST("/chat", OllamaChatHandler(chatSession, cfg))
+		ollama.POST("/generate", OllamaGenerateHandler(chatSession, cfg))
+		ollama.GET("/tags", OllamaTagsHandler(chatSession))
 	}
 
 	return router
 }
 
-// corsMiddleware adds CORS headers to allow cross-origin requests
-func corsMiddleware() gin.HandlerFunc {
+// corsMiddleware adds CORS headers for the origins allowed in the configuration.
+// Browsers from any other origin are refused; "*" allows every origin but
+// without credentials.
+func corsMiddleware(cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
+		origin := c.Request.Header.Get("Origin")
+		c.Writer.Header().Add("Vary", "Origin")
+
+		if origin != "" {
+			for _, allowed := range cfg.API.AllowedOrigins {
+				if allowed == "*" {
+					c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
+					break
+				}
+				if strings.EqualFold(allowed, origin) {
+					c.Writer.Header().Set("Access-Control-Allow-Origin", origin)
+					c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
+					break
+				}
+			}
+		}
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, X-API-Key, accept, origin, Cache-Control, X-Requested-With")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "Retry-After, X-RateLimit-Limit, X-RateLimit-Remaining")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -0,0 +1,344 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"duckduckgo-chat-cli/internal/chat"
+	"duckduckgo-chat-cli/internal/config"
+	"duckduckgo-chat-cli/internal/models"
+	"duckduckgo-chat-cli/internal/ui"
+
+	"github.com/gin-gonic/gin"
+)
+
+var errSessionLimit = errors.New("maximum number of sessions reached")
+
+// apiSession is an isolated conversation owned by one API client
+type apiSession struct {
+	chat       *chat.Chat
+	createdAt  time.Time
+	lastActive time.Time
+}
+
+// sessionStore keeps the per-client sessions of the API server and evicts the
+// ones that have been idle for longer than the configured timeout.
+type sessionStore struct {
+	mu          sync.Mutex
+	sessions    map[string]*apiSession
+	maxSessions int
+	idleTimeout time.Duration
+	parent      *chat.Chat
+	cfg         *config.Config
+	stop        chan struct{}
+}
+
+// newSessionStore creates a session store and starts its eviction loop
+func newSessionStore(parent *chat.Chat, cfg *config.Config) *sessionStore {
+	store := &sessionStore{
+		sessions:    make(map[string]*apiSession),
+		maxSessions: *cfg.API.MaxSessions,
+		idleTimeout: time.Duration(*cfg.API.SessionIdleTimeout) * time.Minute,
+		parent:      parent,
+		cfg:         cfg,
+		stop:        make(chan struct{}),
+	}
+	if store.idleTimeout > 0 {
+		go store.evictLoop()
+	}
+	return store
+}
+
+// create starts a new session with its own history, model and VQD state.
+// The VQD is fetched lazily on the session's first message.
+func (s *sessionStore) create(model models.Model) (*apiSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.maxSessions > 0 && len(s.sessions) >= s.maxSessions {
+		return nil, errSessionLimit
+	}
+
+	id, err := newSessionID()
+	if err != nil {
+		return nil, err
+	}
+
+	session := chat.NewAPIChat(model, s.cfg)
+	session.SessionID = id
+	// Report API traffic in the server's statistics
+	session.Analytics = s.parent.Analytics
+
+	now := time.Now()
+	entry := &apiSession{chat: session, createdAt: now, lastActive: now}
+	s.sessions[id] = entry
+	return entry, nil
+}
+
+// get returns the session with the given ID and marks it as active
+func (s *sessionStore) get(id string) (*apiSession, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.sessions[id]
+	if ok {
+		entry.lastActive = time.Now()
+	}
+	return entry, ok
+}
+
+// remove deletes the session with the given ID
+func (s *sessionStore) remove(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.sessions[id]; !ok {
+		return false
+	}
+	delete(s.sessions, id)
+	return true
+}
+
+// count returns the number of open sessions
+func (s *sessionStore) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.sessions)
+}
+
+// info returns the API representation of a session
+func (s *sessionStore) info(entry *apiSession) SessionResponse {
+	s.mu.Lock()
+	lastActive := entry.lastActive
+	s.mu.Unlock()
+
+	state := entry.chat.State()
+	response := SessionResponse{
+		ID:           state.SessionID,
+		Model:        string(state.Model),
+		MessageCount: state.MessageCount,
+		QueueDepth:   state.QueueDepth,
+		CreatedAt:    entry.createdAt,
+		LastActivity: lastActive,
+	}
+	if s.idleTimeout > 0 {
+		expiresAt := lastActive.Add(s.idleTimeout)
+		response.ExpiresAt = &expiresAt
+	}
+	return response
+}
+
+// evictLoop periodically removes idle sessions until the store is closed
+func (s *sessionStore) evictLoop() {
+	interval := s.idleTimeout / 2
+	if interval > time.Minute {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.evictIdle()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// evictIdle removes every session that has been idle for too long
+func (s *sessionStore) evictIdle() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cutoff := time.Now().Add(-s.idleTimeout)
+	for id, entry := range s.sessions {
+		if entry.lastActive.Before(cutoff) {
+			delete(s.sessions, id)
+			if s.cfg.API.LogRequests {
+				ui.APILog("Evicted idle session %s", id)
+			}
+		}
+	}
+}
+
+// close stops the eviction loop
+func (s *sessionStore) close() {
+	close(s.stop)
+}
+
+// newSessionID returns a random, hard to guess session identifier
+func newSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("error generating session ID: %w", err)
+	}
+	return "sess_" + hex.EncodeToString(b), nil
+}
+
+// CreateSessionHandler handles session creation requests
+// @Summary      Create a session
+// @Description  Create an isolated chat session with its own history, model and VQD state.
+// @Description  Sessions are evicted after a period of inactivity.
+// @Tags         Session
+// @Accept       json
+// @Produce      json
+// @Param        request body CreateSessionRequest false "Session options"
+// @Success      201 {object} APIResponse{data=SessionResponse} "Session created successfully"
+// @Failure      400 {object} APIResponse{error=APIError} "Invalid request or unknown model"
+// @Failure      429 {object} APIResponse{error=APIError} "Maximum number of sessions reached"
+// @Security     ApiKeyAuth
+// @Router       /sessions [post]
+func CreateSessionHandler(store *sessionStore, cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestStartTime := time.Now()
+
+		var req CreateSessionRequest
+		if c.Request.ContentLength > 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				recordAPICall(store.parent, requestStartTime, false, "validation")
+				response := NewErrorResponse(ErrorCodeValidation, "Invalid request payload", err.Error())
+				c.JSON(http.StatusBadRequest, response)
+				return
+			}
+		}
+
+		model := chat.ConfiguredModel(cfg)
+		if req.Model != "" {
+			var ok bool
+			if model, ok = chat.ResolveModelName(store.parent, req.Model); !ok {
+				recordAPICall(store.parent, requestStartTime, false, "model_not_found")
+				response := NewErrorResponse(ErrorCodeModelNotFound, "Cannot create session", fmt.Sprintf("Model '%s' is not available", req.Model))
+				c.JSON(http.StatusBadRequest, response)
+				return
+			}
+		}
+
+		entry, err := store.create(model)
+		if err != nil {
+			if errors.Is(err, errSessionLimit) {
+				recordAPICall(store.parent, requestStartTime, false, "session_limit")
+				response := NewErrorResponse(ErrorCodeSessionLimit, "Cannot create session", fmt.Sprintf("The server already holds %d sessions", store.maxSessions))
+				c.JSON(http.StatusTooManyRequests, response)
+				return
+			}
+			recordAPICall(store.parent, requestStartTime, false, "internal")
+			response := NewErrorResponse(ErrorCodeInternal, "Cannot create session", err.Error())
+			c.JSON(http.StatusInternalServerError, response)
+			return
+		}
+
+		if cfg.API.LogRequests {
+			ui.APILog("Created session %s for %s (model: %s)", entry.chat.SessionID, c.ClientIP(), entry.chat.Model)
+		}
+
+		recordAPICall(store.parent, requestStartTime, true, "")
+		successResponse := NewSuccessResponse(store.info(entry), "Session created successfully")
+		c.JSON(http.StatusCreated, successResponse)
+	}
+}
+
+// GetSessionHandler handles session retrieval requests
+// @Summary      Get a session
+// @Description  Get a session's information and message history
+// @Tags         Session
+// @Produce      json
+// @Param        id path string true "Session ID"
+// @Success      200 {object} APIResponse{data=SessionResponse} "Session retrieved successfully"
+// @Failure      404 {object} APIResponse{error=APIError} "Session not found"
+// @Security     ApiKeyAuth
+// @Router       /sessions/{id} [get]
+func GetSessionHandler(store *sessionStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestStartTime := time.Now()
+
+		entry, ok := store.get(c.Param("id"))
+		if !ok {
+			recordAPICall(store.parent, requestStartTime, false, "session_not_found")
+			c.JSON(http.StatusNotFound, sessionNotFound(c.Param("id")))
+			return
+		}
+
+		sessionResponse := store.info(entry)
+		sessionResponse.Messages = ConvertChatMessagesToResponse(entry.chat.MessagesSnapshot(), sessionResponse.ID, models.Model(sessionResponse.Model)).Messages
+
+		recordAPICall(store.parent, requestStartTime, true, "")
+		successResponse := NewSuccessResponse(sessionResponse, "Session retrieved successfully")
+		c.JSON(http.StatusOK, successResponse)
+	}
+}
+
+// DeleteSessionHandler handles session deletion requests
+// @Summary      Delete a session
+// @Description  Delete a session and its message history
+// @Tags         Session
+// @Produce      json
+// @Param        id path string true "Session ID"
+// @Success      200 {object} APIResponse "Session deleted successfully"
+// @Failure      404 {object} APIResponse{error=APIError} "Session not found"
+// @Security     ApiKeyAuth
+// @Router       /sessions/{id} [delete]
+func DeleteSessionHandler(store *sessionStore, cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestStartTime := time.Now()
+
+		id := c.Param("id")
+		if !store.remove(id) {
+			recordAPICall(store.parent, requestStartTime, false, "session_not_found")
+			c.JSON(http.StatusNotFound, sessionNotFound(id))
+			return
+		}
+
+		if cfg.API.LogRequests {
+			ui.APILog("Deleted session %s", id)
+		}
+
+		recordAPICall(store.parent, requestStartTime, true, "")
+		successResponse := NewSuccessResponse(nil, "Session deleted successfully")
+		c.JSON(http.StatusOK, successResponse)
+	}
+}
+
+// SessionChatHandler handles chat requests on a specific session
+// @Summary      Send a chat message to a session
+// @Description  Send a message within an isolated session. Requests on the same session are queued and processed one at a time.
+// @Description  With stream=true the answer is sent as Server-Sent Events, like POST /chat.
+// @Tags         Session
+// @Accept       json
+// @Produce      json,text/event-stream
+// @Param        id path string true "Session ID"
+// @Param        request body ChatRequest true "Chat message request"
+// @Param        stream query bool false "Stream the response as Server-Sent Events"
+// @Success      200 {object} APIResponse{data=ChatResponse} "Successful chat response"
+// @Failure      400 {object} APIResponse{error=APIError} "Invalid request"
+// @Failure      404 {object} APIResponse{error=APIError} "Session not found"
+// @Failure      500 {object} APIResponse{error=APIError} "Internal server error"
+// @Failure      503 {object} APIResponse{error=APIError} "Session busy, retry after the Retry-After delay"
+// @Security     ApiKeyAuth
+// @Router       /sessions/{id}/chat [post]
+func SessionChatHandler(store *sessionStore, cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		entry, ok := store.get(c.Param("id"))
+		if !ok {
+			recordAPICall(store.parent, time.Now(), false, "session_not_found")
+			c.JSON(http.StatusNotFound, sessionNotFound(c.Param("id")))
+			return
+		}
+
+		handleChat(c, entry.chat, cfg, true)
+
+		// A long generation counts as activity
+		store.get(entry.chat.SessionID)
+	}
+}
+
+// sessionNotFound creates the error response for an unknown session
+func sessionNotFound(id string) APIResponse {
+	return NewErrorResponse(ErrorCodeInvalidSession, "Session not found", fmt.Sprintf("Session '%s' does not exist or has expired", id))
+}
@@ -1,10 +1,12 @@
 package api
 
 import (
-	"fmt"
+	"context"
+	"encoding/json"
 	"time"
 
 	"duckduckgo-chat-cli/internal/chat"
+	"duckduckgo-chat-cli/internal/config"
 	"duckduckgo-chat-cli/internal/models"
 )
 
@@ -15,14 +17,47 @@ import (
 type ChatRequest struct {
 	Message string `json:"message" binding:"required" example:"Hello, how are you?" minLength:"1" maxLength:"10000"`
 	Model   string `json:"model,omitempty" example:"gpt-4o-mini"`
+	Stream  bool   `json:"stream,omitempty" example:"false"`
 } // @name ChatRequest
 
 // ModelChangeRequest represents a model change request
 // @Description Model change request payload
 type ModelChangeRequest struct {
-	Model string `json:"model" binding:"required" example:"gpt-4o-mini" enum:"gpt-4o-mini,claude-3-haiku,llama,mixtral,o4mini"`
+	Model string `json:"model" binding:"required" example:"gpt-4o-mini"`
 } // @name ModelChangeRequest
 
+// CreateSessionRequest represents a session creation request
+// @Description Session creation request payload
+type CreateSessionRequest struct {
+	Model string `json:"model,omitempty" example:"gpt-4o-mini"`
+} // @name CreateSessionRequest
+
+// URLContextRequest represents a request to load a webpage as context
+// @Description URL context request payload
+type URLContextRequest struct {
+	URL string `json:"url" binding:"required" example:"https://go.dev/doc/effective_go"`
+} // @name URLContextRequest
+
+// SearchContextRequest represents a request to load web search results as context
+// @Description Search context request payload
+type SearchContextRequest struct {
+	Query string `json:"query" binding:"required" example:"golang generics tutorial"`
+} // @name SearchContextRequest
+
+// LibraryContextRequest represents a request to load library files as context
+// @Description Library context request payload
+type LibraryContextRequest struct {
+	Library string   `json:"library" binding:"required" example:"my_docs"`
+	Files   []string `json:"files,omitempty" example:"notes/todo.md"`
+} // @name LibraryContextRequest
+
+// PMPContextRequest represents a request to load a project prompt as context
+// @Description PMP context request payload
+type PMPContextRequest struct {
+	Library string `json:"library" binding:"required" example:"my_project"`
+	Path    string `json:"path,omitempty" example:"internal/api"`
+} // @name PMPContextRequest
+
 // API Response Types
 
 // APIResponse represents the standard API response wrapper
@@ -40,18 +75,50 @@ type APIResponse struct {
 type ChatResponse struct {
 	Response  string       `json:"response" example:"Hello! I'm doing well, thank you for asking."`
 	Model     string       `json:"model" example:"gpt-4o-mini"`
-	MessageID string       `json:"message_id" example:"msg_123456"`
+	MessageID string       `json:"message_id" example:"msg_5f2b9c0e1a7d3b64"`
 	Metadata  ChatMetadata `json:"metadata"`
 } // @name ChatResponse
 
 // ChatMetadata contains additional information about the chat response
 // @Description Metadata about the chat response
 type ChatMetadata struct {
-	ProcessingTime    int64 `json:"processing_time_ms" example:"1500"`
-	TokensEstimate    int   `json:"tokens_estimate" example:"45"`
-	ConversationCount int   `json:"conversation_count" example:"3"`
+	ProcessingTime    int64  `json:"processing_time_ms" example:"1500"`
+	TokensEstimate    int    `json:"tokens_estimate" example:"45"`
+	ConversationCount int    `json:"conversation_count" example:"3"`
+	ServedModel       string `json:"served_model,omitempty" example:"gpt-4o-mini-2024-07-18"`
+	Truncated         bool   `json:"truncated,omitempty" example:"false"`
+	FinishReason      string `json:"finish_reason,omitempty" example:"stop" enum:"stop,length"`
 } // @name ChatMetadata
 
+// ChatChunk represents a partial response sent as a "chunk" Server-Sent Event
+// @Description Partial chat response sent while streaming
+type ChatChunk struct {
+	Content string `json:"content" example:"Hello"`
+} // @name ChatChunk
+
+// ChatStreamMetadata is sent as a "metadata" Server-Sent Event when the
+// upstream service reports which model is answering
+// @Description Model and ID the upstream service gave the answer
+type ChatStreamMetadata struct {
+	Model string `json:"model,omitempty" example:"gpt-4o-mini-2024-07-18"`
+	ID    string `json:"id,omitempty" example:"chatcmpl-abc123"`
+} // @name ChatStreamMetadata
+
+// ChatStreamTool is sent as a "tool" Server-Sent Event for upstream actions
+// other than writing the answer
+// @Description Upstream action other than writing the answer
+type ChatStreamTool struct {
+	Action string          `json:"action" example:"tool_call"`
+	Data   json.RawMessage `json:"data,omitempty" swaggertype:"object"`
+} // @name ChatStreamTool
+
+// ChatStreamError is sent as an "error" Server-Sent Event when the answer
+// fails after it has started
+// @Description Error that ended a streamed answer
+type ChatStreamError struct {
+	Error string `json:"error" example:"upstream error ERR_CONVERSATION_LIMIT (status 429)"`
+} // @name ChatStreamError
+
 // HistoryResponse represents the chat history response
 // @Description Chat history response payload
 type HistoryResponse struct {
@@ -64,19 +131,103 @@ type HistoryResponse struct {
 // MessageResponse represents a single message in the history
 // @Description Individual message in chat history
 type MessageResponse struct {
-	ID        string    `json:"id" example:"msg_123456"`
+	ID        string    `json:"id" example:"msg_5f2b9c0e1a7d3b64"`
 	Role      string    `json:"role" example:"user" enum:"user,assistant"`
 	Content   string    `json:"content" example:"Hello, how are you?"`
 	Timestamp time.Time `json:"timestamp" example:"2023-01-01T12:00:00Z"`
+	Model     string    `json:"model,omitempty" example:"gpt-4o-mini"`
+	LatencyMs int64     `json:"latency_ms,omitempty" example:"1500"`
+	Source    string    `json:"source,omitempty" example:"typed" enum:"typed,file,url,search,library,pmp,chain"`
 } // @name MessageResponse
 
+// SessionResponse represents an isolated API session
+// @Description Information about an API session
+type SessionResponse struct {
+	ID           string            `json:"id" example:"sess_9f86d081884c7d659a2feaa0c55ad015"`
+	Model        string            `json:"model" example:"gpt-4o-mini"`
+	MessageCount int               `json:"message_count" example:"4"`
+	QueueDepth   int               `json:"queue_depth" example:"0"`
+	CreatedAt    time.Time         `json:"created_at" example:"2023-01-01T12:00:00Z"`
+	LastActivity time.Time         `json:"last_activity" example:"2023-01-01T12:05:00Z"`
+	ExpiresAt    *time.Time        `json:"expires_at,omitempty" example:"2023-01-01T12:35:00Z"`
+	Messages     []MessageResponse `json:"messages,omitempty"`
+} // @name SessionResponse
+
+// ContextItem represents a piece of context loaded in the conversation
+// @Description Context loaded in the conversation
+type ContextItem struct {
+	Index      int    `json:"index" example:"0"`
+	Kind       string `json:"kind" example:"file" enum:"file,url,search,project"`
+	Source     string `json:"source" example:"main.go"`
+	Characters int    `json:"characters" example:"1024"`
+} // @name ContextItem
+
+// ContextResponse represents the list of loaded context
+// @Description Context loaded in the conversation
+type ContextResponse struct {
+	Items           []ContextItem `json:"items"`
+	TotalItems      int           `json:"total_items" example:"2"`
+	TotalCharacters int           `json:"total_characters" example:"4096"`
+} // @name ContextResponse
+
+// ArchivedSession represents a conversation saved by the history manager
+// @Description Saved conversation metadata
+type ArchivedSession struct {
+	ID           string    `json:"id" example:"session_1700000000000000000"`
+	Model        string    `json:"model" example:"gpt-4o-mini"`
+	StartTime    time.Time `json:"start_time" example:"2023-01-01T12:00:00Z"`
+	EndTime      time.Time `json:"end_time" example:"2023-01-01T12:30:00Z"`
+	MessageCount int       `json:"message_count" example:"12"`
+	Compressed   bool      `json:"compressed" example:"true"`
+} // @name ArchivedSession
+
+// ArchiveListResponse represents a page of saved conversations
+// @Description Page of saved conversations, most recent first
+type ArchiveListResponse struct {
+	Sessions []ArchivedSession `json:"sessions"`
+	Total    int               `json:"total" example:"42"`
+	Limit    int               `json:"limit" example:"20"`
+	Offset   int               `json:"offset" example:"0"`
+} // @name ArchiveListResponse
+
+// ArchiveSummary summarizes a saved conversation
+// @Description Summary of a saved conversation
+type ArchiveSummary struct {
+	DurationSeconds int64    `json:"duration_seconds" example:"1800"`
+	FirstMessage    string   `json:"first_message" example:"How do I write a REST API in Go?"`
+	LastMessage     string   `json:"last_message" example:"Can you add authentication?"`
+	KeyTopics       []string `json:"key_topics" example:"go,api"`
+} // @name ArchiveSummary
+
+// ArchiveSessionResponse represents a saved conversation with its transcript
+// @Description Saved conversation with its summary and full transcript
+type ArchiveSessionResponse struct {
+	ArchivedSession
+	Summary  ArchiveSummary    `json:"summary"`
+	Messages []MessageResponse `json:"messages"`
+} // @name ArchiveSessionResponse
+
+// ArchiveStatsResponse represents history storage statistics
+// @Description History storage statistics
+type ArchiveStatsResponse struct {
+	TotalSessions      int        `json:"total_sessions" example:"42"`
+	CompressedSessions int        `json:"compressed_sessions" example:"42"`
+	TotalSizeBytes     int64      `json:"total_size_bytes" example:"1048576"`
+	OldestSession      *time.Time `json:"oldest_session,omitempty" example:"2023-01-01T12:00:00Z"`
+	NewestSession      *time.Time `json:"newest_session,omitempty" example:"2023-01-31T12:00:00Z"`
+} // @name ArchiveStatsResponse
+
 // ModelInfo represents available model information
 // @Description Information about an available model
 type ModelInfo struct {
-	ID          string `json:"id" example:"gpt-4o-mini"`
-	Name        string `json:"name" example:"GPT-4o-mini"`
-	Description string `json:"description" example:"Fast and efficient model for general conversations"`
-	IsDefault   bool   `json:"is_default" example:"true"`
+	ID            string   `json:"id" example:"gpt-4o-mini"`
+	Name          string   `json:"name" example:"GPT-4o-mini"`
+	Description   string   `json:"description" example:"Fast and efficient model for general conversations"`
+	IsDefault     bool     `json:"is_default" example:"true"`
+	UpstreamID    string   `json:"upstream_id" example:"gpt-4o-mini"`
+	Aliases       []string `json:"aliases,omitempty"`
+	ContextWindow int      `json:"context_window" example:"50000"`
+	Strengths     []string `json:"strengths,omitempty" example:"general,fast"`
 } // @name ModelInfo
 
 // ModelsResponse represents the available models response
@@ -114,6 +265,10 @@ const (
 	ErrorCodeModelNotFound  = "MODEL_NOT_FOUND"
 	ErrorCodeChatError      = "CHAT_ERROR"
 	ErrorCodeInvalidSession = "INVALID_SESSION"
+	ErrorCodeSessionLimit   = "SESSION_LIMIT_REACHED"
+	ErrorCodeUnauthorized   = "UNAUTHORIZED"
+	ErrorCodeSessionBusy    = "SESSION_BUSY"
+	ErrorCodeContextError   = "CONTEXT_ERROR"
 )
 
 // Helper functions for creating standardized responses
@@ -146,10 +301,13 @@ func ConvertChatMessagesToResponse(messages []chat.Message, sessionID string, cu
 	messageResponses := make([]MessageResponse, len(messages))
 	for i, msg := range messages {
 		messageResponses[i] = MessageResponse{
-			ID:        generateMessageID(i),
+			ID:        msg.ID,
 			Role:      msg.Role,
 			Content:   msg.Content,
-			Timestamp: time.Now().Add(-time.Duration(len(messages)-i) * time.Minute), // Estimate timestamps
+			Timestamp: msg.Timestamp,
+			Model:     string(msg.Model),
+			LatencyMs: msg.Latency.Milliseconds(),
+			Source:    msg.Source,
 		}
 	}
 
@@ -161,43 +319,52 @@ func ConvertChatMessagesToResponse(messages []chat.Message, sessionID string, cu
 	}
 }
 
-// generateMessageID generates a unique message ID
-func generateMessageID(index int) string {
-	return fmt.Sprintf("msg_%d_%d", time.Now().UnixNano(), index)
+// lastAnswerID returns the ID of the session's last answer, as listed by the
+// history endpoint
+func lastAnswerID(chatSession *chat.Chat) string {
+	messages := chatSession.MessagesSnapshot()
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "assistant" {
+			return messages[i].ID
+		}
+	}
+	return ""
+}
+
+// providerModels returns the models the session's provider serves: the
+// registry for DuckDuckGo, the model list of the server otherwise
+func providerModels(ctx context.Context, chatSession *chat.Chat) ([]ModelInfo, error) {
+	if chatSession.Provider.Name() == config.ProviderDuckDuckGo {
+		return GetAvailableModels(), nil
+	}
+
+	names, err := chatSession.Provider.Models(ctx)
+	if err != nil {
+		return nil, err
+	}
+	current := string(chatSession.CurrentModel())
+	result := make([]ModelInfo, len(names))
+	for i, name := range names {
+		result[i] = ModelInfo{ID: name, Name: name, UpstreamID: name, IsDefault: name == current}
+	}
+	return result, nil
 }
 
 // GetAvailableModels returns information about all available models
 func GetAvailableModels() []ModelInfo {
-	return []ModelInfo{
-		{
-			ID:          "gpt-4o-mini",
-			Name:        "GPT-4o-mini",
-			Description: "Fast and efficient model for general conversations",
-			IsDefault:   true,
-		},
-		{
-			ID:          "claude-3-haiku",
-			Name:        "Claude-3-haiku",
-			Description: "Anthropic's Claude 3 Haiku model for thoughtful responses",
-			IsDefault:   false,
-		},
-		{
-			ID:          "llama",
-			Name:        "Llama 3.3",
-			Description: "Meta's Llama 3.3 70B model for advanced reasoning",
-			IsDefault:   false,
-		},
-		{
-			ID:          "mixtral",
-			Name:        "Mistral Small 3",
-			Description: "Mistral's efficient small model for quick responses",
-			IsDefault:   false,
-		},
-		{
-			ID:          "o4mini",
-			Name:        "o4-mini",
-			Description: "Compact and efficient model for basic interactions",
-			IsDefault:   false,
-		},
+	offered := models.Offered()
+	result := make([]ModelInfo, len(offered))
+	for i, m := range offered {
+		result[i] = ModelInfo{
+			ID:            string(m.Alias),
+			Name:          m.DisplayName,
+			Description:   m.Description,
+			IsDefault:     m.Default,
+			UpstreamID:    string(m.ID),
+			Aliases:       m.Aliases,
+			ContextWindow: m.ContextWindow,
+			Strengths:     m.Strengths,
+		}
 	}
+	return result
 }
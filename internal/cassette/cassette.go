@@ -0,0 +1,133 @@
+// Package cassette records the exchanges with the DuckDuckGo chat API into a
+// file and replays them from a local stand-in server, so the chat protocol can
+// be exercised without reaching duckduckgo.com.
+package cassette
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Version is the cassette file format version
+const Version = 1
+
+// ChatAPIPrefix is the path of the endpoints that are recorded and replayed
+const ChatAPIPrefix = "/duckchat/v1/"
+
+// Cassette is a recorded sequence of HTTP exchanges
+type Cassette struct {
+	Version      int           `json:"version"`
+	RecordedAt   time.Time     `json:"recorded_at"`
+	Description  string        `json:"description,omitempty"`
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a single request and the response it received
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is a recorded HTTP request
+type Request struct {
+	Method  string      `json:"method"`
+	URL     string      `json:"url"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    Body        `json:"body,omitempty"`
+}
+
+// Response is a recorded HTTP response. For the chat endpoint the body is the
+// complete server-sent events stream.
+type Response struct {
+	Status  int         `json:"status"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    Body        `json:"body,omitempty"`
+}
+
+// Body is a request or response body. It is stored as text when it is valid
+// UTF-8, which keeps the SSE streams readable, and as base64 otherwise.
+type Body []byte
+
+type encodedBody struct {
+	Encoding string `json:"encoding"`
+	Data     string `json:"data"`
+}
+
+func (b Body) MarshalJSON() ([]byte, error) {
+	if utf8.Valid(b) {
+		return json.Marshal(string(b))
+	}
+	return json.Marshal(encodedBody{Encoding: "base64", Data: base64.StdEncoding.EncodeToString(b)})
+}
+
+func (b *Body) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*b = Body(text)
+		return nil
+	}
+
+	var encoded encodedBody
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return err
+	}
+	if encoded.Encoding != "base64" {
+		return fmt.Errorf("unknown body encoding %q", encoded.Encoding)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(encoded.Data)
+	if err != nil {
+		return err
+	}
+	*b = decoded
+	return nil
+}
+
+// Load reads a cassette file
+func Load(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var c Cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("invalid cassette %s: %v", path, err)
+	}
+	if c.Version != Version {
+		return nil, fmt.Errorf("unsupported cassette version %d in %s", c.Version, path)
+	}
+	return &c, nil
+}
+
+// Save writes the cassette to path, replacing the file only once it is
+// completely written
+func (c *Cassette) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// isChatAPI reports whether the request targets the recorded endpoints
+func isChatAPI(req *http.Request) bool {
+	return strings.HasPrefix(req.URL.Path, ChatAPIPrefix)
+}
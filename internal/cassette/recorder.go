@@ -0,0 +1,136 @@
+package cassette
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Recorder is an http.RoundTripper that passes requests on to the network and
+// appends the chat API exchanges to a cassette file. Streamed responses are
+// forwarded as they arrive and written to the cassette once fully read.
+type Recorder struct {
+	path      string
+	transport http.RoundTripper
+
+	mu       sync.Mutex
+	cassette *Cassette
+	err      error
+}
+
+// NewRecorder returns a Recorder writing to path. Requests are sent with
+// transport, or http.DefaultTransport when it is nil.
+func NewRecorder(path string, transport http.RoundTripper) *Recorder {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return &Recorder{
+		path:      path,
+		transport: transport,
+		cassette: &Cassette{
+			Version:      Version,
+			RecordedAt:   time.Now().UTC(),
+			Interactions: []Interaction{},
+		},
+	}
+}
+
+// Err returns the last error met while writing the cassette
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// Len returns the number of recorded interactions
+func (r *Recorder) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.cassette.Interactions)
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !isChatAPI(req) {
+		return r.transport.RoundTrip(req)
+	}
+
+	var reqBody []byte
+	if req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		reqBody = body
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	resp, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	interaction := Interaction{
+		Request: Request{
+			Method:  req.Method,
+			URL:     req.URL.String(),
+			Headers: req.Header.Clone(),
+			Body:    reqBody,
+		},
+		Response: Response{
+			Status:  resp.StatusCode,
+			Headers: resp.Header.Clone(),
+		},
+	}
+	resp.Body = &recordingBody{
+		body: resp.Body,
+		done: func(body []byte) {
+			interaction.Response.Body = body
+			r.add(interaction)
+		},
+	}
+	return resp, nil
+}
+
+// add appends an interaction and saves the cassette, so a crash or an
+// interrupt loses at most the exchange in flight
+func (r *Recorder) add(interaction Interaction) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+	if err := r.cassette.Save(r.path); err != nil {
+		r.err = err
+	}
+}
+
+// recordingBody keeps a copy of what is read and hands it over when the body
+// reaches EOF or is closed, whichever comes first
+type recordingBody struct {
+	body io.ReadCloser
+	buf  bytes.Buffer
+	once sync.Once
+	done func([]byte)
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	b.buf.Write(p[:n])
+	if err == io.EOF {
+		b.finish()
+	}
+	return n, err
+}
+
+func (b *recordingBody) Close() error {
+	b.finish()
+	return b.body.Close()
+}
+
+func (b *recordingBody) finish() {
+	b.once.Do(func() {
+		b.done(bytes.Clone(b.buf.Bytes()))
+	})
+}
@@ -0,0 +1,137 @@
+package cassette
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// Server is a local stand-in for the DuckDuckGo chat API that answers with the
+// responses of a cassette. Each endpoint serves its recorded responses in
+// order, then keeps repeating the last one, so a retry scenario plays out the
+// same way on every run and a short cassette still supports a longer session.
+type Server struct {
+	URL string
+
+	server   *http.Server
+	listener net.Listener
+
+	mu     sync.Mutex
+	queues map[string][]Response
+	served map[string]int
+}
+
+// NewServer starts a Server for the cassette on a random local port
+func NewServer(c *Cassette) (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("cannot start replay server: %v", err)
+	}
+
+	s := &Server{
+		URL:      "http://" + listener.Addr().String(),
+		listener: listener,
+		queues:   make(map[string][]Response),
+		served:   make(map[string]int),
+	}
+	for _, interaction := range c.Interactions {
+		key, err := routeKey(interaction.Request.Method, interaction.Request.URL)
+		if err != nil {
+			listener.Close()
+			return nil, err
+		}
+		s.queues[key] = append(s.queues[key], interaction.Response)
+	}
+
+	s.server = &http.Server{Handler: s}
+	go s.server.Serve(listener)
+	return s, nil
+}
+
+// Close stops the server
+func (s *Server) Close() error {
+	return s.server.Shutdown(context.Background())
+}
+
+// Transport returns an http.RoundTripper that sends the chat API requests to
+// the server and every other request to transport, or http.DefaultTransport
+// when it is nil
+func (s *Server) Transport(transport http.RoundTripper) http.RoundTripper {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	target, _ := url.Parse(s.URL)
+	return &replayTransport{target: target, transport: transport}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := r.Method + " " + r.URL.Path
+
+	s.mu.Lock()
+	queue := s.queues[key]
+	if len(queue) == 0 {
+		s.mu.Unlock()
+		http.Error(w, fmt.Sprintf("no recorded response for %s", key), http.StatusNotFound)
+		return
+	}
+	i := s.served[key]
+	if i >= len(queue) {
+		i = len(queue) - 1
+	}
+	s.served[key]++
+	response := queue[i]
+	s.mu.Unlock()
+
+	for name, values := range response.Headers {
+		// The body is replayed as recorded, so the original framing no longer applies
+		if name == "Content-Length" || name == "Transfer-Encoding" {
+			continue
+		}
+		for _, value := range values {
+			w.Header().Add(name, value)
+		}
+	}
+	w.WriteHeader(response.Status)
+
+	// Send the stream one event at a time, like the real server
+	flusher, _ := w.(http.Flusher)
+	for _, event := range bytes.SplitAfter(response.Body, []byte("\n\n")) {
+		if _, err := w.Write(event); err != nil {
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
+
+// routeKey identifies the endpoint a recorded request was sent to
+func routeKey(method, rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid recorded URL %q: %v", rawURL, err)
+	}
+	return method + " " + u.Path, nil
+}
+
+// replayTransport redirects the chat API requests to the replay server
+type replayTransport struct {
+	target    *url.URL
+	transport http.RoundTripper
+}
+
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !isChatAPI(req) {
+		return t.transport.RoundTrip(req)
+	}
+
+	redirected := req.Clone(req.Context())
+	redirected.URL.Scheme = t.target.Scheme
+	redirected.URL.Host = t.target.Host
+	redirected.Host = t.target.Host
+	return t.transport.RoundTrip(redirected)
+}
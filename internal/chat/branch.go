@@ -0,0 +1,381 @@
+package chat
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"duckduckgo-chat-cli/internal/intelligence"
+	"duckduckgo-chat-cli/internal/models"
+	"duckduckgo-chat-cli/internal/persistence"
+	"duckduckgo-chat-cli/internal/ui"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/fatih/color"
+)
+
+// Branch is one line of the conversation tree. /retry and /edit fork a new
+// branch that shares the first ForkAt messages of its parent and continues
+// differently, so the original thread is never lost. The active branch's
+// messages live in Chat.Messages; its Messages copy is only refreshed when the
+// tree is read or another branch becomes active.
+type Branch struct {
+	ID       int
+	Name     string
+	Parent   int // 0 for the root branch
+	ForkAt   int // number of messages shared with the parent
+	Created  time.Time
+	Messages []Message
+}
+
+// Label returns the branch's name, or its number if it has none
+func (b Branch) Label() string {
+	if b.Name != "" {
+		return fmt.Sprintf("#%d %s", b.ID, b.Name)
+	}
+	return fmt.Sprintf("#%d", b.ID)
+}
+
+// ensureRootBranch records the conversation so far as the root branch. The
+// caller must hold c.mu.
+func (c *Chat) ensureRootBranch() {
+	if len(c.branches) == 0 {
+		c.branches = []*Branch{{ID: 1, Name: "main", Created: c.Analytics.SessionStartTime}}
+		c.activeBranch = 1
+	}
+}
+
+// branch returns the branch with the given ID. The caller must hold c.mu.
+func (c *Chat) branch(id int) *Branch {
+	for _, b := range c.branches {
+		if b.ID == id {
+			return b
+		}
+	}
+	return nil
+}
+
+// forkBranch makes a new branch, sharing the first at messages of the active
+// one, the active branch. The returned function abandons the new branch and
+// goes back to the previous one, for when the turn that forked fails.
+func (c *Chat) forkBranch(at int) (abandon func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ensureRootBranch()
+	parent := c.branch(c.activeBranch)
+	parent.Messages = append([]Message(nil), c.Messages...)
+
+	forked := &Branch{
+		ID:      c.branches[len(c.branches)-1].ID + 1,
+		Parent:  parent.ID,
+		ForkAt:  at,
+		Created: time.Now(),
+	}
+	c.branches = append(c.branches, forked)
+	c.activeBranch = forked.ID
+	ui.Mutedln("🌿 Started branch %s (the previous answer stays on %s, see /branch)", forked.Label(), parent.Label())
+
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		c.branches = c.branches[:len(c.branches)-1]
+		c.activeBranch = parent.ID
+		c.Messages = parent.Messages
+		c.journalMessages()
+	}
+}
+
+// Branches returns a copy of the conversation tree and the ID of the active
+// branch. A conversation that never forked is a single root branch.
+func (c *Chat) Branches() ([]Branch, int) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.branchTree()
+}
+
+// branchTree builds the copy returned by Branches. The caller must hold c.mu.
+func (c *Chat) branchTree() ([]Branch, int) {
+	if len(c.branches) == 0 {
+		root := Branch{ID: 1, Name: "main", Created: c.Analytics.SessionStartTime, Messages: append([]Message(nil), c.Messages...)}
+		return []Branch{root}, 1
+	}
+
+	branches := make([]Branch, len(c.branches))
+	for i, b := range c.branches {
+		branches[i] = *b
+		if b.ID == c.activeBranch {
+			branches[i].Messages = c.Messages
+		}
+		branches[i].Messages = append([]Message(nil), branches[i].Messages...)
+	}
+	return branches, c.activeBranch
+}
+
+// SwitchBranch makes another branch the active one
+func (c *Chat) SwitchBranch(id int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	target := c.branch(id)
+	if target == nil {
+		return fmt.Errorf("no branch #%d", id)
+	}
+	if id == c.activeBranch {
+		return nil
+	}
+
+	c.branch(c.activeBranch).Messages = append([]Message(nil), c.Messages...)
+	c.activeBranch = id
+	c.Messages = append([]Message(nil), target.Messages...)
+	c.journalMessages()
+	return nil
+}
+
+// NameBranch names the active branch
+func (c *Chat) NameBranch(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ensureRootBranch()
+	for _, b := range c.branches {
+		if b.Name == name && b.ID != c.activeBranch {
+			return fmt.Errorf("branch %s already has this name", b.Label())
+		}
+	}
+	c.branch(c.activeBranch).Name = name
+	c.journalMessages()
+	return nil
+}
+
+// resetBranches forgets the conversation tree. The caller must hold c.mu.
+func (c *Chat) resetBranches() {
+	c.branches = nil
+	c.activeBranch = 0
+}
+
+// sessionBranches converts the tree for persistence. A conversation that
+// never forked has no branches to save. The caller must hold c.mu.
+func (c *Chat) sessionBranches() ([]persistence.SessionBranch, int) {
+	if len(c.branches) == 0 {
+		return nil, 0
+	}
+
+	branches, active := c.branchTree()
+	saved := make([]persistence.SessionBranch, len(branches))
+	for i, b := range branches {
+		saved[i] = persistence.SessionBranch{
+			ID:       b.ID,
+			Name:     b.Name,
+			Parent:   b.Parent,
+			ForkAt:   b.ForkAt,
+			Created:  b.Created,
+			Messages: toIntelligenceMessages(b.Messages),
+		}
+	}
+	return saved, active
+}
+
+// restoreBranches rebuilds the tree of a saved session. The caller must hold
+// c.mu and set c.Messages to the active branch.
+func (c *Chat) restoreBranches(session *persistence.ConversationSession) {
+	c.resetBranches()
+	if len(session.Branches) == 0 {
+		return
+	}
+
+	for _, b := range session.Branches {
+		c.branches = append(c.branches, &Branch{
+			ID:       b.ID,
+			Name:     b.Name,
+			Parent:   b.Parent,
+			ForkAt:   b.ForkAt,
+			Created:  b.Created,
+			Messages: fromIntelligenceMessages(b.Messages),
+		})
+	}
+	c.activeBranch = session.ActiveBranch
+	if c.branch(c.activeBranch) == nil {
+		c.activeBranch = c.branches[0].ID
+	}
+}
+
+// HandleBranchCommand processes the /branch command
+func HandleBranchCommand(c *Chat, args string) {
+	subcommand, rest, _ := strings.Cut(strings.TrimSpace(args), " ")
+	rest = strings.TrimSpace(rest)
+
+	switch subcommand {
+	case "", "list":
+		printBranches(c)
+	case "switch":
+		handleBranchSwitch(c, rest)
+	case "name":
+		if rest == "" {
+			ui.Errorln("Usage: /branch name <name>")
+			return
+		}
+		if err := c.NameBranch(rest); err != nil {
+			ui.Errorln("%v", err)
+			return
+		}
+		ui.AIln("Branch named %q", rest)
+	default:
+		ui.Errorln("Unknown subcommand: %s. Use /branch list|switch|name", subcommand)
+	}
+}
+
+func handleBranchSwitch(c *Chat, ref string) {
+	branches, active := c.Branches()
+	if len(branches) == 1 {
+		ui.Warningln("This conversation has a single branch. /retry and /edit create new ones.")
+		return
+	}
+
+	if ref == "" {
+		options := make([]string, len(branches))
+		for i, b := range branches {
+			options[i] = fmt.Sprintf("%s (%d messages)", b.Label(), len(b.Messages))
+		}
+		var choice int
+		prompt := &survey.Select{Message: "Switch to branch:", Options: options}
+		if err := survey.AskOne(prompt, &choice, survey.WithStdio(os.Stdin, os.Stdout, os.Stderr)); err != nil {
+			ui.Warningln("Branch switch canceled")
+			return
+		}
+		ref = strconv.Itoa(branches[choice].ID)
+	}
+
+	target := findBranch(branches, ref)
+	if target == nil {
+		ui.Errorln("No branch %q. Use /branch list", ref)
+		return
+	}
+	if target.ID == active {
+		ui.Warningln("Already on branch %s", target.Label())
+		return
+	}
+	if err := c.SwitchBranch(target.ID); err != nil {
+		ui.Errorln("%v", err)
+		return
+	}
+	ui.AIln("Switched to branch %s (%d messages)", target.Label(), len(target.Messages))
+}
+
+// findBranch finds a branch by number, with or without #, or by name
+func findBranch(branches []Branch, ref string) *Branch {
+	id, err := strconv.Atoi(strings.TrimPrefix(ref, "#"))
+	for i, b := range branches {
+		if (err == nil && b.ID == id) || (b.Name != "" && b.Name == ref) {
+			return &branches[i]
+		}
+	}
+	return nil
+}
+
+func printBranches(c *Chat) {
+	branches, active := c.Branches()
+	fmt.Println()
+	walkBranches(branches, func(b Branch, depth int) {
+		marker := "  "
+		if b.ID == active {
+			marker = "* "
+		}
+		line := fmt.Sprintf("%s%s%s", marker, strings.Repeat("  ", depth), b.Label())
+		details := fmt.Sprintf("%d messages", len(b.Messages))
+		if b.Parent != 0 {
+			details += fmt.Sprintf(", forked after message %d", b.ForkAt)
+		}
+		if preview := lastUserPreview(b.Messages); preview != "" {
+			details += fmt.Sprintf(" — %q", preview)
+		}
+		if b.ID == active {
+			color.New(color.FgHiGreen, color.Bold).Print(line)
+		} else {
+			ui.Whitef("%s", line)
+		}
+		ui.Mutedln("  %s", details)
+	})
+	fmt.Println()
+}
+
+// walkBranches visits the branches depth first, children in creation order
+func walkBranches(branches []Branch, visit func(b Branch, depth int)) {
+	children := make(map[int][]Branch)
+	for _, b := range branches {
+		children[b.Parent] = append(children[b.Parent], b)
+	}
+	for _, list := range children {
+		sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	}
+
+	var walk func(parent, depth int)
+	walk = func(parent, depth int) {
+		for _, b := range children[parent] {
+			visit(b, depth)
+			walk(b.ID, depth+1)
+		}
+	}
+	walk(0, 0)
+}
+
+// ownMessages returns the messages of a branch that are not shared with its
+// parent, and the index of the first one
+func ownMessages(b Branch) ([]Message, int) {
+	from := min(b.ForkAt, len(b.Messages))
+	if b.Parent == 0 {
+		from = 0
+	}
+	return b.Messages[from:], from
+}
+
+func lastUserPreview(messages []Message) string {
+	turns := userTurns(messages)
+	if len(turns) == 0 {
+		return ""
+	}
+	preview := strings.Join(strings.Fields(messages[turns[len(turns)-1]].Content), " ")
+	if len([]rune(preview)) > 50 {
+		preview = string([]rune(preview)[:50]) + "…"
+	}
+	return preview
+}
+
+func toIntelligenceMessages(messages []Message) []intelligence.Message {
+	result := make([]intelligence.Message, len(messages))
+	for i, msg := range messages {
+		result[i] = intelligence.Message{
+			Content:   msg.Content,
+			Role:      msg.Role,
+			Timestamp: msg.Timestamp,
+			ID:        msg.ID,
+			Model:     string(msg.Model),
+			Latency:   msg.Latency,
+			Source:    msg.Source,
+		}
+	}
+	return result
+}
+
+// fromIntelligenceMessages converts messages back, stamping those of sessions
+// saved before messages had an ID
+func fromIntelligenceMessages(messages []intelligence.Message) []Message {
+	result := make([]Message, len(messages))
+	for i, msg := range messages {
+		result[i] = Message{
+			Content:   msg.Content,
+			Role:      msg.Role,
+			ID:        msg.ID,
+			Timestamp: msg.Timestamp,
+			Model:     models.Model(msg.Model),
+			Latency:   msg.Latency,
+			Source:    msg.Source,
+		}
+		stampMessage(&result[i])
+	}
+	return result
+}
@@ -1,12 +1,11 @@
 package chat
 
 import (
-	"bufio"
-	"bytes"
-	"encoding/json"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"io"
-	"log"
 	"net/http"
 	"net/http/cookiejar"
 	"net/url"
@@ -15,6 +14,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
 
 	"duckduckgo-chat-cli/internal/analytics"
@@ -29,8 +29,13 @@ import (
 
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/fatih/color"
+	"golang.org/x/term"
 )
 
+// Transport carries the requests sent to DuckDuckGo. Record and replay modes
+// replace it before the first session is created.
+var Transport http.RoundTripper = http.DefaultTransport
+
 type Chat struct {
 	OldVqd     string
 	NewVqd     string
@@ -43,17 +48,104 @@ type Chat struct {
 	CookieJar  *cookiejar.Jar
 	LastHash   string
 	RetryCount int
+	Provider   Provider
 
 	// New intelligent features
 	Analytics        *analytics.ChatAnalytics
 	ContextOptimizer *intelligence.ContextOptimizer
 	HistoryManager   *persistence.HistoryManager
 	SessionID        string
+
+	// journal records the history on disk as it changes, so the conversation
+	// survives a crash (see StartJournal). Nil when not journaling.
+	journal *persistence.Journal
+
+	// mu guards the conversation state against readers outside the current
+	// turn. Writers must also hold the turn (see Acquire).
+	mu    sync.RWMutex
+	queue *turnQueue
+
+	// unsentVQD is the VQD acquired for the session until it is sent, so that
+	// it can go back to the shared cache if the session drops it unused
+	unsentVQD vqdHeaders
+
+	// Conversation tree, empty until the conversation forks (see Branch)
+	branches     []*Branch
+	activeBranch int
+}
+
+// SessionState is a consistent snapshot of a Chat's state
+type SessionState struct {
+	SessionID    string
+	Model        models.Model
+	MessageCount int
+	NewVqd       string
+	RetryCount   int
+	QueueDepth   int
 }
 
+// Message is one message of the conversation. Only Content and Role are sent
+// to the provider; the other fields are local metadata, kept in saved
+// sessions and shown by /history, the exports and the API.
 type Message struct {
 	Content string `json:"content"`
 	Role    string `json:"role"`
+
+	ID        string        `json:"-"`
+	Timestamp time.Time     `json:"-"`
+	Model     models.Model  `json:"-"` // model that wrote an assistant message
+	Latency   time.Duration `json:"-"` // time taken by an assistant message
+	Source    string        `json:"-"` // how a user message was added, one of the Source constants
+}
+
+// Sources of user messages
+const (
+	SourceTyped   = "typed"
+	SourceFile    = "file"
+	SourceURL     = "url"
+	SourceSearch  = "search"
+	SourceLibrary = "library"
+	SourcePMP     = "pmp"
+	SourceChain   = "chain"
+)
+
+// newMessageID returns a random identifier that stays with a message when it
+// is saved, restored or moved to another branch
+func newMessageID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("msg_%x", time.Now().UnixNano())
+	}
+	return "msg_" + hex.EncodeToString(b)
+}
+
+// stampMessage fills in the ID and timestamp of a new message, and the source
+// of a user message added without one
+func stampMessage(msg *Message) {
+	if msg.ID == "" {
+		msg.ID = newMessageID()
+	}
+	if msg.Timestamp.IsZero() {
+		msg.Timestamp = time.Now()
+	}
+	if msg.Role == "user" && msg.Source == "" {
+		msg.Source = guessSource(msg.Content)
+	}
+}
+
+// guessSource tells the source of a user message from its context header
+func guessSource(content string) string {
+	switch {
+	case strings.HasPrefix(content, chatcontext.FileHeader):
+		return SourceFile
+	case strings.HasPrefix(content, chatcontext.URLHeader):
+		return SourceURL
+	case strings.HasPrefix(content, chatcontext.SearchHeader):
+		return SourceSearch
+	case strings.HasPrefix(content, chatcontext.ProjectHeader):
+		return SourcePMP
+	}
+	return SourceTyped
 }
 
 type ToolChoice struct {
@@ -76,15 +168,32 @@ type ChatPayload struct {
 }
 
 func InitializeSession(cfg *config.Config) *Chat {
-	model := models.GetModel(cfg.DefaultModel)
-	vqd, vqdHash1, feSignals, feVersion := GetVQD()
-	chat := NewChat(vqd, vqdHash1, feSignals, feVersion, model, cfg)
-	ui.AIln("Chat initialized with model: %s", model)
-	setTerminalTitle(fmt.Sprintf("DuckDuckGo Chat - %s", model))
+	chat := NewSession(cfg)
+	ui.AIln("Chat initialized with model: %s", chat.Model)
+	setTerminalTitle(fmt.Sprintf("DuckDuckGo Chat - %s", chat.Model))
+	return chat
+}
+
+// NewSession creates a chat session with the configured default model
+// without touching the terminal, for the headless modes.
+func NewSession(cfg *config.Config) *Chat {
+	chat := NewChat("", "", "", "", ConfiguredModel(cfg), cfg)
+	if cfg.Provider.Type == config.ProviderOpenAI {
+		return chat
+	}
+	if err := chat.refreshVQD(); err != nil {
+		ui.Errorln("%v", err)
+	} else {
+		ui.AIln("✅ Successfully got VQD and all required headers")
+	}
 	return chat
 }
 
 func setTerminalTitle(title string) {
+	// Escape sequences would end up in logs when the output is redirected
+	if !term.IsTerminal(int(os.Stdout.Fd())) {
+		return
+	}
 	switch runtime.GOOS {
 	case "windows":
 		exec.Command("cmd", "/c", fmt.Sprintf("title %s", title)).Run()
@@ -94,6 +203,23 @@ func setTerminalTitle(title string) {
 }
 
 func NewChat(vqd, vqdHash1, feSignals, feVersion string, model models.Model, cfg *config.Config) *Chat {
+	chat := newChat(vqd, vqdHash1, feSignals, feVersion, model, cfg)
+	if chat.Provider.Name() == config.ProviderDuckDuckGo {
+		// Use all headers like the real web browser
+		ui.AIln("🔍 Using VQD with all required headers like web browser")
+	}
+	ui.AIln("🧠 Intelligent features enabled: Analytics, Context Optimization, History Management")
+	return chat
+}
+
+// NewAPIChat creates a chat for a session opened through the API. Unlike
+// NewChat it prints nothing, so API clients do not clutter the terminal the
+// REPL may be running in.
+func NewAPIChat(model models.Model, cfg *config.Config) *Chat {
+	return newChat("", "", "", "", model, cfg)
+}
+
+func newChat(vqd, vqdHash1, feSignals, feVersion string, model models.Model, cfg *config.Config) *Chat {
 	jar, _ := cookiejar.New(nil)
 
 	// Set required cookies avec les cookies minimum nécessaires
@@ -111,10 +237,14 @@ func NewChat(vqd, vqdHash1, feSignals, feVersion string, model models.Model, cfg
 	// Initialize intelligent features
 	analytics := analytics.NewChatAnalytics()
 	contextOptimizer := intelligence.NewContextOptimizer()
+	contextOptimizer.MaxContextSize = models.ContextWindow(model)
 	historyManager := persistence.NewHistoryManager(cfg.ExportDir)
 
-	// Use all headers like the real web browser
-	ui.AIln("🔍 Using VQD with all required headers like web browser")
+	provider, err := NewProvider(cfg)
+	if err != nil {
+		ui.Warningln("Provider error: %v. Falling back to %s.", err, config.ProviderDuckDuckGo)
+		provider = newDuckDuckGoProvider(cfg.Network)
+	}
 
 	chat := &Chat{
 		OldVqd:     vqd,       // x-vqd-4 value
@@ -125,89 +255,25 @@ func NewChat(vqd, vqdHash1, feSignals, feVersion string, model models.Model, cfg
 		Model:      model,
 		Messages:   []Message{},
 		CookieJar:  jar,
-		Client:     &http.Client{Timeout: 30 * time.Second, Jar: jar},
+		Client:     newUpstream(cfg.Network).client(jar),
 		RetryCount: 0,
+		Provider:   provider,
 
 		// Initialize new intelligent features
 		Analytics:        analytics,
 		ContextOptimizer: contextOptimizer,
 		HistoryManager:   historyManager,
 		SessionID:        sessionID,
+
+		queue: newTurnQueue(*cfg.API.MaxQueuedRequests),
 	}
 
 	// Record initial model
 	analytics.RecordModelChange(string(model))
 
-	ui.AIln("🧠 Intelligent features enabled: Analytics, Context Optimization, History Management")
-
 	return chat
 }
 
-func GetVQD() (string, string, string, string) {
-	// Simple approach like the working PowerShell script
-	// Use static headers that work, no complex challenges
-	ui.Warningln("⌛ Getting VQD from status API (simple approach like working PS1 script)...")
-
-	client := &http.Client{Timeout: 10 * time.Second}
-
-	// Set up cookies avec les cookies minimum nécessaires (comme dans le script PS1)
-	jar, _ := cookiejar.New(nil)
-	u, _ := url.Parse("https://duckduckgo.com")
-	cookies := []*http.Cookie{
-		{Name: "5", Value: "1", Domain: ".duckduckgo.com"},
-		{Name: "dcm", Value: "3", Domain: ".duckduckgo.com"},
-		{Name: "dcs", Value: "1", Domain: ".duckduckgo.com"},
-	}
-	jar.SetCookies(u, cookies)
-	client.Jar = jar
-
-	// Direct GET to /status with exact headers from working PS1 script
-	req, _ := http.NewRequest("GET", models.StatusURL, nil)
-	req.Header.Set("Accept", "*/*")
-	req.Header.Set("Accept-Encoding", "gzip, deflate, br, zstd")
-	req.Header.Set("Accept-Language", "fr-FR,fr;q=0.6")
-	req.Header.Set("Authority", "duckduckgo.com")
-	req.Header.Set("Cache-Control", "no-store")
-	req.Header.Set("DNT", "1")
-	req.Header.Set("Method", "GET")
-	req.Header.Set("Path", "/duckchat/v1/status")
-	req.Header.Set("Priority", "u=1, i")
-	req.Header.Set("Referer", "https://duckduckgo.com/")
-	req.Header.Set("Scheme", "https")
-	req.Header.Set("Sec-CH-UA", `"Not)A;Brand";v="8", "Chromium";v="138", "Brave";v="138"`)
-	req.Header.Set("Sec-CH-UA-Mobile", "?0")
-	req.Header.Set("Sec-CH-UA-Platform", `"Windows"`)
-	req.Header.Set("Sec-Fetch-Dest", "empty")
-	req.Header.Set("Sec-Fetch-Mode", "cors")
-	req.Header.Set("Sec-Fetch-Site", "same-origin")
-	req.Header.Set("Sec-GPC", "1")
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/138.0.0.0 Safari/537.36")
-	req.Header.Set("x-vqd-accept", "1")
-
-	resp, err := client.Do(req)
-	if err != nil {
-		ui.Errorln("Error fetching VQD: %v", err)
-		return "", "", "", ""
-	}
-	defer resp.Body.Close()
-
-	// Le VQD header de la status API pour x-vqd-4
-	vqdHeader := resp.Header.Get("x-vqd-hash-1")
-	if vqdHeader == "" {
-		ui.Errorln("No VQD header found in response")
-		return "", "", "", ""
-	}
-
-	// Return the VQD and static headers that work in PowerShell script
-	vqd := vqdHeader
-	vqdHash1 := "eyJzZXJ2ZXJfaGFzaGVzIjpbImRQSlJJTWczZnFYQXIvaStaa3c2cEpFVzEwckdTdmxJVlVkNlFsOVRGWXc9IiwiMUN3Qzg3N0Q3WXE1dzlEeTc4UjhBVi9qZVZWaUlYbmV0Q0xvckx3c01QZz0iLCJQSzc3TGc2L25weDdWQ2J2UWxsTEhBR3cyenJIVmEvQUFBRFBhQTl1ekVRPSJdLCJjbGllbnRfaGFzaGVzIjpbImxWblI0MStCMVFWZ0o4d0hhMUdBNmdxR0JoSjlWdjN5K0dISkdGekJmTGM9IiwiVS9RRUc2RE1qdEU4V2hHU1FxOUU1Z0VGNmw1SWJrNk9NVlBuY01DU1licz0iLCJ6SURsYUNvZG9JUjNwbTNSVTlWOUJXaUJkZDJqenRMODAyN0VYTHhkWll3PSJdLCJzaWduYWxzIjp7fSwibWV0YSI6eyJ2IjoiNCIsImNoYWxsZW5nZV9pZCI6ImM4M2Q0ZTc5NTU2MjJmZjU3Mzc0ZDUzOTk2ZjliMmJhZGE2ZDQxZTMzNDM1ZjVlNzMyYjFmNmZjNmQ0ZTE1NzVoOGpidCIsInRpbWVzdGFtcCI6IjE3NTIxNTU3Nzc4NjYiLCJvcmlnaW4iOiJodHRwczovL2R1Y2tkdWNrZ28uY29tIiwic3RhY2siOiJFcnJvclxuYXQgRSAoaHR0cHM6Ly9kdWNrZHVja2dvLmNvbS9kaXN0L3dwbS5jaGF0LjcwZWFjYTZhZWEyOTQ4YjBiYjYwLmpzOjE6MTQ4MjUpXG5hdCBhc3luYyBodHRwczovL2R1Y2tkdWNrZ28uY29tL2Rpc3Qvd3BtLmNoYXQuNzBlYWNhNmFlYTI5NDhiMGJiNjAuanM6MToxNjk4NSIsImR1cmF0aW9uIjoiNTgifX0="
-	feSignals := "eyJzdGFydCI6MTc1MjE1NTc3NzQ4MCwiZXZlbnRzIjpbeyJuYW1lIjoic3RhcnROZXdDaGF0IiwiZGVsdGEiOjc1fSx7Im5hbWUiOiJyZWNlbnRDaGF0c0xpc3RJbXByZXNzaW9uIiwiZGVsdGEiOjEyNH1dLCJlbmQiOjQzNDN9"
-	feVersion := "serp_20250710_090702_ET-70eaca6aea2948b0bb60"
-
-	ui.AIln("✅ Successfully got VQD and all required headers")
-	return vqd, vqdHash1, feSignals, feVersion
-}
-
 func (c *Chat) Clear(cfg *config.Config) {
 	// Save current session before clearing if it has content
 	if len(c.Messages) > 0 {
@@ -217,18 +283,17 @@ func (c *Chat) Clear(cfg *config.Config) {
 	clearTerminal()
 
 	if len(c.Messages) > 0 {
+		c.Provider.Reset(c)
+
+		c.mu.Lock()
 		c.Messages = []Message{}
-		newVqd, newVqdHash1, newFeSignals, newFeVersion := GetVQD()
-		c.NewVqd = newVqd
-		c.OldVqd = c.NewVqd
-		c.VqdHash1 = newVqdHash1
-		c.FeSignals = newFeSignals
-		c.FeVersion = newFeVersion
-		// Hash will be refreshed on next request if needed
+		c.resetBranches()
 		c.RetryCount = 0
 
 		// Generate new session ID for the fresh start
 		c.SessionID = fmt.Sprintf("session_%d", time.Now().UnixNano())
+		c.restartJournal()
+		c.mu.Unlock()
 
 		ui.AIln("Chat history and context cleared")
 	} else {
@@ -254,7 +319,34 @@ func clearTerminal() {
 	cmd.Run()
 }
 
-func ProcessInput(c *Chat, input string, cfg *config.Config) {
+// TruncatedNote is appended to answers cut short by a cancellation, so that
+// both the reader and the model can tell the answer is incomplete
+const TruncatedNote = "\n\n[answer interrupted]"
+
+// IncompleteNote is appended to answers cut short upstream, by an error or by
+// the output limit
+const IncompleteNote = "\n\n[answer incomplete]"
+
+// keptAnswer returns a streamed answer as it is kept in the history, marked
+// with TruncatedNote if ctx was cancelled or IncompleteNote if it was cut
+// short upstream. It is empty when nothing was received.
+func keptAnswer(ctx context.Context, result *StreamResult) string {
+	content := result.Content()
+	switch {
+	case content == "":
+		return ""
+	case ctx.Err() != nil:
+		return content + TruncatedNote
+	case result.Truncated():
+		return content + IncompleteNote
+	}
+	return content
+}
+
+// ProcessInput sends the input and renders the answer. Cancelling ctx aborts
+// the request: the partial answer is kept with TruncatedNote, or the input is
+// dropped from the history if nothing was received yet.
+func ProcessInput(ctx context.Context, c *Chat, input string, cfg *config.Config) {
 	if strings.TrimSpace(input) == "" {
 		return
 	}
@@ -268,7 +360,7 @@ func ProcessInput(c *Chat, input string, cfg *config.Config) {
 		actualMessage = cfg.GlobalPrompt + "\n\n" + input
 	}
 
-	c.Messages = append(c.Messages, Message{
+	c.appendMessage(Message{
 		Role:    "user",
 		Content: actualMessage,
 	})
@@ -276,78 +368,84 @@ func ProcessInput(c *Chat, input string, cfg *config.Config) {
 	// Check if context optimization is needed
 	if c.ContextOptimizer.IsOptimizationNeeded(c.convertMessagesToIntelligence()) {
 		optimizedMessages, bytesSaved := c.ContextOptimizer.OptimizeContext(c.convertMessagesToIntelligence())
-		c.Messages = c.convertFromIntelligenceMessages(optimizedMessages)
+		c.setMessages(c.convertFromIntelligenceMessages(optimizedMessages))
 		c.Analytics.RecordContextOptimization(bytesSaved)
 	}
 
-	// Track chat interaction timing
+	if !c.answer(ctx) && ctx.Err() != nil {
+		c.dropLastUserMessage()
+	}
+}
+
+// answer sends the conversation, which ends with the user's turn, renders the
+// answer and appends it to the history. It reports whether an answer was
+// added; a canceled answer is kept with TruncatedNote and an answer cut short
+// upstream with IncompleteNote, if anything was received.
+func (c *Chat) answer(ctx context.Context) bool {
+	// Chat interaction timing is tracked by FetchStream
 	startTime := time.Now()
-	stream, err := c.FetchStream(actualMessage)
+	stream, err := c.FetchStream(ctx, c.Messages[len(c.Messages)-1].Content)
 	if err != nil {
-		c.Analytics.RecordChatInteraction(time.Since(startTime), false, "unknown")
+		if ctx.Err() != nil {
+			ui.Warningln("Request canceled")
+			return false
+		}
 		ui.Errorln("Error: %v", err)
-		return
+		return false
 	}
 
 	// Use the new stable streaming renderer
 	modelName := shortenModelName(string(c.Model))
-	finalResponse := RenderStream(stream, modelName)
+	result := RenderStream(stream, modelName)
+	finalResponse := keptAnswer(ctx, result)
 
-	// Track successful chat interaction
-	c.Analytics.RecordChatInteraction(time.Since(startTime), true, "")
+	if ctx.Err() != nil {
+		if finalResponse == "" {
+			ui.Warningln("Request canceled")
+			return false
+		}
+		ui.Warningln("Answer interrupted")
+	}
+	if finalResponse == "" {
+		// The renderer has shown the upstream error
+		return false
+	}
 
 	// Track assistant message
 	c.Analytics.RecordMessage("assistant", len(finalResponse))
 
-	c.Messages = append(c.Messages, Message{
+	c.appendMessage(Message{
 		Role:    "assistant",
 		Content: finalResponse,
+		Model:   c.CurrentModel(),
+		Latency: time.Since(startTime),
 	})
+	return true
 }
 
-// renderStreamToString captures the stream output into a single string.
-func renderStreamToString(stream <-chan string) string {
-	var fullResponse strings.Builder
-	var currentLine strings.Builder
-	inCodeBlock := false
-	var codeBlockLang string
-
-	for chunk := range stream {
-		for _, char := range chunk {
-			currentLine.WriteRune(char)
-			// This is a simplified version of RenderStream.
-			// It doesn't handle complex ANSI and formatting, just captures the text.
-			if char == '\n' {
-				lineStr := currentLine.String()
-				if strings.HasPrefix(lineStr, "```") {
-					if !inCodeBlock {
-						inCodeBlock = true
-						codeBlockLang = strings.TrimSpace(strings.TrimPrefix(lineStr, "```"))
-						fullResponse.WriteString("```" + codeBlockLang + "\n")
-					} else {
-						inCodeBlock = false
-						fullResponse.WriteString("```\n")
-					}
-				} else {
-					fullResponse.WriteString(lineStr)
-				}
-				currentLine.Reset()
-			}
-		}
+func ProcessInputAndReturn(c *Chat, input string, cfg *config.Config) (string, error) {
+	if strings.TrimSpace(input) == "" {
+		return "", nil
 	}
 
-	if currentLine.Len() > 0 {
-		fullResponse.WriteString(currentLine.String())
+	stream, err := ProcessInputStream(context.Background(), c, input, cfg)
+	if err != nil {
+		return "", err
 	}
 
-	return fullResponse.String()
-}
-
-func ProcessInputAndReturn(c *Chat, input string, cfg *config.Config) (string, error) {
-	if strings.TrimSpace(input) == "" {
-		return "", nil
+	// Capture the entire response from the stream
+	result := collectStream(stream)
+	if result.Content() == "" && result.Err != nil {
+		return "", result.Err
 	}
+	return keptAnswer(context.Background(), result), nil
+}
 
+// ProcessInputStream adds the input to the history and returns the upstream
+// stream of events. The assistant's answer is appended to the history once the
+// stream is drained. Cancelling ctx aborts the upstream request; any partial
+// answer received so far is still kept.
+func ProcessInputStream(ctx context.Context, c *Chat, input string, cfg *config.Config) (<-chan StreamEvent, error) {
 	// Check if this is the first message and if a GlobalPrompt is defined
 	isFirstMessage := len(c.Messages) == 0
 
@@ -357,246 +455,123 @@ func ProcessInputAndReturn(c *Chat, input string, cfg *config.Config) (string, e
 		actualMessage = cfg.GlobalPrompt + "\n\n" + input
 	}
 
-	c.Messages = append(c.Messages, Message{
+	c.appendMessage(Message{
 		Role:    "user",
 		Content: actualMessage,
 	})
 
-	stream, err := c.FetchStream(actualMessage)
+	startTime := time.Now()
+	stream, err := c.FetchStream(ctx, actualMessage)
 	if err != nil {
-		return "", fmt.Errorf("error fetching stream: %w", err)
+		c.dropLastUserMessage()
+		return nil, fmt.Errorf("error fetching stream: %w", err)
 	}
 
-	// Capture the entire response from the stream
-	finalResponse := renderStreamToString(stream)
+	out := make(chan StreamEvent)
+	go func() {
+		defer close(out)
+
+		result := &StreamResult{}
+		for event := range stream {
+			result.Add(event)
+			select {
+			case out <- event:
+			case <-ctx.Done():
+				// Keep draining so the upstream goroutine can finish
+			}
+		}
 
-	// Add the assistant's response to the message history
-	c.Messages = append(c.Messages, Message{
-		Role:    "assistant",
-		Content: finalResponse,
-	})
+		// Add the assistant's response to the message history
+		if response := keptAnswer(ctx, result); response != "" {
+			c.appendMessage(Message{
+				Role:    "assistant",
+				Content: response,
+				Model:   c.CurrentModel(),
+				Latency: time.Since(startTime),
+			})
+		}
+	}()
 
-	return finalResponse, nil
+	return out, nil
 }
 
 func shortenModelName(model string) string {
-	displayNames := map[string]models.ModelAlias{
-		"gpt-4o-mini":                               "gpt-4o-mini",
-		"claude-3-haiku-20240307":                   "claude-3-haiku",
-		"meta-llama/Llama-3.3-70B-Instruct-Turbo":   "llama",
-		"mistralai/Mistral-Small-24B-Instruct-2501": "mixtral",
-		"o4-mini": "o4mini",
-		"o3-mini": "o3mini",
+	if model == "" {
+		return "unknown"
 	}
-
-	if shortName, exists := displayNames[model]; exists {
-		return string(shortName)
-	}
-
-	return "unknown"
+	return models.ShortName(models.Model(model))
 }
 
-func (c *Chat) FetchStream(content string) (<-chan string, error) {
-	resp, err := c.Fetch(content)
+// FetchStream sends the conversation to the provider and streams the answer
+// as events. The stream is closed after a done or an error event, or once ctx
+// is cancelled. The outcome and duration of the exchange, the model that
+// served it and answers cut short are recorded in the analytics.
+func (c *Chat) FetchStream(ctx context.Context, content string) (<-chan StreamEvent, error) {
+	startTime := time.Now()
+	resp, err := c.Provider.Stream(ctx, c)
 	if err != nil {
+		if ctx.Err() == nil {
+			c.Analytics.RecordChatInteraction(string(c.Model), time.Since(startTime), false, interactionErrorType(err))
+		}
 		return nil, err
 	}
 
-	stream := make(chan string)
+	stream := make(chan StreamEvent)
 	go func() {
-		defer resp.Body.Close()
 		defer close(stream)
 
-		scanner := bufio.NewScanner(resp.Body)
-
-		for scanner.Scan() {
-			line := scanner.Text()
-
-			if line == "data: [DONE]" {
-				break
-			}
-
-			if strings.HasPrefix(line, "data: ") {
-				data := strings.TrimPrefix(line, "data: ")
-				var messageData struct {
-					Role    string `json:"role"`
-					Message string `json:"message"`
-					Created int64  `json:"created"`
-					ID      string `json:"id"`
-					Action  string `json:"action"`
-					Model   string `json:"model"`
-				}
-				if err := json.Unmarshal([]byte(data), &messageData); err != nil {
-					log.Printf("Error unmarshaling data: %v\n", err)
-					continue
-				}
-
-				if messageData.Message != "" {
-					stream <- messageData.Message
-				}
+		result := &StreamResult{}
+		for event := range resp.Events {
+			result.Add(event)
+			select {
+			case stream <- event:
+			case <-ctx.Done():
+				// Keep draining so the provider can finish
 			}
 		}
 
-		if err := scanner.Err(); err != nil {
-			log.Printf("Error reading response body: %v\n", err)
+		if ctx.Err() != nil {
+			return
 		}
-
-		if newVqd := resp.Header.Get("x-vqd-4"); newVqd != "" {
-			c.OldVqd = c.NewVqd
-			c.NewVqd = newVqd
+		if result.Model != "" {
+			c.Analytics.RecordServedModel(result.Model)
+		}
+		if result.Truncated() && result.Content() != "" {
+			c.Analytics.RecordTruncatedAnswer()
+		}
+		// The error itself is shown by whoever reads the stream
+		if result.Err != nil {
+			c.Analytics.RecordChatInteraction(string(c.Model), time.Since(startTime), false, "stream")
+		} else {
+			c.Analytics.RecordChatInteraction(string(c.Model), time.Since(startTime), true, "")
 		}
-
-		c.RetryCount = 0
 	}()
 
 	return stream, nil
 }
 
-func (c *Chat) Fetch(content string) (*http.Response, error) {
-	startTime := time.Now()
-	if c.NewVqd == "" {
-		newVqd, newVqdHash1, newFeSignals, newFeVersion := GetVQD()
-		c.NewVqd = newVqd
-		c.VqdHash1 = newVqdHash1
-		c.FeSignals = newFeSignals
-		c.FeVersion = newFeVersion
-		if c.NewVqd == "" {
-			return nil, fmt.Errorf("failed to get VQD")
-		}
-	}
-
-	// VQD hash is now initialized during chat creation
-
-	payload := ChatPayload{
-		Model: c.Model,
-		Metadata: Metadata{
-			ToolChoice: ToolChoice{
-				NewsSearch:      false,
-				VideosSearch:    false,
-				LocalSearch:     false,
-				WeatherForecast: false,
-			},
-		},
-		Messages:             c.Messages,
-		CanUseTools:          true,
-		CanUseApproxLocation: true,
-	}
-
-	jsonPayload, err := json.Marshal(payload)
-	if err != nil {
-		return nil, fmt.Errorf("error marshaling payload: %v", err)
-	}
-
-	if os.Getenv("DEBUG") == "true" {
-		color.Cyan("Payload: %s", string(jsonPayload))
-	}
-
-	req, err := http.NewRequest("POST", models.ChatURL, bytes.NewBuffer(jsonPayload))
-	if err != nil {
-		return nil, fmt.Errorf("error creating request: %v", err)
-	}
-
-	// Set ALL required headers EXACTLY like the real web browser request
-	req.Header.Set("Accept", "text/event-stream")
-	req.Header.Set("Accept-Encoding", "gzip, deflate, br, zstd")
-	req.Header.Set("Accept-Language", "fr-FR,fr;q=0.6")
-	req.Header.Set("Authority", "duckduckgo.com")
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("DNT", "1")
-	req.Header.Set("Method", "POST")
-	req.Header.Set("Origin", "https://duckduckgo.com")
-	req.Header.Set("Path", "/duckchat/v1/chat")
-	req.Header.Set("Priority", "u=1, i")
-	req.Header.Set("Referer", "https://duckduckgo.com/")
-	req.Header.Set("Scheme", "https")
-	req.Header.Set("Sec-CH-UA", `"Not)A;Brand";v="8", "Chromium";v="138", "Brave";v="138"`)
-	req.Header.Set("Sec-CH-UA-Mobile", "?0")
-	req.Header.Set("Sec-CH-UA-Platform", `"Windows"`)
-	req.Header.Set("Sec-Fetch-Dest", "empty")
-	req.Header.Set("Sec-Fetch-Mode", "cors")
-	req.Header.Set("Sec-Fetch-Site", "same-origin")
-	req.Header.Set("Sec-GPC", "1")
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/138.0.0.0 Safari/537.36")
-
-	// ALL VQD-related headers from the real web browser request
-	req.Header.Set("x-vqd-4", c.NewVqd)
-	if c.FeSignals != "" {
-		req.Header.Set("x-fe-signals", c.FeSignals)
-	}
-	if c.FeVersion != "" {
-		req.Header.Set("x-fe-version", c.FeVersion)
-	}
-	if c.VqdHash1 != "" {
-		req.Header.Set("x-vqd-hash-1", c.VqdHash1)
-	}
-
-	resp, err := c.Client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("error sending request: %v", err)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		resp.Body.Close()
-
-		if os.Getenv("DEBUG") == "true" {
-			color.Red("Request Headers: %+v", req.Header)
-			color.Red("Response Headers: %+v", resp.Header)
-			color.Red("Response Status: %d", resp.StatusCode)
-			color.Red("Response Body: %s", string(body))
-		}
-
-		// Handle various error conditions including 418 (I'm a teapot)
-		if resp.StatusCode == 418 || resp.StatusCode == 429 || strings.Contains(string(body), "ERR_INVALID_VQD") {
-			// Track specific error types
-			errorType := "unknown"
-			switch resp.StatusCode {
-			case 418:
-				errorType = "418"
-			case 429:
-				errorType = "429"
-			}
-			c.Analytics.RecordChatInteraction(time.Since(startTime), false, errorType)
-
-			time.Sleep(2 * time.Second)
-
-			// Refresh ONLY VQD on errors, like the PowerShell script
-			ui.Warningln("🔄 Error %d detected, refreshing VQD...", resp.StatusCode)
-			newVqd, newVqdHash1, newFeSignals, newFeVersion := GetVQD()
-			if newVqd != "" {
-				c.NewVqd = newVqd
-				c.VqdHash1 = newVqdHash1
-				c.FeSignals = newFeSignals
-				c.FeVersion = newFeVersion
-				ui.AIln("✅ Refreshed VQD: %s...", c.NewVqd[:50])
-			}
-			c.Analytics.RecordVQDRefresh()
-
-			if c.NewVqd != "" && c.RetryCount < 3 {
-				c.RetryCount++
-				ui.Warningln("Retrying request (attempt %d/3)...", c.RetryCount)
-				return c.Fetch(content)
-			}
+// interactionErrorType names the error that ended a failed exchange, so the
+// 418 and 429 left once retries are exhausted keep their own counters
+func interactionErrorType(err error) string {
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		switch statusErr.Status {
+		case 418:
+			return "418"
+		case 429:
+			return "429"
 		}
-		return nil, fmt.Errorf("%d: Failed to send message. %s. Body: %s", resp.StatusCode, resp.Status, string(body))
 	}
-
-	newVqd := resp.Header.Get("x-vqd-4")
-	if newVqd != "" {
-		c.OldVqd = c.NewVqd
-		c.NewVqd = newVqd
-	}
-
-	return resp, nil
+	return "unknown"
 }
 
-func (c *Chat) AddURLContext(url string) error {
+func (c *Chat) AddURLContext(ctx context.Context, url string) error {
 	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
 		url = "https://" + url
 	}
 
 	ui.Warningln("⌛ Retrieving webpage content...")
-	content, err := scrape.WebContent(url)
+	content, err := scrape.WebContent(ctx, url)
 	if err != nil {
 		return err
 	}
@@ -606,14 +581,22 @@ func (c *Chat) AddURLContext(url string) error {
 		ui.AIln("Retrieved %d characters of content", contentLength)
 	}
 
-	c.Messages = append(c.Messages, Message{
+	c.AddWebpageContext(url, content.Content)
+	return nil
+}
+
+// AddWebpageContext adds webpage content that was already scraped to the
+// conversation.
+func (c *Chat) AddWebpageContext(url string, content string) {
+	c.appendMessage(Message{
 		Role:    "user",
-		Content: fmt.Sprintf("[URL Context]\nURL: %s\n\n%s", url, content.Content),
+		Content: chatcontext.FormatURL(url, content),
+		Source:  SourceURL,
 	})
 
-	c.Analytics.RecordURLProcessed()
-
-	return nil
+	if c.Analytics != nil {
+		c.Analytics.RecordURLProcessed()
+	}
 }
 
 func PrintCommands() {
@@ -703,14 +686,18 @@ func printCommandsTable(commands []CommandHelp) {
 	}
 }
 
-func HandleURLCommand(c *Chat, input string, cfg *config.Config, chainCtx *chatcontext.Context) {
+func HandleURLCommand(ctx context.Context, c *Chat, input string, cfg *config.Config, chainCtx *chatcontext.Context) {
 	urlStr := strings.TrimSpace(strings.TrimPrefix(input, "/url"))
 	if urlStr == "" {
 		ui.Errorln("URL cannot be empty.")
 		return
 	}
 
-	result, err := scrape.WebContent(urlStr)
+	result, err := scrape.WebContent(ctx, urlStr)
+	if ctx.Err() != nil {
+		ui.Warningln("Request canceled")
+		return
+	}
 	if err != nil {
 		ui.Errorln("URL error: %v", err)
 		return
@@ -733,9 +720,10 @@ func (c *Chat) addURLContext(url string, content string) {
 		ui.AIln("Adding %d characters from URL", contentLength)
 	}
 
-	c.Messages = append(c.Messages, Message{
+	c.appendMessage(Message{
 		Role:    "user",
-		Content: fmt.Sprintf("[URL Context]\nURL: %s\n\n%s", url, content),
+		Content: chatcontext.FormatURL(url, content),
+		Source:  SourceURL,
 	})
 }
 
@@ -745,6 +733,7 @@ func HandleExportCommand(c *Chat, cfg *config.Config) {
 		Message: "Choose what to export:",
 		Options: []string{
 			"Full conversation",
+			"Conversation tree (all branches)",
 			"Last AI response",
 			"Largest code block",
 			"Search in conversation",
@@ -763,6 +752,8 @@ func HandleExportCommand(c *Chat, cfg *config.Config) {
 	switch choice {
 	case "Full conversation":
 		filename, content = c.Export("conversation", "")
+	case "Conversation tree (all branches)":
+		filename, content = c.Export("conversation_tree", "")
 	case "Last AI response":
 		filename, content = c.Export("last_response", "")
 	case "Largest code block":
@@ -841,10 +832,20 @@ func HandleLoadCommand(c *Chat, args string) {
 }
 
 func loadAndRestoreSession(c *Chat, sessionID string) {
+	if err := c.LoadSavedSession(sessionID); err != nil {
+		ui.Errorln("%v", err)
+		return
+	}
+	ui.AIln("Session %s loaded successfully. Context restored.", sessionID)
+}
+
+// LoadSavedSession replaces the conversation with a session saved by the
+// history manager. The current conversation is saved first so it can be
+// loaded back later. The caller must hold the session's turn.
+func (c *Chat) LoadSavedSession(sessionID string) error {
 	session, err := c.HistoryManager.LoadSession(sessionID)
 	if err != nil {
-		ui.Errorln("Error loading session %s: %v", sessionID, err)
-		return
+		return fmt.Errorf("error loading session %s: %w", sessionID, err)
 	}
 
 	// Save current session before loading a new one
@@ -853,63 +854,151 @@ func loadAndRestoreSession(c *Chat, sessionID string) {
 	}
 
 	c.RestoreContext(session)
-	ui.AIln("Session %s loaded successfully. Context restored.", sessionID)
+	return nil
 }
 
 func (c *Chat) ChangeModel(model models.Model) {
-	c.Model = model
+	c.SetModel(model)
 	c.Analytics.RecordModelChange(string(model))
 	setTerminalTitle(fmt.Sprintf("DuckDuckGo Chat - %s", model))
 	ui.AIln("Model changed to %s", model)
 }
 
+// SetModel switches the model without printing, retitling the terminal or
+// recording a model change, for API sessions the terminal user does not see.
+func (c *Chat) SetModel(model models.Model) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Model = model
+	c.ContextOptimizer.MaxContextSize = models.ContextWindow(model)
+	c.journal.SetModel(string(model))
+}
+
+// Fork returns a new Chat that shares this session's HTTP client and
+// intelligent features but carries its own message history and model. It
+// gets a VQD of its own on its first request, since a VQD belongs to one
+// conversation at a time. It is used by stateless API endpoints that send the
+// full conversation with every request, so the interactive history is left
+// untouched.
+func (c *Chat) Fork(messages []Message, model models.Model) *Chat {
+	for i := range messages {
+		stampMessage(&messages[i])
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return &Chat{
+		Model:      model,
+		Messages:   messages,
+		CookieJar:  c.CookieJar,
+		Client:     c.Client,
+		RetryCount: 0,
+		Provider:   c.Provider,
+
+		Analytics:        c.Analytics,
+		ContextOptimizer: c.ContextOptimizer,
+		HistoryManager:   c.HistoryManager,
+		SessionID:        c.SessionID,
+
+		queue: newTurnQueue(c.queue.limit),
+	}
+}
+
+// AddContextMessage adds the context loaded by a command chain
 func (c *Chat) AddContextMessage(content string) {
-	c.Messages = append(c.Messages, Message{
+	c.appendMessage(Message{
 		Role:    "user",
 		Content: content,
+		Source:  SourceChain,
 	})
 }
 
 // RestoreContext restores the chat context from a given conversation session.
 func (c *Chat) RestoreContext(session *persistence.ConversationSession) {
-	// Convert persistence.Message to chat.Message
-	c.Messages = make([]Message, len(session.Messages))
-	for i, msg := range session.Messages {
-		c.Messages[i] = Message{
-			Content: msg.Content,
-			Role:    msg.Role,
-		}
-	}
+	messages := fromIntelligenceMessages(session.Messages)
+
+	c.mu.Lock()
+	c.Messages = messages
+	c.restoreBranches(session)
 	c.SessionID = session.ID
 	c.Model = models.Model(session.Model) // Restore the model used in that session
+	c.restartJournal()
+	c.mu.Unlock()
 	ui.AIln("Context restored from session %s. Model set to %s.", session.ID, session.Model)
 }
 
+// appendMessage adds a message to the history. All history changes go
+// through appendMessage or setMessages so readers see a consistent state.
+func (c *Chat) appendMessage(msg Message) {
+	stampMessage(&msg)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Messages = append(c.Messages, msg)
+	c.journal.AppendMessage(toIntelligenceMessages([]Message{msg})[0])
+}
+
+// dropLastUserMessage removes the input of a turn that got no answer, so the
+// history keeps alternating between user and assistant messages
+func (c *Chat) dropLastUserMessage() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if n := len(c.Messages); n > 0 && c.Messages[n-1].Role == "user" {
+		c.Messages = c.Messages[:n-1]
+		c.journalMessages()
+	}
+}
+
+// setMessages replaces the whole history
+func (c *Chat) setMessages(messages []Message) {
+	for i := range messages {
+		stampMessage(&messages[i])
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Messages = messages
+	c.journalMessages()
+}
+
+// MessagesSnapshot returns a copy of the history that is safe to use while
+// another goroutine holds the turn.
+func (c *Chat) MessagesSnapshot() []Message {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]Message(nil), c.Messages...)
+}
+
+// CurrentModel returns the session's model
+func (c *Chat) CurrentModel() models.Model {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Model
+}
+
+// State returns a consistent snapshot of the session state
+func (c *Chat) State() SessionState {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return SessionState{
+		SessionID:    c.SessionID,
+		Model:        c.Model,
+		MessageCount: len(c.Messages),
+		NewVqd:       c.NewVqd,
+		RetryCount:   c.RetryCount,
+		QueueDepth:   c.QueueDepth(),
+	}
+}
+
 // Helper methods for intelligent features
 
 // convertMessagesToIntelligence converts Chat messages to intelligence.Message format
 func (c *Chat) convertMessagesToIntelligence() []intelligence.Message {
-	result := make([]intelligence.Message, len(c.Messages))
-	for i, msg := range c.Messages {
-		result[i] = intelligence.Message{
-			Content:   msg.Content,
-			Role:      msg.Role,
-			Timestamp: time.Now(), // We don't have timestamps in current messages
-		}
-	}
-	return result
+	return toIntelligenceMessages(c.Messages)
 }
 
 // convertFromIntelligenceMessages converts intelligence.Message back to Chat messages
 func (c *Chat) convertFromIntelligenceMessages(messages []intelligence.Message) []Message {
-	result := make([]Message, len(messages))
-	for i, msg := range messages {
-		result[i] = Message{
-			Content: msg.Content,
-			Role:    msg.Role,
-		}
-	}
-	return result
+	return fromIntelligenceMessages(messages)
 }
 
 // saveCurrentSession saves the current conversation session to persistent storage
@@ -917,12 +1006,31 @@ func (c *Chat) saveCurrentSession() {
 	if len(c.Messages) == 0 {
 		return // No content to save
 	}
+	session := c.currentSession()
+
+	// Save session asynchronously. The journal is only removed once the
+	// session is safely saved.
+	journal := c.journal
+	go func() {
+		if err := c.HistoryManager.SaveSession(session); err != nil {
+			ui.Warningln("Failed to save session: %v", err)
+			return
+		}
+		journal.Remove()
+	}()
+}
+
+// currentSession builds the session object saved by the history manager
+func (c *Chat) currentSession() *persistence.ConversationSession {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 
 	// Convert messages to intelligence format
 	intelligenceMessages := c.convertMessagesToIntelligence()
 
-	// Create session object
-	session := &persistence.ConversationSession{
+	branches, activeBranch := c.sessionBranches()
+
+	return &persistence.ConversationSession{
 		ID:        c.SessionID,
 		StartTime: c.Analytics.SessionStartTime,
 		Model:     string(c.Model),
@@ -934,14 +1042,60 @@ func (c *Chat) saveCurrentSession() {
 			ErrorCount:        c.Analytics.APICallsFailed,
 			OptimizationsUsed: c.Analytics.ContextOptimizations,
 		},
+		Branches:     branches,
+		ActiveBranch: activeBranch,
+	}
+}
+
+// StartJournal records the conversation on disk from now on, so that it can
+// be recovered if the program is killed (see RecoverUnfinishedSession)
+func (c *Chat) StartJournal() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.journal = c.HistoryManager.NewJournal(c.SessionID, c.Analytics.SessionStartTime, string(c.Model))
+	if len(c.Messages) > 0 {
+		c.journalMessages()
 	}
+}
 
-	// Save session asynchronously
-	go func() {
-		if err := c.HistoryManager.SaveSession(session); err != nil {
+// restartJournal gives a new session its own journal, leaving the previous
+// one to saveCurrentSession. The caller must hold c.mu.
+func (c *Chat) restartJournal() {
+	if c.journal == nil {
+		return
+	}
+	c.journal = c.HistoryManager.NewJournal(c.SessionID, c.Analytics.SessionStartTime, string(c.Model))
+	if len(c.Messages) > 0 {
+		c.journalMessages()
+	}
+}
+
+// journalMessages records a history replaced as a whole, along with the
+// conversation tree so the other branches survive a crash too. The caller
+// must hold c.mu.
+func (c *Chat) journalMessages() {
+	if c.journal == nil {
+		return
+	}
+	branches, activeBranch := c.sessionBranches()
+	c.journal.ReplaceMessages(toIntelligenceMessages(c.Messages), branches, activeBranch)
+}
+
+// Close saves the conversation at the end of an interactive session and
+// removes its journal. If the save fails, the journal is kept so the
+// conversation can still be recovered on the next start.
+func (c *Chat) Close() {
+	if len(c.MessagesSnapshot()) > 0 {
+		if err := c.HistoryManager.SaveSession(c.currentSession()); err != nil {
 			ui.Warningln("Failed to save session: %v", err)
+			return
 		}
-	}()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.journal.Remove()
+	c.journal = nil
 }
 
 // ShowSessionStats displays analytics at the end of the session
@@ -950,7 +1104,7 @@ func (c *Chat) ShowSessionStats() {
 }
 
 // HandlePromptCommand processes the /prompt command for prompt management and loading
-func HandlePromptCommand(c *Chat, input string, cfg *config.Config) {
+func HandlePromptCommand(ctx context.Context, c *Chat, input string, cfg *config.Config) {
 	args := strings.TrimPrefix(input, "/prompt")
 	args = strings.TrimSpace(args)
 	if args == "" || args == "help" {
@@ -1051,7 +1205,7 @@ func HandlePromptCommand(c *Chat, input string, cfg *config.Config) {
 			preview = preview[:80] + "..."
 		}
 		ui.AIln("[Prompt: %s] Preview: %s", name, preview)
-		ProcessInput(c, promptText, cfg)
+		ProcessInput(ctx, c, promptText, cfg)
 	default:
 		ui.Errorln("Unknown /prompt subcommand: %s", subcmd)
 	}
@@ -0,0 +1,228 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"duckduckgo-chat-cli/internal/config"
+	"duckduckgo-chat-cli/internal/models"
+	"duckduckgo-chat-cli/internal/ui"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/fatih/color"
+)
+
+// comparison is the answer of one model to a /compare
+type comparison struct {
+	Model   models.Model
+	Answer  string
+	Latency time.Duration
+	Err     error
+
+	fork *Chat // carries the model's upstream conversation state
+}
+
+// HandleCompareCommand processes the /compare command: it sends the
+// conversation and a new message to several models at once, shows their
+// answers one after the other and keeps the one the user picks. Without
+// models, every offered DuckDuckGo model is compared.
+func HandleCompareCommand(ctx context.Context, c *Chat, input string, cfg *config.Config) {
+	args := strings.TrimSpace(strings.TrimPrefix(input, "/compare"))
+	names, prompt, _ := strings.Cut(args, "--")
+	prompt = strings.TrimSpace(prompt)
+	if prompt == "" {
+		ui.Errorln("Usage: /compare [models...] -- prompt")
+		return
+	}
+
+	compared, ok := comparedModels(c, strings.Fields(names))
+	if !ok {
+		return
+	}
+	if len(compared) < 2 {
+		ui.Warningln("Name at least two models to compare")
+		return
+	}
+
+	previous := c.MessagesSnapshot()
+	content := prompt
+	if len(previous) == 0 && cfg.GlobalPrompt != "" {
+		content = cfg.GlobalPrompt + "\n\n" + prompt
+	}
+	question := Message{Role: "user", Content: content}
+	stampMessage(&question)
+
+	ui.AIln("⚖️  Asking %d models...", len(compared))
+	results := c.compare(ctx, append(previous, question), compared)
+	if ctx.Err() != nil {
+		ui.Warningln("Comparison interrupted")
+	}
+	printComparison(results)
+
+	choice, ok := pickComparison(results)
+	if !ok {
+		ui.Warningln("No answer kept, the conversation is unchanged")
+		return
+	}
+	c.keepComparison(question, results[choice])
+}
+
+// comparedModels resolves the models named by the user, or lists the offered
+// ones. It reports false after printing an error.
+func comparedModels(c *Chat, names []string) ([]models.Model, bool) {
+	if len(names) == 0 {
+		if c.Provider.Name() != config.ProviderDuckDuckGo {
+			ui.Errorln("Name the models to compare: /compare <model> <model>... -- prompt")
+			return nil, false
+		}
+		var compared []models.Model
+		for _, m := range models.Offered() {
+			compared = append(compared, m.ID)
+		}
+		return compared, true
+	}
+
+	var compared []models.Model
+	seen := make(map[models.Model]bool)
+	for _, name := range names {
+		model, ok := ResolveModelName(c, name)
+		if !ok {
+			ui.Errorln("Unknown model: %s", name)
+			return nil, false
+		}
+		if !seen[model] {
+			seen[model] = true
+			compared = append(compared, model)
+		}
+	}
+	return compared, true
+}
+
+// compare sends the conversation to every model concurrently. Each model gets
+// a fork of the session with its own upstream state, so that their VQDs do
+// not interfere.
+func (c *Chat) compare(ctx context.Context, conversation []Message, compared []models.Model) []comparison {
+	results := make([]comparison, len(compared))
+	var wg sync.WaitGroup
+
+	for i, model := range compared {
+		fork := c.Fork(append([]Message(nil), conversation...), model)
+		results[i] = comparison{Model: model, fork: fork}
+
+		wg.Add(1)
+		go func(r *comparison) {
+			defer wg.Done()
+
+			startTime := time.Now()
+			stream, err := r.fork.FetchStream(ctx, conversation[len(conversation)-1].Content)
+			if err != nil {
+				r.Err = err
+				return
+			}
+			result := collectStream(stream)
+			r.Latency = time.Since(startTime)
+			r.Answer = keptAnswer(ctx, result)
+			if r.Answer == "" && result.Err != nil {
+				r.Err = result.Err
+				return
+			}
+			ui.Mutedln("  ✓ %s answered in %s", models.DisplayName(r.Model), formatLatency(r.Latency))
+		}(&results[i])
+	}
+
+	wg.Wait()
+	return results
+}
+
+// printComparison prints each answer in its own labelled section, then a
+// summary of the latencies and lengths
+func printComparison(results []comparison) {
+	header := color.New(color.FgHiCyan, color.Bold)
+	for i, r := range results {
+		fmt.Println()
+		header.Printf("━━ [%d] %s ", i+1, models.DisplayName(r.Model))
+		switch {
+		case r.Err != nil:
+			fmt.Println()
+			ui.Errorln("Error: %v", r.Err)
+		case r.Answer == "":
+			fmt.Println()
+			ui.Warningln("(no answer)")
+		default:
+			ui.Mutedln("· %s · %d characters", formatLatency(r.Latency), len(r.Answer))
+			fmt.Println(strings.TrimRight(RenderMarkdown(r.Answer), " \n"))
+		}
+	}
+
+	fmt.Println()
+	ui.Systemln("%-4s %-20s %10s %12s", "", "Model", "Latency", "Length")
+	for i, r := range results {
+		latency, length := "-", "-"
+		if r.Err == nil && r.Answer != "" {
+			latency = formatLatency(r.Latency)
+			length = fmt.Sprintf("%d chars", len(r.Answer))
+		}
+		ui.Whiteln("%-4s %-20s %10s %12s", fmt.Sprintf("[%d]", i+1), models.DisplayName(r.Model), latency, length)
+	}
+	fmt.Println()
+}
+
+// pickComparison asks which answer to keep
+func pickComparison(results []comparison) (int, bool) {
+	var options []string
+	var indexes []int
+	for i, r := range results {
+		if r.Err == nil && r.Answer != "" {
+			options = append(options, fmt.Sprintf("[%d] %s", i+1, models.DisplayName(r.Model)))
+			indexes = append(indexes, i)
+		}
+	}
+	if len(options) == 0 {
+		return 0, false
+	}
+	options = append(options, "Keep none")
+
+	var choice int
+	prompt := &survey.Select{
+		Message: "Keep which answer and continue with its model?",
+		Options: options,
+	}
+	if err := survey.AskOne(prompt, &choice, survey.WithStdio(os.Stdin, os.Stdout, os.Stderr)); err != nil || choice == len(indexes) {
+		return 0, false
+	}
+	return indexes[choice], true
+}
+
+// keepComparison adds the question and the chosen answer to the history and
+// continues the conversation with the model that wrote it, taking over its
+// upstream state
+func (c *Chat) keepComparison(question Message, r comparison) {
+	r.fork.mu.RLock()
+	oldVqd, newVqd, vqdHash1 := r.fork.OldVqd, r.fork.NewVqd, r.fork.VqdHash1
+	feSignals, feVersion := r.fork.FeSignals, r.fork.FeVersion
+	r.fork.mu.RUnlock()
+
+	c.mu.Lock()
+	c.OldVqd, c.NewVqd, c.VqdHash1 = oldVqd, newVqd, vqdHash1
+	c.FeSignals, c.FeVersion = feSignals, feVersion
+	c.mu.Unlock()
+
+	if r.Model != c.CurrentModel() {
+		c.ChangeModel(r.Model)
+	}
+
+	c.Analytics.RecordMessage("user", len(question.Content))
+	c.appendMessage(question)
+	c.Analytics.RecordMessage("assistant", len(r.Answer))
+	c.appendMessage(Message{
+		Role:    "assistant",
+		Content: r.Answer,
+		Model:   r.Model,
+		Latency: r.Latency,
+	})
+	ui.AIln("Kept the answer of %s", models.DisplayName(r.Model))
+}
@@ -4,11 +4,11 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"net/http/cookiejar"
-	"net/url"
 	"regexp"
 	"strings"
 	"time"
+
+	"duckduckgo-chat-cli/internal/fingerprint"
 )
 
 type DynamicHeaders struct {
@@ -18,6 +18,8 @@ type DynamicHeaders struct {
 	UserAgent string
 	ChromeUA  string
 	Cookies   map[string]string
+
+	profile fingerprint.Profile // browser the requests pass for
 }
 
 // Structure pour décoder les données de configuration JavaScript
@@ -47,24 +49,18 @@ type VqdHashData struct {
 // ExtractDynamicHeaders récupère dynamiquement les headers depuis DuckDuckGo
 // en suivant le même processus que les scripts PowerShell qui fonctionnent
 func ExtractDynamicHeaders() (*DynamicHeaders, error) {
-	client := &http.Client{Timeout: 30 * time.Second}
-
-	// Configuration des cookies avec jar
-	jar, _ := cookiejar.New(nil)
-	u, _ := url.Parse("https://duckduckgo.com")
-	cookies := []*http.Cookie{
-		{Name: "5", Value: "1", Domain: ".duckduckgo.com"},
-		{Name: "dcm", Value: "3", Domain: ".duckduckgo.com"},
-		{Name: "dcs", Value: "1", Domain: ".duckduckgo.com"},
-	}
-	jar.SetCookies(u, cookies)
-	client.Jar = jar
+	return extractDynamicHeaders(newVQDClient(30*time.Second), fingerprint.Current())
+}
 
+// extractDynamicHeaders suit le processus avec le client donné, qui garde les
+// cookies posés par les requêtes de challenge
+func extractDynamicHeaders(client *http.Client, profile fingerprint.Profile) (*DynamicHeaders, error) {
 	// Headers de base pour simuler un navigateur réel
 	headers := &DynamicHeaders{
-		UserAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/138.0.0.0 Safari/537.36",
-		ChromeUA:  `"Not)A;Brand";v="8", "Chromium";v="138", "Brave";v="138"`,
+		UserAgent: profile.UserAgent,
+		ChromeUA:  profile.SecCHUA,
 		Cookies:   make(map[string]string),
+		profile:   profile,
 	}
 
 	// Étape 1: Récupérer la page principale pour obtenir les données dynamiques
@@ -125,9 +121,8 @@ func ExtractDynamicHeaders() (*DynamicHeaders, error) {
 	}
 
 	// Utiliser le VQD hash JSON fonctionnel du navigateur
-	fmt.Printf("🔍 Using working VQD hash from browser analysis...\n")
-	headers.VqdHash1 = "eyJzZXJ2ZXJfaGFzaGVzIjpbIkNjS0RKWjNjQjVNc0QzVzJkc2hQK1hFQjB0RjNmWE1jUXcxV1M2NE5zcWc9IiwiQ3l2TUpNKzNIY3k0dkVPS1d6alJIbUZyQmEzMDR5MTVGcHBtTUpqV1NFOD0iLCIrTTFVZ1ZPWHpCc1JybDhIOVBjWjlkd1VxTExZNWRWdVZTb09RcUxqdzk4PSJdLCJjbGllbnRfaGFzaGVzIjpbImxWblI0MStCMVFWZ0o4d0hhMUdBNmdxR0JoSjlWdjN5K0dISkdGekJmTGM9IiwiTFEwYUxFaEJTeHJPeFoxcVVjb1dIWUlRKzNEcWtmZTZGYm9MdUlQcEFVYz0iLCJkRXkxaVNaTWxqQnYvK3dFRzg2UStSaFNTUlJFbDh2eGtaem1jS21iYTM0PSJdLCJzaWduYWxzIjp7fSwibWV0YSI6eyJ2IjoiNCIsImNoYWxsZW5nZV9pZCI6IjBkZTYxMmRkZTM4ZTVkOGFlNmQyYTFjMzY1ZmYzZjdiNDBiNDViYzgxODk1OTNiNzdmY2UwMDBhNzA3ODc4Y2VoOGpidCIsInRpbWVzdGFtcCI6IjE3NTIxNTIwNTkzOTkiLCJvcmlnaW4iOiJodHRwczovL2R1Y2tkdWNrZ28uY29tIiwic3RhY2siOiJFcnJvclxuYXQgRSAoaHR0cHM6Ly9kdWNrZHVja2dvLmNvbS9kaXN0L3dwbS5jaGF0LjcwZWFjYTZhZWEyOTQ4YjBiYjYwLmpzOjE6MTQ4MjUpXG5hdCBhc3luYyBodHRwczovL2R1Y2tkdWNrZ28uY29tL2Rpc3Qvd3BtLmNoYXQuNzBlYWNhNmFlYTI5NDhiMGJiNjAuanM6MToxNjk4NSIsImR1cmF0aW9uIjoiODUifX0="
-	fmt.Printf("✅ Using proven working VQD hash from browser\n")
+	fmt.Printf("🔍 Using VQD hash of fingerprint profile %s...\n", profile.Label())
+	headers.VqdHash1 = profile.VqdHash1
 
 	// Capturer les cookies supplémentaires
 	extractCookies(resp, headers)
@@ -137,10 +132,10 @@ func ExtractDynamicHeaders() (*DynamicHeaders, error) {
 
 	// Validation des données extraites
 	if headers.FeVersion == "" {
-		headers.FeVersion = "serp_20250710_070136_ET-70eaca6aea2948b0bb60"
+		headers.FeVersion = profile.FeVersion
 	}
 	if headers.FeSignals == "" {
-		headers.FeSignals = "eyJzdGFydCI6MTc1MjE1MjA1OTAzMywiZXZlbnRzIjpbeyJuYW1lIjoic3RhcnROZXdDaGF0IiwiZGVsdGEiOjcyfSx7Im5hbWUiOiJyZWNlbnRDaGF0c0xpc3RJbXByZXNzaW9uIiwiZGVsdGEiOjEzOH1dLCJlbmQiOjQ2MDZ9"
+		headers.FeSignals = profile.FeSignals
 	}
 
 	// Afficher les informations de débogage pour les headers extraits
@@ -157,20 +152,16 @@ func ExtractDynamicHeaders() (*DynamicHeaders, error) {
 // setRequestHeaders configure les headers de la requête
 func setRequestHeaders(req *http.Request, headers *DynamicHeaders) {
 	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8")
-	req.Header.Set("Accept-Language", "fr-FR,fr;q=0.5")
 	req.Header.Set("Cache-Control", "no-cache")
 	req.Header.Set("DNT", "1")
 	req.Header.Set("Pragma", "no-cache")
-	req.Header.Set("Sec-CH-UA", headers.ChromeUA)
-	req.Header.Set("Sec-CH-UA-Mobile", "?0")
-	req.Header.Set("Sec-CH-UA-Platform", `"Windows"`)
 	req.Header.Set("Sec-Fetch-Dest", "document")
 	req.Header.Set("Sec-Fetch-Mode", "navigate")
 	req.Header.Set("Sec-Fetch-Site", "none")
 	req.Header.Set("Sec-Fetch-User", "?1")
 	req.Header.Set("Sec-GPC", "1")
 	req.Header.Set("Upgrade-Insecure-Requests", "1")
-	req.Header.Set("User-Agent", headers.UserAgent)
+	headers.profile.SetHeaders(req.Header)
 }
 
 // extractFeVersion extrait la version FE depuis le HTML
@@ -245,7 +236,7 @@ func generateFeSignals(headers *DynamicHeaders) error {
 	now := time.Now().UnixMilli()
 
 	// Générer une variation des signaux de base avec des valeurs réalistes
-	baseSignals := "eyJzdGFydCI6MTc1MTc1MTg4NTc3MiwiZXZlbnRzIjpbeyJuYW1lIjoic3RhcnROZXdDaGF0IiwiZGVsdGEiOjk1fSx7Im5hbWUiOiJyZWNlbnRDaGF0c0xpc3RJbXByZXNzaW9uIiwiZGVsdGEiOjIxOX0seyJuYW1lIjoiaW5pdFN3aXRjaE1vZGVsIiwiZGVsdGEiOjI2OTd9LHsibmFtZSI6InN0YXJ0TmV3Q2hhdCIsImRlbHRhIjo4MTYxfV0sImVuZCI6MjU0ODN9"
+	baseSignals := headers.profile.FeSignals
 
 	// Ajouter une légère variation temporelle pour rendre les signaux plus réalistes
 	timeVariation := now % 1000
@@ -318,21 +309,17 @@ func performChallengeRequests(client *http.Client, challengeURL string, headers
 
 		// Headers spécifiques pour les requêtes de challenge
 		req.Header.Set("Accept", "*/*")
-		req.Header.Set("Accept-Language", "fr-FR,fr;q=0.8")
 		req.Header.Set("Cache-Control", "max-age=0")
 		req.Header.Set("Content-Type", "text/plain;charset=UTF-8")
 		req.Header.Set("DNT", "1")
 		req.Header.Set("Origin", "https://duckduckgo.com")
 		req.Header.Set("Priority", "u=1, i")
 		req.Header.Set("Referer", "https://duckduckgo.com/")
-		req.Header.Set("Sec-CH-UA", headers.ChromeUA)
-		req.Header.Set("Sec-CH-UA-Mobile", "?0")
-		req.Header.Set("Sec-CH-UA-Platform", `"Windows"`)
 		req.Header.Set("Sec-Fetch-Dest", "empty")
 		req.Header.Set("Sec-Fetch-Mode", "cors")
 		req.Header.Set("Sec-Fetch-Site", "same-origin")
 		req.Header.Set("Sec-GPC", "1")
-		req.Header.Set("User-Agent", headers.UserAgent)
+		headers.profile.SetHeaders(req.Header)
 
 		resp, err := client.Do(req)
 		if err != nil {
@@ -356,19 +343,15 @@ func performHEADRequest(client *http.Client, headURL string, headers *DynamicHea
 
 	// Headers pour la requête HEAD
 	req.Header.Set("Accept", "*/*")
-	req.Header.Set("Accept-Language", "fr-FR,fr;q=0.8")
 	req.Header.Set("Cache-Control", "max-age=0")
 	req.Header.Set("DNT", "1")
 	req.Header.Set("Priority", "u=1, i")
 	req.Header.Set("Referer", "https://duckduckgo.com/")
-	req.Header.Set("Sec-CH-UA", headers.ChromeUA)
-	req.Header.Set("Sec-CH-UA-Mobile", "?0")
-	req.Header.Set("Sec-CH-UA-Platform", `"Windows"`)
 	req.Header.Set("Sec-Fetch-Dest", "empty")
 	req.Header.Set("Sec-Fetch-Mode", "cors")
 	req.Header.Set("Sec-Fetch-Site", "same-origin")
 	req.Header.Set("Sec-GPC", "1")
-	req.Header.Set("User-Agent", headers.UserAgent)
+	headers.profile.SetHeaders(req.Header)
 
 	resp, err := client.Do(req)
 	if err != nil {
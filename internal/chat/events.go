@@ -0,0 +1,124 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// EventType is the kind of a StreamEvent
+type EventType string
+
+// Stream event types
+const (
+	EventDelta    EventType = "delta"    // a piece of the answer
+	EventMetadata EventType = "metadata" // the model and ID the upstream service gave the answer
+	EventTool     EventType = "tool"     // an upstream action other than writing the answer, e.g. a tool call
+	EventError    EventType = "error"    // an error that ends the answer
+	EventDone     EventType = "done"     // the answer is complete
+)
+
+// Finish reasons of a done event
+const (
+	FinishStop   = "stop"   // the model finished its answer
+	FinishLength = "length" // the answer hit the output limit
+)
+
+// StreamEvent is one event of a streamed answer. A stream ends with a done or
+// an error event, unless its context is cancelled first.
+type StreamEvent struct {
+	Type EventType
+
+	Content      string          // EventDelta: the text
+	Model        string          // EventMetadata: the model that served the answer
+	ID           string          // EventMetadata: the upstream ID of the answer
+	Action       string          // EventTool: the upstream action
+	Data         json.RawMessage // EventTool: the upstream payload
+	Err          error           // EventError: what went wrong
+	FinishReason string          // EventDone: one of the Finish constants
+}
+
+// errIncomplete ends a stream that stopped without saying it was done
+var errIncomplete = errors.New("the answer stopped before it was complete")
+
+// upstreamError is an error reported inside a stream, once the answer has
+// started
+type upstreamError struct {
+	Status int
+	Type   string
+}
+
+func (e *upstreamError) Error() string {
+	if e.Status != 0 {
+		return fmt.Sprintf("upstream error %s (status %d)", e.Type, e.Status)
+	}
+	return fmt.Sprintf("upstream error %s", e.Type)
+}
+
+// StreamResult sums up the events of a streamed answer
+type StreamResult struct {
+	content strings.Builder
+
+	Model        string // model that served the answer, when the upstream service said
+	ID           string
+	Tools        []StreamEvent // tool events, in order
+	Err          error         // the error that ended the answer
+	Done         bool          // a done event was received
+	FinishReason string
+}
+
+// Add records an event
+func (r *StreamResult) Add(event StreamEvent) {
+	switch event.Type {
+	case EventDelta:
+		r.content.WriteString(event.Content)
+	case EventMetadata:
+		if event.Model != "" {
+			r.Model = event.Model
+		}
+		if event.ID != "" {
+			r.ID = event.ID
+		}
+	case EventTool:
+		r.Tools = append(r.Tools, event)
+	case EventError:
+		r.Err = event.Err
+	case EventDone:
+		r.Done = true
+		r.FinishReason = event.FinishReason
+	}
+}
+
+// Content returns the text of the answer received so far
+func (r *StreamResult) Content() string {
+	return r.content.String()
+}
+
+// Truncated reports whether the answer was cut short, by an error or by the
+// output limit. An answer whose stream was cancelled is neither done nor
+// failed, and is not reported here.
+func (r *StreamResult) Truncated() bool {
+	return r.Err != nil || r.FinishReason == FinishLength
+}
+
+// collectStream reads a stream to its end
+func collectStream(events <-chan StreamEvent) *StreamResult {
+	result := &StreamResult{}
+	for event := range events {
+		result.Add(event)
+	}
+	return result
+}
+
+// sendEvent passes an event on, unless ctx is done first. It reports whether
+// the event was sent.
+func sendEvent(ctx context.Context, events chan<- StreamEvent, event StreamEvent) bool {
+	select {
+	case events <- event:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
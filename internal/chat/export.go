@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"strings"
 	"time"
+
+	"duckduckgo-chat-cli/internal/models"
 )
 
 type ExportMetadata struct {
@@ -27,6 +29,8 @@ func (c *Chat) Export(exportType string, query string) (string, string) {
 	switch exportType {
 	case "conversation":
 		content = c.formatConversation(metadata)
+	case "conversation_tree":
+		content = c.formatConversationTree(metadata)
 	case "last_response":
 		content = c.formatLastResponse(metadata)
 	case "code_block":
@@ -43,29 +47,79 @@ func (c *Chat) Export(exportType string, query string) (string, string) {
 func (c *Chat) formatConversation(metadata ExportMetadata) string {
 	var sb strings.Builder
 	writeMetadataHeader(&sb, metadata)
+	c.writeMessages(&sb, c.Messages)
+	return sb.String()
+}
+
+// formatConversationTree exports every branch of the conversation, each
+// starting where it forks from its parent
+func (c *Chat) formatConversationTree(metadata ExportMetadata) string {
+	var sb strings.Builder
+	metadata.Type = "Conversation Tree"
+	writeMetadataHeader(&sb, metadata)
 
-	for i, msg := range c.Messages {
-		timestamp := time.Now().Add(time.Duration(-len(c.Messages)+i) * time.Minute).Format("15:04")
+	branches, active := c.Branches()
+	walkBranches(branches, func(b Branch, depth int) {
+		own, from := ownMessages(b)
+		title := fmt.Sprintf("%s 🌿 Branch %s", strings.Repeat("#", min(depth+2, 6)), b.Label())
+		if b.Parent != 0 {
+			title += fmt.Sprintf(" (forked after message %d)", from)
+		}
+		if b.ID == active {
+			title += " — active"
+		}
+		sb.WriteString(title + "\n\n")
+		c.writeMessages(&sb, own)
+	})
+
+	return sb.String()
+}
+
+// writeMessages writes messages as export sections
+func (c *Chat) writeMessages(sb *strings.Builder, messages []Message) {
+	for _, msg := range messages {
+		timestamp := msg.exportTime()
+		if msg.ID != "" {
+			sb.WriteString(fmt.Sprintf("<!-- message %s -->\n", msg.ID))
+		}
 
 		switch {
 		case strings.Contains(msg.Content, "[Search Context]"):
-			writeSection(&sb, "🔍 Search Results", timestamp,
+			writeSection(sb, "🔍 Search Results", timestamp,
 				strings.TrimPrefix(msg.Content, "[Search Context]\n"))
 		case strings.Contains(msg.Content, "[File Context]"):
-			writeSection(&sb, "📄 File Content", timestamp,
+			writeSection(sb, "📄 File Content", timestamp,
 				strings.TrimPrefix(msg.Content, "[File Context]\n"))
 		case strings.Contains(msg.Content, "[URL Context]"):
-			writeSection(&sb, "🌐 Web Content", timestamp,
+			writeSection(sb, "🌐 Web Content", timestamp,
 				strings.TrimPrefix(msg.Content, "[URL Context]\n"))
 		case msg.Role == "user":
-			writeSection(&sb, "🧑 User Query", timestamp, msg.Content)
+			writeSection(sb, "🧑 User Query", timestamp, msg.Content)
 		case msg.Role == "assistant":
-			title := fmt.Sprintf("🤖 %s Response", formatModelName(string(c.Model)))
-			writeSection(&sb, title, timestamp, msg.Content)
+			if msg.Latency > 0 {
+				timestamp += ", " + formatLatency(msg.Latency)
+			}
+			writeSection(sb, c.responseTitle(msg), timestamp, msg.Content)
 		}
 	}
+}
 
-	return sb.String()
+// responseTitle is the section title of an answer, named after the model that
+// wrote it
+func (c *Chat) responseTitle(msg Message) string {
+	model := msg.Model
+	if model == "" {
+		model = c.Model
+	}
+	return fmt.Sprintf("🤖 %s Response", formatModelName(string(model)))
+}
+
+// exportTime is the time shown in the export section of a message
+func (msg Message) exportTime() string {
+	if msg.Timestamp.IsZero() {
+		return time.Now().Format("15:04")
+	}
+	return msg.Timestamp.Format("15:04")
 }
 
 func (c *Chat) formatLastResponse(metadata ExportMetadata) string {
@@ -75,8 +129,7 @@ func (c *Chat) formatLastResponse(metadata ExportMetadata) string {
 
 	lastMsg := findLastAssistantMessage(c.Messages)
 	if lastMsg != nil {
-		title := fmt.Sprintf("🤖 %s Response", formatModelName(string(c.Model)))
-		writeSection(&sb, title, time.Now().Format("15:04"), lastMsg.Content)
+		writeSection(&sb, c.responseTitle(*lastMsg), lastMsg.exportTime(), lastMsg.Content)
 	}
 
 	return sb.String()
@@ -104,7 +157,7 @@ func (c *Chat) formatSearchResults(metadata ExportMetadata, query string) string
 
 	for _, msg := range c.Messages {
 		if strings.Contains(msg.Content, "[Search Context]") {
-			writeSection(&sb, "📊 Results", time.Now().Format("15:04"),
+			writeSection(&sb, "📊 Results", msg.exportTime(),
 				strings.TrimPrefix(msg.Content, "[Search Context]\n"))
 			break
 		}
@@ -129,15 +182,14 @@ func (c *Chat) formatSearchInConversation(metadata ExportMetadata, searchText st
 
 			// Ajouter le contexte (question et réponse)
 			if msg.Role == "user" && i+1 < len(c.Messages) {
-				writeSection(&sb, "🧑 User Message", time.Now().Format("15:04"), msg.Content)
-				if c.Messages[i+1].Role == "assistant" {
-					title := fmt.Sprintf("🤖 %s Response", formatModelName(string(c.Model)))
-					writeSection(&sb, title, time.Now().Format("15:04"), c.Messages[i+1].Content)
+				writeSection(&sb, "🧑 User Message", msg.exportTime(), msg.Content)
+				if answer := c.Messages[i+1]; answer.Role == "assistant" {
+					writeSection(&sb, c.responseTitle(answer), answer.exportTime(), answer.Content)
 				}
 			} else if msg.Role == "assistant" && i > 0 {
-				writeSection(&sb, "🧑 User Message", time.Now().Format("15:04"), c.Messages[i-1].Content)
-				title := fmt.Sprintf("🤖 %s Response", formatModelName(string(c.Model)))
-				writeSection(&sb, title, time.Now().Format("15:04"), msg.Content)
+				question := c.Messages[i-1]
+				writeSection(&sb, "🧑 User Message", question.exportTime(), question.Content)
+				writeSection(&sb, c.responseTitle(msg), msg.exportTime(), msg.Content)
 			}
 		}
 	}
@@ -184,16 +236,5 @@ func sanitizeFilename(name string) string {
 }
 
 func formatModelName(modelName string) string {
-	displayNames := map[string]string{
-		"gpt-4o-mini":                             "GPT-4o mini",
-		"claude-3-haiku-20240307":                 "Claude 3 Haiku",
-		"meta-llama/Llama-3.3-70B-Instruct-Turbo": "Llama 3.3 70B",
-		"mistralai/Mixtral-8x7B-Instruct-v0.1":    "Mistral 8x7B",
-		"o3-mini":                                 "o3-mini",
-	}
-
-	if shortName, exists := displayNames[modelName]; exists {
-		return shortName
-	}
-	return modelName
+	return models.DisplayName(models.Model(modelName))
 }
@@ -1,15 +1,15 @@
 package chat
 
 import (
+	"context"
 	"duckduckgo-chat-cli/internal/chatcontext"
 	"duckduckgo-chat-cli/internal/config"
 	"duckduckgo-chat-cli/internal/ui"
-	"fmt"
 	"os"
 	"strings"
 )
 
-func HandleFileCommand(c *Chat, input string, cfg *config.Config, chainCtx *chatcontext.Context) {
+func HandleFileCommand(ctx context.Context, c *Chat, input string, cfg *config.Config, chainCtx *chatcontext.Context) {
 	var path, userRequest string
 	var err error
 
@@ -52,27 +52,29 @@ func HandleFileCommand(c *Chat, input string, cfg *config.Config, chainCtx *chat
 		ui.AIln("Successfully added content from file to chain context: %s", path)
 	} else {
 		ui.Warningln("Adding file content: %s", path)
-		c.addFileContext(path, content)
+		c.AddFileContext(path, content)
 		ui.AIln("Successfully added content from file: %s", path)
 		// If user provided a specific request, process it with the file context
 		if userRequest != "" {
 			ui.Systemln("Processing your request about the file...")
-			ProcessInput(c, userRequest, cfg)
+			ProcessInput(ctx, c, userRequest, cfg)
 		} else {
 			ui.Warningln("File content added to context. You can now ask questions about it.")
 		}
 	}
 }
 
-func (c *Chat) addFileContext(path string, content []byte) {
+// AddFileContext adds file content to the conversation as a [File Context] message
+func (c *Chat) AddFileContext(path string, content []byte) {
 	contentLength := len(content)
 	if contentLength > 500 {
 		ui.AIln("Adding %d characters from file", contentLength)
 	}
 
-	c.Messages = append(c.Messages, Message{
+	c.appendMessage(Message{
 		Role:    "user",
-		Content: fmt.Sprintf("[File Context]\nFile: %s\n\n%s", path, string(content)),
+		Content: chatcontext.FormatFile(path, content),
+		Source:  SourceFile,
 	})
 
 	if c.Analytics != nil {
@@ -8,20 +8,67 @@ import (
 	"github.com/fatih/color"
 )
 
-func PrintHistory(c *Chat) {
+// PrintHistory prints the active branch of the conversation, or the whole
+// conversation tree when args is "tree"
+func PrintHistory(c *Chat, args string) {
+	if strings.TrimSpace(args) == "tree" {
+		printHistoryTree(c)
+		return
+	}
+
 	if len(c.Messages) == 0 {
 		color.Yellow("No messages in history yet")
 		return
 	}
 
+	// add a newline before printing the history
+	fmt.Println()
+	printMessages(c.Messages, true)
+	fmt.Println()
+}
+
+// printHistoryTree prints every branch, each starting where it forks from its
+// parent
+func printHistoryTree(c *Chat) {
+	branches, active := c.Branches()
+	if len(branches) == 1 {
+		color.Yellow("This conversation has a single branch")
+		PrintHistory(c, "")
+		return
+	}
+
+	header := color.New(color.FgHiCyan, color.Bold)
+	walkBranches(branches, func(b Branch, depth int) {
+		own, from := ownMessages(b)
+		title := fmt.Sprintf("%s🌿 Branch %s", strings.Repeat("  ", depth), b.Label())
+		if b.Parent != 0 {
+			title += fmt.Sprintf(" (forked after message %d)", from)
+		}
+		if b.ID == active {
+			title += " [active]"
+		}
+		fmt.Println()
+		header.Println(title)
+		if len(own) == 0 {
+			color.New(color.Faint).Println("(no messages of its own)")
+			return
+		}
+		fmt.Println()
+		printMessages(own, false)
+	})
+	fmt.Println()
+}
+
+// printMessages prints messages in the history style. When numbered, the
+// user's messages carry the number /edit refers to them by.
+func printMessages(messages []Message, numbered bool) {
 	dimWhite := color.New(color.FgHiWhite, color.Faint)
 	dimBlue := color.New(color.FgBlue, color.Faint)
 	dimGreen := color.New(color.FgHiGreen, color.Faint)
 	dimYellow := color.New(color.FgYellow, color.Faint)
 
-	// add a newline before printing the history
-	fmt.Println()
-	for i, msg := range c.Messages {
+	turn := 0
+	for i, msg := range messages {
 		switch {
 		case strings.HasPrefix(msg.Content, "[Search Context]"):
 			dimYellow.Print("🔍 Search Context: ")
@@ -32,6 +79,10 @@ func PrintHistory(c *Chat) {
 		case strings.HasPrefix(msg.Content, "[URL Context]"):
 			dimYellow.Print("🌐 URL Context: ")
 			dimWhite.Println(strings.TrimSpace(strings.TrimPrefix(msg.Content, "[URL Context]")))
+		case msg.Role == "user" && numbered && !isContextMessage(msg):
+			turn++
+			dimBlue.Printf("You [%d]: ", turn)
+			dimWhite.Println(msg.Content)
 		case msg.Role == "user":
 			dimBlue.Print("You: ")
 			dimWhite.Println(msg.Content)
@@ -41,13 +92,51 @@ func PrintHistory(c *Chat) {
 		default:
 			dimWhite.Println(msg.Content)
 		}
+		if details := messageDetails(msg); details != "" {
+			color.New(color.Faint).Printf("   %s\n", details)
+		}
 
 		// add a newline between messages
-		if i < len(c.Messages)-1 {
+		if i < len(messages)-1 {
 			fmt.Println()
 		}
 	}
-	fmt.Println()
+}
+
+// messageDetails describes when and how a message was written: its time, and
+// the source of a user message or the model and latency of an answer
+func messageDetails(msg Message) string {
+	var details []string
+	if !msg.Timestamp.IsZero() {
+		details = append(details, formatMessageTime(msg.Timestamp))
+	}
+	if msg.Role == "user" && msg.Source != "" {
+		details = append(details, msg.Source)
+	}
+	if msg.Model != "" {
+		details = append(details, shortenModelName(string(msg.Model)))
+	}
+	if msg.Latency > 0 {
+		details = append(details, formatLatency(msg.Latency))
+	}
+	return strings.Join(details, " · ")
+}
+
+// formatMessageTime shows the time of a message, with its date unless it is
+// today
+func formatMessageTime(t time.Time) string {
+	if y, m, d := t.Date(); y == time.Now().Year() && m == time.Now().Month() && d == time.Now().Day() {
+		return t.Format("15:04:05")
+	}
+	return t.Format("2006-01-02 15:04")
+}
+
+// formatLatency shows how long an answer took
+func formatLatency(d time.Duration) string {
+	if d < time.Second {
+		return fmt.Sprintf("%dms", d.Milliseconds())
+	}
+	return fmt.Sprintf("%.1fs", d.Seconds())
 }
 
 // GetMarkdownContent exports the chat history in markdown format
@@ -61,8 +150,8 @@ func (c *Chat) GetMarkdownContent() string {
 	md.WriteString("---\n\n")
 	md.WriteString("# DuckDuckGo AI Chat Export\n\n")
 
-	for i, msg := range c.Messages {
-		timestamp := time.Now().Add(time.Duration(-len(c.Messages)+i) * time.Minute).Format("15:04")
+	for _, msg := range c.Messages {
+		timestamp := msg.exportTime()
 		switch {
 		case strings.Contains(msg.Content, "[Search Context]"):
 			md.WriteString(fmt.Sprintf("### 🔍 Search Context (%s)\n\n", timestamp))
@@ -78,7 +167,10 @@ func (c *Chat) GetMarkdownContent() string {
 				md.WriteString(fmt.Sprintf("### 🧑 User Query (%s)\n\n", timestamp))
 				md.WriteString(msg.Content + "\n")
 			} else {
-				md.WriteString(fmt.Sprintf("### 🤖 AI Response (%s)\n\n", timestamp))
+				if msg.Latency > 0 {
+					timestamp += ", " + formatLatency(msg.Latency)
+				}
+				md.WriteString(fmt.Sprintf("### %s (%s)\n\n", c.responseTitle(msg), timestamp))
 				md.WriteString(msg.Content + "\n")
 			}
 		}
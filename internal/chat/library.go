@@ -1,6 +1,8 @@
 package chat
 
 import (
+	"context"
+	"duckduckgo-chat-cli/internal/chatcontext"
 	"duckduckgo-chat-cli/internal/config"
 	"duckduckgo-chat-cli/internal/ui"
 	"fmt"
@@ -65,7 +67,7 @@ type FileInfo struct {
 }
 
 // HandleLibraryCommand processes the /library command
-func HandleLibraryCommand(c *Chat, input string, cfg *config.Config) {
+func HandleLibraryCommand(ctx context.Context, c *Chat, input string, cfg *config.Config) {
 	commandInput := strings.TrimSpace(strings.TrimPrefix(input, "/library"))
 
 	var subCommand, argument, userRequest string
@@ -104,7 +106,7 @@ func HandleLibraryCommand(c *Chat, input string, cfg *config.Config) {
 	case "remove", "rm":
 		handleLibraryRemove(cfg)
 	case "load":
-		handleLibraryLoad(c, cfg, argument, userRequest)
+		handleLibraryLoad(ctx, c, cfg, argument, userRequest)
 	case "search":
 		handleLibrarySearch(cfg, argument)
 	case "help":
@@ -400,7 +402,7 @@ func handleLibrarySearch(cfg *config.Config, argument string) {
 }
 
 // handleLibraryLoad loads all files from a library into context
-func handleLibraryLoad(c *Chat, cfg *config.Config, argument string, userRequest string) {
+func handleLibraryLoad(ctx context.Context, c *Chat, cfg *config.Config, argument string, userRequest string) {
 	if len(cfg.Library.Directories) == 0 {
 		ui.Warningln("No libraries configured. Use '/library add <path>' to add one.")
 		return
@@ -436,15 +438,8 @@ func handleLibraryLoad(c *Chat, cfg *config.Config, argument string, userRequest
 			ui.Errorln("Failed to read file %s: %v", file, err)
 			continue
 		}
-		c.Messages = append(c.Messages, Message{
-			Role:    "user",
-			Content: fmt.Sprintf("[File Context]\nFile: %s\n\n%s", file, string(content)),
-		})
+		c.AddLibraryFile(file, content)
 		totalChars += len(content)
-
-		if c.Analytics != nil {
-			c.Analytics.RecordFileProcessed()
-		}
 	}
 
 	ui.AIln("✅ Added %d files (%d characters) to context.", len(files), totalChars)
@@ -452,12 +447,97 @@ func handleLibraryLoad(c *Chat, cfg *config.Config, argument string, userRequest
 	// If user provided a specific request, process it
 	if userRequest != "" {
 		ui.Systemln("Processing your request about the files...")
-		ProcessInput(c, userRequest, cfg)
+		ProcessInput(ctx, c, userRequest, cfg)
 	} else {
 		ui.Warningln("File contents added to context.")
 	}
 }
 
+// AddLibraryFile adds the content of a library file to the conversation.
+func (c *Chat) AddLibraryFile(path string, content []byte) {
+	c.appendMessage(Message{
+		Role:    "user",
+		Content: chatcontext.FormatFile(path, content),
+		Source:  SourceLibrary,
+	})
+
+	if c.Analytics != nil {
+		c.Analytics.RecordFileProcessed()
+	}
+}
+
+// LibraryPath returns the directory of the library chosen by name or number,
+// joined with an optional subdirectory that must stay inside the library.
+// It never prompts, so the API can use it.
+func LibraryPath(cfg *config.Config, library string, subdir string) (string, error) {
+	if !cfg.Library.Enabled {
+		return "", fmt.Errorf("library system is disabled")
+	}
+	if library == "" {
+		return "", fmt.Errorf("library name or number is required")
+	}
+	dir, err := selectLibrary(cfg, library)
+	if err != nil {
+		return "", err
+	}
+	if subdir == "" {
+		return dir, nil
+	}
+
+	path := filepath.Join(dir, subdir)
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("path is outside the library: %s", subdir)
+	}
+	return path, nil
+}
+
+// LibraryFiles returns the supported files of the library chosen by name or
+// number. When names are given, only the files whose path relative to the
+// library matches one of them are returned, and each name must match a file.
+func LibraryFiles(cfg *config.Config, library string, names []string) ([]string, error) {
+	dir, err := LibraryPath(cfg, library, "")
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := make(map[string]bool, len(names))
+	for _, name := range names {
+		wanted[filepath.ToSlash(filepath.Clean(name))] = false
+	}
+
+	var files []string
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if !SupportedExtensions[strings.ToLower(filepath.Ext(path))] {
+			return nil
+		}
+		if len(wanted) > 0 {
+			rel, _ := filepath.Rel(dir, path)
+			rel = filepath.ToSlash(rel)
+			if _, ok := wanted[rel]; !ok {
+				return nil
+			}
+			wanted[rel] = true
+		}
+		files = append(files, path)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for name, found := range wanted {
+		if !found {
+			return nil, fmt.Errorf("file not found in library %s: %s", getLibraryName(dir), name)
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
 // selectLibrary allows the user to choose a library interactively or by name/number.
 func selectLibrary(cfg *config.Config, argument string) (string, error) {
 	if argument != "" {
@@ -1,6 +1,8 @@
 package chat
 
 import (
+	"context"
+	"duckduckgo-chat-cli/internal/chatcontext"
 	"duckduckgo-chat-cli/internal/config"
 	"fmt"
 	"os"
@@ -14,7 +16,7 @@ import (
 )
 
 // HandlePMPCommand processes the /pmp command for project prompt generation
-func HandlePMPCommand(c *Chat, input string, cfg *config.Config) {
+func HandlePMPCommand(ctx context.Context, c *Chat, input string, cfg *config.Config) {
 	commandInput := strings.TrimPrefix(input, "/pmp")
 	commandInput = strings.TrimSpace(commandInput)
 
@@ -78,7 +80,11 @@ func HandlePMPCommand(c *Chat, input string, cfg *config.Config) {
 	color.Yellow("🔄 Generating project prompt for: %s", path)
 
 	// Execute PMP command
-	prompt, err := executePMP(path, args)
+	prompt, err := executePMP(ctx, path, args)
+	if ctx.Err() != nil {
+		color.Yellow("Request canceled")
+		return
+	}
 	if err != nil {
 		color.Red("❌ Error executing PMP: %v", err)
 		return
@@ -90,22 +96,48 @@ func HandlePMPCommand(c *Chat, input string, cfg *config.Config) {
 	}
 
 	// Add the generated prompt to context
-	c.Messages = append(c.Messages, Message{
-		Role:    "user",
-		Content: fmt.Sprintf("[Project Context - Generated by PMP]\nPath: %s\n\n%s", path, prompt),
-	})
+	c.AddProjectContext(path, prompt)
 
 	color.Green("✅ Project prompt added to context (%d characters)", len(prompt))
 
 	// If user provided a specific request, process it with the project context
 	if userRequest != "" {
 		color.Cyan("Processing your request about the project...")
-		ProcessInput(c, userRequest, cfg)
+		ProcessInput(ctx, c, userRequest, cfg)
 	} else {
 		color.White("Project structure and code added to context. You can now ask questions about the project.")
 	}
 }
 
+// GenerateProjectPrompt runs PMP on path and returns the generated prompt.
+// Unlike /pmp it never offers to install PMP, and cancelling ctx stops it.
+func GenerateProjectPrompt(ctx context.Context, path string) (string, error) {
+	if !isPMPInstalled() && pmpExecutablePath == "" {
+		return "", fmt.Errorf("PMP (Prompt My Project) is not installed")
+	}
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("path does not exist: %s", path)
+	}
+
+	prompt, err := executePMP(ctx, path, nil)
+	if err != nil {
+		return "", err
+	}
+	if prompt == "" {
+		return "", fmt.Errorf("no prompt generated (empty output)")
+	}
+	return prompt, nil
+}
+
+// AddProjectContext adds a prompt generated by PMP to the conversation.
+func (c *Chat) AddProjectContext(path string, prompt string) {
+	c.appendMessage(Message{
+		Role:    "user",
+		Content: chatcontext.FormatProject(path, prompt),
+		Source:  SourcePMP,
+	})
+}
+
 // isPMPInstalled checks if PMP is available in the system PATH
 func isPMPInstalled() bool {
 	_, err := exec.LookPath("pmp")
@@ -286,7 +318,7 @@ func validatePMPExecutable(pmpExe string) error {
 }
 
 // executePMP runs the PMP command and returns the generated prompt
-func executePMP(path string, args []string) (string, error) {
+func executePMP(ctx context.Context, path string, args []string) (string, error) {
 	// Build the command arguments
 	cmdArgs := []string{"--format", "stdout"}
 	cmdArgs = append(cmdArgs, args...)
@@ -300,8 +332,11 @@ func executePMP(path string, args []string) (string, error) {
 
 	// Execute PMP using the validated executable path
 	// #nosec G204 - pmpExe is validated above to ensure it's a legitimate PMP executable
-	cmd := exec.Command(pmpExe, cmdArgs...)
+	cmd := exec.CommandContext(ctx, pmpExe, cmdArgs...)
 	output, err := cmd.Output()
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
 	if err != nil {
 		return "", fmt.Errorf("pmp execution failed: %v", err)
 	}
@@ -0,0 +1,116 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"duckduckgo-chat-cli/internal/config"
+	"duckduckgo-chat-cli/internal/models"
+	"duckduckgo-chat-cli/internal/ui"
+
+	"github.com/AlecAivazis/survey/v2"
+)
+
+// Provider is an upstream chat service. Chat keeps the conversation, context
+// and history; the provider only sends the conversation and streams back the
+// answer, so every command works the same whichever provider is selected.
+type Provider interface {
+	// Name identifies the provider, as in the configuration
+	Name() string
+	// Stream sends the conversation of c to its current model and streams
+	// the answer as events
+	Stream(ctx context.Context, c *Chat) (*Response, error)
+	// Models lists the models the provider can serve
+	Models(ctx context.Context) ([]string, error)
+	// Reset drops the upstream state tied to the conversation of c. It is
+	// called when the conversation is cleared.
+	Reset(c *Chat)
+}
+
+// Response is an answer being streamed by a provider
+type Response struct {
+	// Events receives the answer piece by piece, with what the upstream
+	// service says about it. It is closed after a done or an error event, or
+	// when the context is done.
+	Events <-chan StreamEvent
+}
+
+// newResponse returns a Response and the channel its provider writes to
+func newResponse() (*Response, chan StreamEvent) {
+	events := make(chan StreamEvent)
+	return &Response{Events: events}, events
+}
+
+// NewProvider returns the provider selected in the configuration
+func NewProvider(cfg *config.Config) (Provider, error) {
+	switch cfg.Provider.Type {
+	case "", config.ProviderDuckDuckGo:
+		return newDuckDuckGoProvider(cfg.Network), nil
+	case config.ProviderOpenAI:
+		if cfg.Provider.BaseURL == "" {
+			return nil, fmt.Errorf("the %s provider needs a base URL", config.ProviderOpenAI)
+		}
+		return newOpenAIProvider(cfg.Provider.BaseURL, cfg.Provider.APIKey, cfg.Network), nil
+	default:
+		return nil, fmt.Errorf("unknown provider %q", cfg.Provider.Type)
+	}
+}
+
+// ResolveModelName maps a model name given by the user or an API client onto
+// a model of the session's provider. DuckDuckGo names go through the model
+// registry; other providers get the name as it is, since only their server
+// knows its models.
+func ResolveModelName(c *Chat, name string) (models.Model, bool) {
+	if c.Provider.Name() != config.ProviderDuckDuckGo {
+		return models.Model(name), true
+	}
+	_, model, ok := models.LookupModel(name)
+	return model, ok
+}
+
+// ConfiguredModel returns the model new conversations start with: the
+// provider's model, or the default DuckDuckGo model
+func ConfiguredModel(cfg *config.Config) models.Model {
+	if cfg.Provider.Type == config.ProviderOpenAI {
+		return models.Model(cfg.Provider.Model)
+	}
+	return models.GetModel(cfg.DefaultModel)
+}
+
+// HandleProviderModelCommand changes the model of a session that does not use
+// the DuckDuckGo provider, either to the given name or to one picked from the
+// models listed by the provider.
+func HandleProviderModelCommand(c *Chat, name string, cfg *config.Config) {
+	if name == "" {
+		available, err := c.Provider.Models(context.Background())
+		if err != nil {
+			ui.Errorln("Cannot list models: %v", err)
+			return
+		}
+		if len(available) == 0 {
+			ui.Warningln("The %s provider did not list any model. Use /model <name>.", c.Provider.Name())
+			return
+		}
+
+		prompt := &survey.Select{
+			Message: "Choose a new model:",
+			Options: available,
+		}
+		for _, model := range available {
+			if model == string(c.CurrentModel()) {
+				prompt.Default = model
+			}
+		}
+		if err := survey.AskOne(prompt, &name, survey.WithStdio(os.Stdin, os.Stdout, os.Stderr)); err != nil {
+			ui.Warningln("Model change canceled")
+			return
+		}
+	}
+
+	c.ChangeModel(models.Model(name))
+	cfg.Provider.Model = name
+	if err := config.SaveConfig(cfg); err != nil {
+		ui.Errorln("Failed to save config: %v", err)
+	}
+}
@@ -0,0 +1,316 @@
+package chat
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"duckduckgo-chat-cli/internal/config"
+	"duckduckgo-chat-cli/internal/fingerprint"
+	"duckduckgo-chat-cli/internal/models"
+	"duckduckgo-chat-cli/internal/ui"
+
+	"github.com/fatih/color"
+)
+
+// duckDuckGoProvider sends conversations to DuckDuckGo AI Chat. Its upstream
+// state (VQD and browser headers) is kept on the Chat, as each conversation
+// needs its own.
+type duckDuckGoProvider struct {
+	upstream upstream
+	breaker  *circuitBreaker
+}
+
+// duckDuckGoBreaker pauses every session's requests once DuckDuckGo keeps
+// answering 418, as retrying only prolongs the block
+var duckDuckGoBreaker = &circuitBreaker{}
+
+func newDuckDuckGoProvider(network config.NetworkConfig) *duckDuckGoProvider {
+	duckDuckGoBreaker.configure(network)
+	return &duckDuckGoProvider{upstream: newUpstream(network), breaker: duckDuckGoBreaker}
+}
+
+func (p *duckDuckGoProvider) Name() string {
+	return config.ProviderDuckDuckGo
+}
+
+func (p *duckDuckGoProvider) Models(ctx context.Context) ([]string, error) {
+	aliases := models.Aliases()
+	names := make([]string, len(aliases))
+	for i, alias := range aliases {
+		names[i] = string(alias)
+	}
+	return names, nil
+}
+
+// Reset fetches a fresh VQD, so the cleared conversation starts anew upstream
+func (p *duckDuckGoProvider) Reset(c *Chat) {
+	if err := c.refreshVQD(); err != nil {
+		ui.Errorln("%v", err)
+	}
+}
+
+func (p *duckDuckGoProvider) Stream(ctx context.Context, c *Chat) (*Response, error) {
+	resp, err := p.fetch(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+
+	response, events := newResponse()
+	go func() {
+		defer resp.Body.Close()
+		defer close(events)
+
+		var model, id string
+		scanner := bufio.NewScanner(resp.Body)
+		// Tool results can come as long lines
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+		for scanner.Scan() {
+			data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+			if !ok {
+				continue
+			}
+			if data == "[DONE]" {
+				sendEvent(ctx, events, StreamEvent{Type: EventDone, FinishReason: FinishStop})
+				return
+			}
+
+			var messageData struct {
+				Role    string `json:"role"`
+				Message string `json:"message"`
+				Created int64  `json:"created"`
+				ID      string `json:"id"`
+				Action  string `json:"action"`
+				Model   string `json:"model"`
+				Status  int    `json:"status"` // set on errors
+				Type    string `json:"type"`   // set on errors
+			}
+			if err := json.Unmarshal([]byte(data), &messageData); err != nil {
+				log.Printf("Error unmarshaling data: %v\n", err)
+				continue
+			}
+
+			if messageData.Action == "error" {
+				sendEvent(ctx, events, StreamEvent{Type: EventError, Err: &upstreamError{Status: messageData.Status, Type: messageData.Type}})
+				return
+			}
+
+			// Every line repeats the model and ID: pass them on when they change
+			if (messageData.Model != "" && messageData.Model != model) || (messageData.ID != "" && messageData.ID != id) {
+				model = cmp.Or(messageData.Model, model)
+				id = cmp.Or(messageData.ID, id)
+				if !sendEvent(ctx, events, StreamEvent{Type: EventMetadata, Model: model, ID: id}) {
+					return
+				}
+			}
+
+			if messageData.Action != "" && messageData.Action != "success" {
+				if !sendEvent(ctx, events, StreamEvent{Type: EventTool, Action: messageData.Action, Data: json.RawMessage(data)}) {
+					return
+				}
+			}
+
+			if messageData.Message != "" {
+				if !sendEvent(ctx, events, StreamEvent{Type: EventDelta, Content: messageData.Message}) {
+					return
+				}
+			}
+		}
+
+		err := scanner.Err()
+		if err == nil {
+			err = errIncomplete
+		}
+		sendEvent(ctx, events, StreamEvent{Type: EventError, Err: err})
+	}()
+
+	return response, nil
+}
+
+// fetch sends the conversation to DuckDuckGo and returns the streaming
+// response. On 418, 429 and invalid VQD errors it refreshes the VQD and
+// retries with an exponential backoff, honouring Retry-After. Repeated 418s
+// open the circuit breaker, which fails requests at once for a while.
+func (p *duckDuckGoProvider) fetch(ctx context.Context, c *Chat) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		if err := p.breaker.allow(); err != nil {
+			return nil, err
+		}
+
+		resp, err := p.send(ctx, c)
+		if err == nil {
+			p.breaker.success()
+			c.mu.Lock()
+			c.RetryCount = 0
+			c.mu.Unlock()
+			return resp, nil
+		}
+
+		var statusErr *statusError
+		if !errors.As(err, &statusErr) || !isRetryable(statusErr) {
+			return nil, err
+		}
+
+		if statusErr.Status == 418 && p.breaker.failure() {
+			ui.Errorln("⛔ DuckDuckGo keeps refusing requests, pausing them for a while")
+			return nil, err
+		}
+		if attempt > p.upstream.maxRetries || statusErr.RetryAfter > maxRetryAfter {
+			return nil, err
+		}
+
+		delay := p.upstream.retryDelay(attempt, statusErr.RetryAfter)
+		ui.Warningln("🔄 Error %d detected, retrying in %s (attempt %d/%d)...", statusErr.Status, delay.Round(100*time.Millisecond), attempt, p.upstream.maxRetries)
+		if err := wait(ctx, delay); err != nil {
+			return nil, err
+		}
+		c.Analytics.RecordUpstreamRetry(statusErr.Status)
+
+		// Refresh ONLY VQD on errors, like the PowerShell script
+		if err := c.refreshVQD(); err != nil {
+			ui.Errorln("%v", err)
+		} else {
+			c.mu.RLock()
+			newVqd := c.NewVqd
+			c.mu.RUnlock()
+			ui.AIln("✅ Refreshed VQD: %s...", newVqd[:min(50, len(newVqd))])
+		}
+		c.mu.Lock()
+		c.RetryCount = attempt
+		c.mu.Unlock()
+		c.Analytics.RecordVQDRefresh()
+	}
+}
+
+// isRetryable reports whether DuckDuckGo may accept the request once the VQD
+// is refreshed
+func isRetryable(err *statusError) bool {
+	return err.Status == 418 || err.Status == 429 || strings.Contains(err.Body, "ERR_INVALID_VQD")
+}
+
+// send makes one attempt at sending the conversation. Non-200 answers are
+// returned as a *statusError.
+func (p *duckDuckGoProvider) send(ctx context.Context, c *Chat) (*http.Response, error) {
+	if c.NewVqd == "" {
+		if err := c.refreshVQD(); err != nil {
+			return nil, err
+		}
+	}
+
+	// VQD hash is now initialized during chat creation
+
+	payload := ChatPayload{
+		Model: c.Model,
+		Metadata: Metadata{
+			ToolChoice: ToolChoice{
+				NewsSearch:      false,
+				VideosSearch:    false,
+				LocalSearch:     false,
+				WeatherForecast: false,
+			},
+		},
+		Messages:             c.Messages,
+		CanUseTools:          true,
+		CanUseApproxLocation: true,
+	}
+
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling payload: %v", err)
+	}
+
+	if os.Getenv("DEBUG") == "true" {
+		color.Cyan("Payload: %s", string(jsonPayload))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", models.ChatURL, bytes.NewBuffer(jsonPayload))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %v", err)
+	}
+
+	// Set ALL required headers EXACTLY like the real web browser request
+	profile := fingerprint.Current()
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Accept-Encoding", "gzip, deflate, br, zstd")
+	req.Header.Set("Authority", "duckduckgo.com")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("DNT", "1")
+	req.Header.Set("Method", "POST")
+	req.Header.Set("Origin", "https://duckduckgo.com")
+	req.Header.Set("Path", "/duckchat/v1/chat")
+	req.Header.Set("Priority", "u=1, i")
+	req.Header.Set("Referer", "https://duckduckgo.com/")
+	req.Header.Set("Scheme", "https")
+	req.Header.Set("Sec-Fetch-Dest", "empty")
+	req.Header.Set("Sec-Fetch-Mode", "cors")
+	req.Header.Set("Sec-Fetch-Site", "same-origin")
+	req.Header.Set("Sec-GPC", "1")
+	profile.SetHeaders(req.Header)
+
+	// ALL VQD-related headers from the real web browser request
+	req.Header.Set("x-vqd-4", c.NewVqd)
+	if c.FeSignals != "" {
+		req.Header.Set("x-fe-signals", c.FeSignals)
+	}
+	if c.FeVersion != "" {
+		req.Header.Set("x-fe-version", c.FeVersion)
+	}
+	if c.VqdHash1 != "" {
+		req.Header.Set("x-vqd-hash-1", c.VqdHash1)
+	}
+
+	// Once sent, the VQD is used up whatever the answer
+	c.mu.Lock()
+	c.unsentVQD = vqdHeaders{}
+	c.mu.Unlock()
+
+	resp, err := p.upstream.do(c.Client, req)
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if os.Getenv("DEBUG") == "true" {
+			color.Red("Request Headers: %+v", req.Header)
+			color.Red("Response Headers: %+v", resp.Header)
+			color.Red("Response Status: %d", resp.StatusCode)
+			color.Red("Response Body: %s", string(body))
+		}
+
+		if resp.StatusCode == http.StatusTeapot {
+			record := fingerprint.RecordBlocked(profile)
+			ui.Warningln("🫖 418 with fingerprint profile %s (%d since %s)", profile.Label(), record.Count, record.First.Format("2006-01-02"))
+		}
+		return nil, &statusError{
+			Status:     resp.StatusCode,
+			Text:       resp.Status,
+			Body:       string(body),
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		}
+	}
+
+	// The answer brings the next VQD
+	newVqd := resp.Header.Get("x-vqd-4")
+	if newVqd != "" {
+		c.mu.Lock()
+		c.OldVqd = c.NewVqd
+		c.NewVqd = newVqd
+		c.mu.Unlock()
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,239 @@
+package chat
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"duckduckgo-chat-cli/internal/config"
+	"duckduckgo-chat-cli/internal/ui"
+)
+
+// openAIProvider sends conversations to an OpenAI-compatible chat completions
+// API, such as a local llama.cpp, vLLM or Ollama server
+type openAIProvider struct {
+	baseURL  string
+	apiKey   string
+	client   *http.Client
+	upstream upstream
+}
+
+func newOpenAIProvider(baseURL, apiKey string, network config.NetworkConfig) *openAIProvider {
+	u := newUpstream(network)
+	return &openAIProvider{
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		apiKey:   apiKey,
+		client:   &http.Client{Transport: u.transport(http.DefaultTransport)},
+		upstream: u,
+	}
+}
+
+func (p *openAIProvider) Name() string {
+	return config.ProviderOpenAI
+}
+
+// Reset does nothing: the server keeps no state between requests
+func (p *openAIProvider) Reset(c *Chat) {}
+
+func (p *openAIProvider) Models(ctx context.Context) ([]string, error) {
+	req, err := p.newRequest(ctx, "GET", "/models", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var list struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("error decoding model list: %v", err)
+	}
+
+	names := make([]string, len(list.Data))
+	for i, model := range list.Data {
+		names[i] = model.ID
+	}
+	return names, nil
+}
+
+func (p *openAIProvider) Stream(ctx context.Context, c *Chat) (*Response, error) {
+	payload := struct {
+		Model    string    `json:"model,omitempty"`
+		Messages []Message `json:"messages"`
+		Stream   bool      `json:"stream"`
+	}{
+		Model:    string(c.Model),
+		Messages: c.Messages,
+		Stream:   true,
+	}
+
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling payload: %v", err)
+	}
+
+	resp, err := p.post(ctx, c, "/chat/completions", jsonPayload)
+	if err != nil {
+		return nil, err
+	}
+
+	response, events := newResponse()
+	go func() {
+		defer resp.Body.Close()
+		defer close(events)
+
+		var model, id, finishReason string
+		scanner := bufio.NewScanner(resp.Body)
+		// Chunks can carry long lines of generated text
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+		for scanner.Scan() {
+			data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+			if !ok {
+				continue
+			}
+			if data == "[DONE]" {
+				sendEvent(ctx, events, StreamEvent{Type: EventDone, FinishReason: cmp.Or(finishReason, FinishStop)})
+				return
+			}
+
+			var chunk struct {
+				ID      string `json:"id"`
+				Model   string `json:"model"`
+				Choices []struct {
+					Delta struct {
+						Content   string          `json:"content"`
+						ToolCalls json.RawMessage `json:"tool_calls"`
+					} `json:"delta"`
+					FinishReason string `json:"finish_reason"`
+				} `json:"choices"`
+				Error *struct {
+					Message string `json:"message"`
+					Type    string `json:"type"`
+				} `json:"error"`
+			}
+			if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+				sendEvent(ctx, events, StreamEvent{Type: EventError, Err: fmt.Errorf("error decoding chunk: %v", err)})
+				return
+			}
+
+			if chunk.Error != nil {
+				sendEvent(ctx, events, StreamEvent{Type: EventError, Err: fmt.Errorf("upstream error %s: %s", chunk.Error.Type, chunk.Error.Message)})
+				return
+			}
+
+			if (chunk.Model != "" && chunk.Model != model) || (chunk.ID != "" && chunk.ID != id) {
+				model = cmp.Or(chunk.Model, model)
+				id = cmp.Or(chunk.ID, id)
+				if !sendEvent(ctx, events, StreamEvent{Type: EventMetadata, Model: model, ID: id}) {
+					return
+				}
+			}
+
+			for _, choice := range chunk.Choices {
+				if choice.FinishReason != "" {
+					finishReason = choice.FinishReason
+				}
+				if len(choice.Delta.ToolCalls) > 0 && string(choice.Delta.ToolCalls) != "null" {
+					if !sendEvent(ctx, events, StreamEvent{Type: EventTool, Action: "tool_calls", Data: choice.Delta.ToolCalls}) {
+						return
+					}
+				}
+				if choice.Delta.Content == "" {
+					continue
+				}
+				if !sendEvent(ctx, events, StreamEvent{Type: EventDelta, Content: choice.Delta.Content}) {
+					return
+				}
+			}
+		}
+
+		err := scanner.Err()
+		if err == nil && finishReason != "" {
+			// Some servers end the stream without [DONE]
+			sendEvent(ctx, events, StreamEvent{Type: EventDone, FinishReason: finishReason})
+			return
+		}
+		if err == nil {
+			err = errIncomplete
+		}
+		sendEvent(ctx, events, StreamEvent{Type: EventError, Err: err})
+	}()
+
+	return response, nil
+}
+
+func (p *openAIProvider) newRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, p.baseURL+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %v", err)
+	}
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+	return req, nil
+}
+
+// post sends a streamed request, retrying with a backoff while the server is
+// rate limiting or unavailable. Retries are counted in c's analytics.
+func (p *openAIProvider) post(ctx context.Context, c *Chat, path string, payload []byte) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		req, err := p.newRequest(ctx, "POST", path, bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "text/event-stream")
+
+		resp, err := p.do(req)
+		var statusErr *statusError
+		if err == nil || !errors.As(err, &statusErr) {
+			return resp, err
+		}
+		retryable := statusErr.Status == http.StatusTooManyRequests || statusErr.Status == http.StatusServiceUnavailable
+		if !retryable || attempt > p.upstream.maxRetries || statusErr.RetryAfter > maxRetryAfter {
+			return nil, err
+		}
+
+		delay := p.upstream.retryDelay(attempt, statusErr.RetryAfter)
+		ui.Warningln("🔄 Error %d detected, retrying in %s (attempt %d/%d)...", statusErr.Status, delay.Round(100*time.Millisecond), attempt, p.upstream.maxRetries)
+		if err := wait(ctx, delay); err != nil {
+			return nil, err
+		}
+		c.Analytics.RecordUpstreamRetry(statusErr.Status)
+	}
+}
+
+// do sends the request and turns non-2xx answers into a *statusError
+func (p *openAIProvider) do(req *http.Request) (*http.Response, error) {
+	resp, err := p.upstream.do(p.client, req)
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %v", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		resp.Body.Close()
+		return nil, &statusError{
+			Status:     resp.StatusCode,
+			Text:       resp.Status,
+			Body:       strings.TrimSpace(string(body)),
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,86 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+
+	"duckduckgo-chat-cli/internal/ui"
+)
+
+// ErrQueueFull is returned by Acquire when too many requests are already
+// waiting for the session.
+var ErrQueueFull = errors.New("chat session is busy: too many queued requests")
+
+// turnQueue hands out turns on a Chat one at a time. A turn covers a whole
+// exchange (user message, upstream request and assistant answer), so the
+// terminal and the API server never interleave their messages or VQD updates.
+type turnQueue struct {
+	slot    chan struct{}
+	waiting atomic.Int32
+	limit   int // maximum number of waiting requests, 0 for no limit
+}
+
+func newTurnQueue(limit int) *turnQueue {
+	return &turnQueue{slot: make(chan struct{}, 1), limit: limit}
+}
+
+// Acquire waits for the session's turn. It fails with ErrQueueFull when the
+// queue limit is reached, or with the context's error if ctx is done first.
+// Every successful Acquire must be paired with a Release.
+func (c *Chat) Acquire(ctx context.Context) error {
+	q := c.queue
+	waiting := int(q.waiting.Add(1))
+	defer q.waiting.Add(-1)
+
+	select {
+	case q.slot <- struct{}{}:
+		return nil
+	default:
+	}
+
+	if q.limit > 0 && waiting > q.limit {
+		return ErrQueueFull
+	}
+
+	select {
+	case q.slot <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// AcquireInteractive waits for the session's turn on behalf of the terminal
+// user, who is never turned away by the queue limit. It fails with the
+// context's error if ctx is done first, e.g. on Ctrl+C; the turn must only be
+// released when it succeeds.
+func (c *Chat) AcquireInteractive(ctx context.Context) error {
+	q := c.queue
+	q.waiting.Add(1)
+	defer q.waiting.Add(-1)
+
+	select {
+	case q.slot <- struct{}{}:
+		return nil
+	default:
+	}
+
+	ui.Warningln("⏳ Waiting for %d API request(s) to finish...", c.QueueDepth()-1)
+	select {
+	case q.slot <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Release ends the current turn and lets the next queued request proceed
+func (c *Chat) Release() {
+	<-c.queue.slot
+}
+
+// QueueDepth returns the number of requests running or waiting on the session
+func (c *Chat) QueueDepth() int {
+	return int(c.queue.waiting.Load()) + len(c.queue.slot)
+}
@@ -0,0 +1,73 @@
+package chat
+
+import (
+	"fmt"
+	"os"
+
+	"duckduckgo-chat-cli/internal/persistence"
+	"duckduckgo-chat-cli/internal/ui"
+
+	"github.com/AlecAivazis/survey/v2"
+)
+
+// RecoverUnfinishedSession looks for conversations left in a journal by a
+// program that was killed before it could save them, and offers to continue
+// one of them. The others are saved to the history, where /load finds them.
+// It then starts the session's journal.
+func RecoverUnfinishedSession(c *Chat) {
+	recovered, err := c.HistoryManager.UnfinishedJournals()
+	if err != nil {
+		ui.Warningln("Could not look for unfinished sessions: %v", err)
+	}
+	if len(recovered) == 0 {
+		c.StartJournal()
+		return
+	}
+
+	choice := pickRecoveredSession(recovered)
+	if choice < len(recovered) {
+		c.RestoreContext(recovered[choice].Session)
+	}
+	// The restored conversation is in the new journal before the old one goes
+	c.StartJournal()
+
+	for i, r := range recovered {
+		if i != choice {
+			if err := c.HistoryManager.SaveSession(r.Session); err != nil {
+				ui.Warningln("Failed to save unfinished session %s: %v", r.Session.ID, err)
+				continue
+			}
+			ui.Mutedln("Unfinished session %s saved, use /load to open it", r.Session.ID)
+		}
+		if err := c.HistoryManager.DiscardJournal(r); err != nil {
+			ui.Warningln("Failed to remove journal: %v", err)
+		}
+	}
+}
+
+// pickRecoveredSession asks which unfinished session to restore. It returns
+// len(recovered) to start a new conversation.
+func pickRecoveredSession(recovered []persistence.RecoveredSession) int {
+	options := make([]string, 0, len(recovered)+1)
+	for _, r := range recovered {
+		options = append(options, fmt.Sprintf("%s (%d messages, last change %s)",
+			r.Session.ID, len(r.Session.Messages), r.Updated.Local().Format("2006-01-02 15:04")))
+	}
+	options = append(options, "Start a new conversation")
+
+	message := "The last session ended unexpectedly. Restore it?"
+	if len(recovered) > 1 {
+		message = fmt.Sprintf("%d sessions ended unexpectedly. Restore one?", len(recovered))
+	}
+
+	var choice int
+	prompt := &survey.Select{
+		Message:  message,
+		Options:  options,
+		PageSize: 10,
+	}
+	if err := survey.AskOne(prompt, &choice, survey.WithStdio(os.Stdin, os.Stdout, os.Stderr)); err != nil {
+		return len(recovered)
+	}
+	return choice
+}
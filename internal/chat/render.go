@@ -6,6 +6,8 @@ import (
 	"strings"
 	"time"
 
+	"duckduckgo-chat-cli/internal/ui"
+
 	"github.com/charmbracelet/glamour"
 	"github.com/charmbracelet/glamour/styles"
 	"github.com/fatih/color"
@@ -62,8 +64,9 @@ func NewStreamRenderer(modelName string) (*StreamRenderer, error) {
 	}, nil
 }
 
-// RenderStream handles the progressive rendering of a streaming response to the terminal
-func RenderStream(stream <-chan string, modelName string) string {
+// RenderStream handles the progressive rendering of a streaming response to the
+// terminal. Upstream errors and actions are shown after the answer.
+func RenderStream(stream <-chan StreamEvent, modelName string) *StreamResult {
 	// Print the model name with a clear loading indicator
 	color.New(color.FgHiGreen, color.Bold).Printf("%s: ", modelName)
 
@@ -76,9 +79,23 @@ func RenderStream(stream <-chan string, modelName string) string {
 	return renderer.ProcessStream(stream)
 }
 
+// RenderMarkdown renders a complete answer with the terminal markdown style.
+// It returns the content unchanged if rendering fails.
+func RenderMarkdown(content string) string {
+	renderer, err := NewStreamRenderer("")
+	if err != nil {
+		return content
+	}
+	rendered, err := renderer.renderer.Render(content)
+	if err != nil {
+		return content
+	}
+	return strings.TrimLeft(rendered, " \n\t\r")
+}
+
 // ProcessStream processes the incoming stream and renders it progressively
-func (sr *StreamRenderer) ProcessStream(stream <-chan string) string {
-	var finalContent strings.Builder
+func (sr *StreamRenderer) ProcessStream(stream <-chan StreamEvent) *StreamResult {
+	result := &StreamResult{}
 
 	// Show loading spinner initially
 	spinnerChars := []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
@@ -97,14 +114,19 @@ func (sr *StreamRenderer) ProcessStream(stream <-chan string) string {
 
 	for {
 		select {
-		case chunk, ok := <-stream:
+		case event, ok := <-stream:
 			if !ok {
 				// Stream finished - replace raw content with formatted version
-				sr.replaceWithFormattedContent(finalContent.String(), contentStarted)
-				return finalContent.String()
+				sr.replaceWithFormattedContent(result.Content(), contentStarted)
+				printStreamNotes(result)
+				return result
 			}
 
-			finalContent.WriteString(chunk)
+			result.Add(event)
+			if event.Type != EventDelta {
+				continue
+			}
+			chunk := event.Content
 
 			// On first chunk, clear spinner and start displaying raw content
 			if !contentStarted {
@@ -199,16 +221,20 @@ func (sr *StreamRenderer) replaceWithFormattedContent(content string, contentSta
 }
 
 // renderStreamFallback is a simple fallback when glamour fails
-func renderStreamFallback(stream <-chan string, modelName string) string {
-	var content strings.Builder
+func renderStreamFallback(stream <-chan StreamEvent, modelName string) *StreamResult {
+	result := &StreamResult{}
 	var displayedLines int
 
 	// Show simple loading
 	fmt.Print(color.New(color.FgYellow).Sprint("⠋") + " ")
 	contentStarted := false
 
-	for chunk := range stream {
-		content.WriteString(chunk)
+	for event := range stream {
+		result.Add(event)
+		if event.Type != EventDelta {
+			continue
+		}
+		chunk := event.Content
 
 		// Clear loading indicator on first chunk
 		if !contentStarted {
@@ -224,11 +250,26 @@ func renderStreamFallback(stream <-chan string, modelName string) string {
 	}
 
 	// For fallback, just ensure we end with a newline (no markdown formatting)
-	if !strings.HasSuffix(content.String(), "\n") {
+	if !strings.HasSuffix(result.Content(), "\n") {
 		fmt.Println()
 	}
+	printStreamNotes(result)
+
+	return result
+}
 
-	return content.String()
+// printStreamNotes shows what the upstream service said besides the answer:
+// the actions it took, and why the answer was cut short
+func printStreamNotes(result *StreamResult) {
+	for _, tool := range result.Tools {
+		ui.Mutedln("🔧 Upstream action: %s", tool.Action)
+	}
+	switch {
+	case result.Err != nil:
+		ui.Errorln("⚠️  %v", result.Err)
+	case result.FinishReason == FinishLength:
+		ui.Warningln("✂️  The answer hit the output limit")
+	}
 }
 
 // getTerminalWidthSafe safely gets terminal width with fallback
@@ -0,0 +1,172 @@
+package chat
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+
+	"duckduckgo-chat-cli/internal/chatcontext"
+	"duckduckgo-chat-cli/internal/ui"
+
+	"github.com/AlecAivazis/survey/v2"
+)
+
+// HandleRetryCommand processes the /retry command: it sends the last user
+// message again, optionally with another model, on a new branch
+func HandleRetryCommand(ctx context.Context, c *Chat, args string) {
+	previous := c.MessagesSnapshot()
+	end := len(previous)
+	if end > 0 && previous[end-1].Role == "assistant" {
+		end--
+	}
+	if end == 0 || previous[end-1].Role != "user" || isContextMessage(previous[end-1]) {
+		ui.Warningln("There is no message to retry")
+		return
+	}
+
+	// A failed retry goes back to the model that was in use
+	restoreModel := func() {}
+	if name := strings.TrimSpace(args); name != "" {
+		model, ok := ResolveModelName(c, name)
+		if !ok {
+			ui.Errorln("Unknown model: %s", name)
+			return
+		}
+		if current := c.CurrentModel(); model != current {
+			c.ChangeModel(model)
+			restoreModel = func() { c.ChangeModel(current) }
+		}
+	}
+
+	// The previous answer stays on its own branch
+	if end == len(previous) {
+		if !c.answer(ctx) {
+			restoreModel()
+			ui.Warningln("The message is still unanswered")
+		}
+		return
+	}
+	abandon := c.forkBranch(end)
+	c.setMessages(previous[:end])
+	if !c.answer(ctx) {
+		abandon()
+		restoreModel()
+		ui.Warningln("The previous answer was kept")
+	}
+}
+
+// HandleEditCommand processes the /edit command: it lets the user edit one of
+// their messages and sends it again on a new branch, without what followed
+// it. n counts the user's messages from 1, as numbered by /history; the
+// default is the last.
+func HandleEditCommand(ctx context.Context, c *Chat, args string) {
+	previous := c.MessagesSnapshot()
+	turns := userTurns(previous)
+	if len(turns) == 0 {
+		ui.Warningln("There is no message to edit")
+		return
+	}
+
+	n := len(turns)
+	if arg := strings.TrimSpace(args); arg != "" {
+		var err error
+		n, err = strconv.Atoi(arg)
+		if err != nil || n < 1 || n > len(turns) {
+			ui.Errorln("Invalid message number: %s (1-%d, see /history)", arg, len(turns))
+			return
+		}
+	}
+	index := turns[n-1]
+	original := previous[index].Content
+
+	edited, err := editMessage(original)
+	if err != nil {
+		ui.Warningln("Edit canceled")
+		return
+	}
+	if strings.TrimSpace(edited) == "" || edited == original {
+		ui.Warningln("Message unchanged, nothing sent")
+		return
+	}
+
+	// The original message and what followed it stay on their own branch
+	abandon := c.forkBranch(index)
+	messages := append(append([]Message(nil), previous[:index]...), Message{Role: "user", Content: edited})
+	c.setMessages(messages)
+	if !c.answer(ctx) {
+		abandon()
+		ui.Warningln("The conversation was left unchanged")
+		return
+	}
+	c.Analytics.RecordMessage("user", len(edited))
+}
+
+// HandleUndoCommand processes the /undo command: it removes the last exchange,
+// or the last message if it got no answer
+func HandleUndoCommand(c *Chat) {
+	messages := c.MessagesSnapshot()
+	if len(messages) == 0 {
+		ui.Warningln("Chat is already empty")
+		return
+	}
+
+	start := len(messages) - 1
+	if messages[start].Role == "assistant" && start > 0 && messages[start-1].Role == "user" && !isContextMessage(messages[start-1]) {
+		start--
+	}
+
+	c.setMessages(messages[:start])
+	c.recordRemoved(messages[start:])
+
+	if len(messages)-start == 2 {
+		ui.AIln("Removed the last exchange")
+	} else {
+		ui.AIln("Removed the last message")
+	}
+}
+
+// userTurns returns the indexes of the messages typed by the user
+func userTurns(messages []Message) []int {
+	var turns []int
+	for i, msg := range messages {
+		if msg.Role == "user" && !isContextMessage(msg) {
+			turns = append(turns, i)
+		}
+	}
+	return turns
+}
+
+// isContextMessage reports whether a message holds context loaded by a
+// command rather than text typed by the user
+func isContextMessage(msg Message) bool {
+	for _, header := range []string{chatcontext.FileHeader, chatcontext.URLHeader, chatcontext.SearchHeader, chatcontext.ProjectHeader} {
+		if strings.HasPrefix(msg.Content, header) {
+			return true
+		}
+	}
+	return false
+}
+
+// recordRemoved updates the analytics for messages taken out of the history.
+// Context messages are not counted when added, so they are skipped.
+func (c *Chat) recordRemoved(messages []Message) {
+	for _, msg := range messages {
+		if !isContextMessage(msg) {
+			c.Analytics.RecordMessageRemoved(msg.Role, len(msg.Content))
+		}
+	}
+}
+
+// editMessage opens the message in the user's editor ($VISUAL or $EDITOR)
+func editMessage(content string) (string, error) {
+	var edited string
+	prompt := &survey.Editor{
+		Message:       "Edit your message:",
+		Default:       content,
+		AppendDefault: true,
+		HideDefault:   true,
+	}
+	err := survey.AskOne(prompt, &edited, survey.WithStdio(os.Stdin, os.Stdout, os.Stderr))
+	return strings.TrimRight(edited, "\n"), err
+}
@@ -1,6 +1,7 @@
 package chat
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -24,7 +25,7 @@ type SearchResult struct {
 	HtmlSnippet      string
 }
 
-func HandleSearchCommand(c *Chat, input string, cfg *config.Config, chainCtx *chatcontext.Context) {
+func HandleSearchCommand(ctx context.Context, c *Chat, input string, cfg *config.Config, chainCtx *chatcontext.Context) {
 	// Parse the command: /search <query> -- <request>
 	commandInput := strings.TrimPrefix(input, "/search ")
 
@@ -48,7 +49,11 @@ func HandleSearchCommand(c *Chat, input string, cfg *config.Config, chainCtx *ch
 	}
 
 	color.Yellow("🔍 Searching for: %s (this may take a few seconds...)", query)
-	results, err := performSearch(query, cfg.Search.MaxResults)
+	results, err := performSearch(ctx, query, cfg.Search.MaxResults)
+	if ctx.Err() != nil {
+		color.Yellow("Request canceled")
+		return
+	}
 	if err != nil {
 		color.Red("Search error: %v", err)
 		return
@@ -65,28 +70,60 @@ func HandleSearchCommand(c *Chat, input string, cfg *config.Config, chainCtx *ch
 		chainCtx.AddSearch(query, contextMsg)
 		color.Green("Added %d search results to the chain context", len(results))
 	} else {
-		c.Messages = append(c.Messages, Message{
-			Role:    "user",
-			Content: fmt.Sprintf("[Search Context]\n%s", contextMsg),
-		})
-
-		if c.Analytics != nil {
-			c.Analytics.RecordSearchPerformed()
-		}
+		c.AddSearchResults(query, contextMsg)
 
 		color.Green("Added %d search results to the context", len(results))
 
 		// If user provided a specific request, process it with the search context
 		if userRequest != "" {
 			color.Cyan("Processing your request about the search results...")
-			ProcessInput(c, userRequest, cfg)
+			ProcessInput(ctx, c, userRequest, cfg)
 		} else {
 			color.Yellow("Search results added to context. You can now ask questions about them.")
 		}
 	}
 }
 
-func performSearch(query string, maxResults int) ([]SearchResult, error) {
+// AddSearchContext searches the web and adds the results to the conversation.
+// It returns the number of results added.
+func (c *Chat) AddSearchContext(ctx context.Context, query string, cfg *config.Config) (int, error) {
+	results, count, err := SearchWeb(ctx, query, cfg)
+	if err != nil {
+		return 0, err
+	}
+
+	c.AddSearchResults(query, results)
+	return count, nil
+}
+
+// SearchWeb searches the web and returns the results formatted as context,
+// along with their number. It does not touch the conversation, so callers can
+// search before waiting for the session's turn.
+func SearchWeb(ctx context.Context, query string, cfg *config.Config) (string, int, error) {
+	results, err := performSearch(ctx, query, cfg.Search.MaxResults)
+	if err != nil {
+		return "", 0, err
+	}
+	if len(results) == 0 {
+		return "", 0, fmt.Errorf("no results found for: %s", query)
+	}
+	return formatSearchResults(results, cfg.Search.IncludeSnippet), len(results), nil
+}
+
+// AddSearchResults adds search results formatted by SearchWeb to the conversation.
+func (c *Chat) AddSearchResults(query string, results string) {
+	c.appendMessage(Message{
+		Role:    "user",
+		Content: chatcontext.FormatSearch(query, results),
+		Source:  SourceSearch,
+	})
+
+	if c.Analytics != nil {
+		c.Analytics.RecordSearchPerformed()
+	}
+}
+
+func performSearch(ctx context.Context, query string, maxResults int) ([]SearchResult, error) {
 	if maxResults <= 0 {
 		maxResults = 5
 	}
@@ -98,11 +135,18 @@ func performSearch(query string, maxResults int) ([]SearchResult, error) {
 		if attempt > 0 {
 			sleepDuration := time.Duration(1<<attempt) * time.Second
 			color.Yellow("Retrying search in %v... (attempt %d/%d)", sleepDuration, attempt+1, maxRetries)
-			time.Sleep(sleepDuration)
+			select {
+			case <-time.After(sleepDuration):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
 		}
 
-		results, err := duckSearch(query, maxResults)
+		results, err := duckSearch(ctx, query, maxResults)
 		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
 			lastErr = err
 			if strings.Contains(err.Error(), "202") {
 				continue
@@ -173,11 +217,11 @@ func clean(text string) string {
 	return strings.TrimSpace(strings.ReplaceAll(text, "\n", ""))
 }
 
-func duckSearch(query string, maxResults int) ([]duckResult, error) {
+func duckSearch(ctx context.Context, query string, maxResults int) ([]duckResult, error) {
 	baseUrl := "https://duckduckgo.com/html/"
 	queryUrl := baseUrl + "?q=" + url.QueryEscape(query)
 
-	req, err := http.NewRequest("GET", queryUrl, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", queryUrl, nil)
 	if err != nil {
 		return nil, err
 	}
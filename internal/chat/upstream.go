@@ -0,0 +1,241 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"duckduckgo-chat-cli/internal/config"
+)
+
+// maxRetryAfter is the longest Retry-After the client waits for. A server
+// asking for more is not retried.
+const maxRetryAfter = 2 * time.Minute
+
+// maxRetryDelay caps the exponential backoff
+const maxRetryDelay = 30 * time.Second
+
+// upstream holds the timeouts and retry policy of the requests sent to a
+// provider, as set in the configuration
+type upstream struct {
+	connectTimeout   time.Duration
+	firstByteTimeout time.Duration
+	idleTimeout      time.Duration
+	maxRetries       int
+	retryBaseDelay   time.Duration
+}
+
+func newUpstream(network config.NetworkConfig) upstream {
+	network = network.WithDefaults()
+	return upstream{
+		connectTimeout:   time.Duration(network.ConnectTimeout) * time.Second,
+		firstByteTimeout: time.Duration(network.FirstByteTimeout) * time.Second,
+		idleTimeout:      time.Duration(network.IdleTimeout) * time.Second,
+		maxRetries:       *network.MaxRetries,
+		retryBaseDelay:   time.Duration(network.RetryBaseDelay) * time.Millisecond,
+	}
+}
+
+// client returns an HTTP client for streamed answers. It has no overall
+// timeout, which would cut long answers off: do applies the first byte and
+// idle timeouts instead.
+func (u upstream) client(jar http.CookieJar) *http.Client {
+	return &http.Client{Jar: jar, Transport: u.transport(Transport)}
+}
+
+// transport applies the connect timeout. Transports other than
+// *http.Transport, such as the cassette recorder, are used as they are.
+func (u upstream) transport(base http.RoundTripper) http.RoundTripper {
+	t, ok := base.(*http.Transport)
+	if !ok {
+		return base
+	}
+	t = t.Clone()
+	t.DialContext = (&net.Dialer{Timeout: u.connectTimeout, KeepAlive: 30 * time.Second}).DialContext
+	t.TLSHandshakeTimeout = u.connectTimeout
+	return t
+}
+
+// do sends the request, giving up if the response headers take longer than
+// the first byte timeout, or if the body then stays silent for longer than
+// the idle timeout
+func (u upstream) do(client *http.Client, req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithCancelCause(req.Context())
+	timer := time.AfterFunc(u.firstByteTimeout, func() {
+		cancel(fmt.Errorf("no answer after %s", u.firstByteTimeout))
+	})
+
+	resp, err := client.Do(req.WithContext(ctx))
+	timer.Stop()
+	if err != nil {
+		cause := context.Cause(ctx)
+		cancel(nil)
+		if cause != nil && req.Context().Err() == nil {
+			return nil, cause
+		}
+		return nil, err
+	}
+
+	body := resp.Body
+	resp.Body = &idleBody{
+		body:    body,
+		ctx:     ctx,
+		parent:  req.Context(),
+		cancel:  cancel,
+		timeout: u.idleTimeout,
+		timer: time.AfterFunc(u.idleTimeout, func() {
+			cancel(fmt.Errorf("the answer stalled: no data for %s", u.idleTimeout))
+			// Unblocks the read even if the transport ignores the context
+			body.Close()
+		}),
+	}
+	return resp, nil
+}
+
+// idleBody cancels a streamed response that stays silent for too long
+type idleBody struct {
+	body    io.ReadCloser
+	ctx     context.Context
+	parent  context.Context
+	cancel  context.CancelCauseFunc
+	timeout time.Duration
+	timer   *time.Timer
+}
+
+func (b *idleBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	if n > 0 {
+		b.timer.Reset(b.timeout)
+	}
+	if err != nil && err != io.EOF && b.parent.Err() == nil {
+		if cause := context.Cause(b.ctx); cause != nil {
+			err = cause
+		}
+	}
+	return n, err
+}
+
+func (b *idleBody) Close() error {
+	b.timer.Stop()
+	b.cancel(nil)
+	return b.body.Close()
+}
+
+// retryDelay returns how long to wait before a retry: an exponential backoff
+// with jitter, or the server's Retry-After if it asks for longer
+func (u upstream) retryDelay(attempt int, retryAfter time.Duration) time.Duration {
+	backoff := u.retryBaseDelay << (attempt - 1)
+	if backoff <= 0 || backoff > maxRetryDelay {
+		backoff = maxRetryDelay
+	}
+	// Half fixed, half random, so that clients failing together spread out
+	delay := backoff/2 + rand.N(backoff/2+1)
+	if retryAfter > delay {
+		delay = retryAfter
+	}
+	return delay
+}
+
+// wait sleeps for d, or until the context is done
+func wait(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// parseRetryAfter reads a Retry-After header, given in seconds or as a date
+func parseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if d := time.Until(date); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// statusError is a non-2xx answer from the upstream service
+type statusError struct {
+	Status     int
+	Text       string
+	Body       string
+	RetryAfter time.Duration
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("%d: %s. Body: %s", e.Status, e.Text, e.Body)
+}
+
+// errCircuitOpen is returned while requests are paused after the upstream
+// service refused too many of them in a row
+var errCircuitOpen = errors.New("requests paused")
+
+// circuitBreaker stops sending requests for a while after too many failures
+// in a row. Once the cooldown is over, requests go through again; one more
+// failure pauses them again until a request succeeds.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+}
+
+// configure sets the breaker's limits
+func (b *circuitBreaker) configure(network config.NetworkConfig) {
+	network = network.WithDefaults()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.threshold = network.BreakerThreshold
+	b.cooldown = time.Duration(network.BreakerCooldown) * time.Second
+}
+
+// allow returns errCircuitOpen, wrapped with the time left, while requests
+// are paused
+func (b *circuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if left := time.Until(b.openUntil); left > 0 {
+		return fmt.Errorf("%w after %d refused requests, try again in %s", errCircuitOpen, b.failures, left.Round(time.Second))
+	}
+	return nil
+}
+
+// failure records a failed request and reports whether it paused requests
+func (b *circuitBreaker) failure() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.threshold > 0 && b.failures >= b.threshold {
+		b.openUntil = time.Now().Add(b.cooldown)
+		return true
+	}
+	return false
+}
+
+// success closes the breaker
+func (b *circuitBreaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.openUntil = time.Time{}
+}
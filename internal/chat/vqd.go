@@ -0,0 +1,446 @@
+package chat
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"duckduckgo-chat-cli/internal/analytics"
+	"duckduckgo-chat-cli/internal/config"
+	"duckduckgo-chat-cli/internal/fingerprint"
+	"duckduckgo-chat-cli/internal/models"
+	"duckduckgo-chat-cli/internal/ui"
+)
+
+// VQD acquisition strategies, in the order they are tried
+const (
+	StrategyCached    = "cached"    // a recent VQD a session gave back without sending it
+	StrategyStatus    = "status"    // the status endpoint, like the PowerShell script
+	StrategyChallenge = "challenge" // the browser challenge flow, then the status endpoint
+	StrategyProfile   = "profile"   // the status endpoint with the other fingerprint profiles
+)
+
+// vqdCacheTTL is how long after its acquisition an unsent VQD can be reused
+const vqdCacheTTL = 5 * time.Minute
+
+// maxVQDAttempts is how many attempts the pipeline remembers for /doctor
+const maxVQDAttempts = 20
+
+// vqdHeaders are the values DuckDuckGo needs to accept a conversation
+type vqdHeaders struct {
+	VQD       string // x-vqd-4 header
+	VqdHash1  string // x-vqd-hash-1 header
+	FeSignals string // x-fe-signals header
+	FeVersion string // x-fe-version header
+
+	Profile    string // fingerprint profile the VQD was acquired with
+	AcquiredAt time.Time
+}
+
+// VQDAttempt is one try of an acquisition strategy
+type VQDAttempt struct {
+	Strategy string
+	Profile  string
+	Time     time.Time
+	Duration time.Duration
+	Err      error
+}
+
+// vqdStrategy is one way of getting a VQD
+type vqdStrategy struct {
+	name    string
+	acquire func(profile fingerprint.Profile) (vqdHeaders, fingerprint.Profile, error)
+}
+
+var vqdStrategies = []vqdStrategy{
+	{StrategyCached, cachedVQD},
+	{StrategyStatus, statusVQD},
+	{StrategyChallenge, challengeVQD},
+	{StrategyProfile, profileFallbackVQD},
+}
+
+// vqdPipeline is shared by every session: DuckDuckGo blocks the program, not
+// a conversation, so what worked for one session is tried first by the next.
+// Acquisitions run one at a time, so that sessions starting together do not
+// all go through the slow strategies.
+//
+// A VQD belongs to one conversation at a time. The cache only holds a VQD no
+// session is holding: one given back unsent (see releaseVQD), which leaves
+// the cache again as soon as it is handed out.
+var vqdPipeline struct {
+	mu          sync.Mutex
+	acquiring   sync.Mutex
+	cached      vqdHeaders
+	lastSuccess string
+	attempts    []VQDAttempt
+}
+
+// errNoCachedVQD is returned by the cached strategy when it has nothing to offer
+var errNoCachedVQD = errors.New("no VQD cached")
+
+// acquireVQD tries the strategies in order, starting with the one that last
+// succeeded after the cache, and records each attempt in the analytics
+func acquireVQD(stats *analytics.ChatAnalytics) (vqdHeaders, error) {
+	vqdPipeline.acquiring.Lock()
+	defer vqdPipeline.acquiring.Unlock()
+
+	var errs []string
+	for _, strategy := range orderedStrategies() {
+		start := time.Now()
+		headers, profile, err := strategy.acquire(fingerprint.Current())
+		attempt := VQDAttempt{Strategy: strategy.name, Profile: profile.Label(), Time: start, Duration: time.Since(start), Err: err}
+
+		// A cache miss costs nothing and is not worth reporting
+		if errors.Is(err, errNoCachedVQD) {
+			continue
+		}
+		recordVQDAttempt(attempt)
+		if strategy.name != StrategyCached && stats != nil {
+			stats.RecordHeaderRefresh()
+		}
+
+		if err != nil {
+			ui.Warningln("⚠️  VQD strategy %s failed: %v", strategy.name, err)
+			errs = append(errs, fmt.Sprintf("%s: %v", strategy.name, err))
+			continue
+		}
+
+		if strategy.name != StrategyCached {
+			headers.Profile = profile.Name
+			headers.AcquiredAt = time.Now()
+			vqdPipeline.mu.Lock()
+			vqdPipeline.lastSuccess = strategy.name
+			vqdPipeline.mu.Unlock()
+		}
+		return headers, nil
+	}
+	return vqdHeaders{}, fmt.Errorf("failed to get VQD (%s)", strings.Join(errs, "; "))
+}
+
+// orderedStrategies returns the strategies to try: the cache, then the one
+// that last succeeded, then the others in their usual order
+func orderedStrategies() []vqdStrategy {
+	vqdPipeline.mu.Lock()
+	last := vqdPipeline.lastSuccess
+	vqdPipeline.mu.Unlock()
+
+	ordered := []vqdStrategy{vqdStrategies[0]}
+	for _, s := range vqdStrategies[1:] {
+		if s.name == last {
+			ordered = append(ordered, s)
+		}
+	}
+	for _, s := range vqdStrategies[1:] {
+		if s.name != last {
+			ordered = append(ordered, s)
+		}
+	}
+	return ordered
+}
+
+func recordVQDAttempt(attempt VQDAttempt) {
+	vqdPipeline.mu.Lock()
+	defer vqdPipeline.mu.Unlock()
+	vqdPipeline.attempts = append(vqdPipeline.attempts, attempt)
+	if len(vqdPipeline.attempts) > maxVQDAttempts {
+		vqdPipeline.attempts = vqdPipeline.attempts[len(vqdPipeline.attempts)-maxVQDAttempts:]
+	}
+}
+
+// releaseVQD gives back a VQD a session acquired and no longer needs, before
+// it was sent. A VQD that was sent, even if rejected, is used up: DuckDuckGo
+// hands out a new one with each answer.
+func releaseVQD(headers vqdHeaders) {
+	if headers.VQD == "" || time.Since(headers.AcquiredAt) > vqdCacheTTL {
+		return
+	}
+	vqdPipeline.mu.Lock()
+	defer vqdPipeline.mu.Unlock()
+	vqdPipeline.cached = headers
+}
+
+// cachedVQD takes the VQD out of the cache, if it is recent and was acquired
+// with the current profile
+func cachedVQD(profile fingerprint.Profile) (vqdHeaders, fingerprint.Profile, error) {
+	vqdPipeline.mu.Lock()
+	defer vqdPipeline.mu.Unlock()
+	cached := vqdPipeline.cached
+	if cached.VQD == "" || time.Since(cached.AcquiredAt) > vqdCacheTTL || cached.Profile != profile.Name {
+		return vqdHeaders{}, profile, errNoCachedVQD
+	}
+	vqdPipeline.cached = vqdHeaders{}
+	return cached, profile, nil
+}
+
+// statusVQD asks the status endpoint for a VQD, sending the headers of the
+// fingerprint profile
+func statusVQD(profile fingerprint.Profile) (vqdHeaders, fingerprint.Profile, error) {
+	ui.Warningln("⌛ Getting VQD from status API (simple approach like working PS1 script)...")
+	vqd, err := requestStatusVQD(newVQDClient(10*time.Second), profile)
+	if err != nil {
+		return vqdHeaders{}, profile, err
+	}
+	return vqdHeaders{VQD: vqd, VqdHash1: profile.VqdHash1, FeSignals: profile.FeSignals, FeVersion: profile.FeVersion}, profile, nil
+}
+
+// challengeVQD goes through the page, challenge and HEAD requests of the
+// browser before asking the status endpoint, keeping the cookies they set and
+// the front-end version and signals found on the page
+func challengeVQD(profile fingerprint.Profile) (vqdHeaders, fingerprint.Profile, error) {
+	ui.Warningln("⌛ Getting VQD through the browser challenge flow...")
+	client := newVQDClient(30 * time.Second)
+	dynamic, err := extractDynamicHeaders(client, profile)
+	if err != nil {
+		return vqdHeaders{}, profile, err
+	}
+	vqd, err := requestStatusVQD(client, profile)
+	if err != nil {
+		return vqdHeaders{}, profile, err
+	}
+	return vqdHeaders{VQD: vqd, VqdHash1: dynamic.VqdHash1, FeSignals: dynamic.FeSignals, FeVersion: dynamic.FeVersion}, profile, nil
+}
+
+// profileFallbackVQD tries the status endpoint with each of the other
+// fingerprint profiles, the least blocked first. The profile that works
+// becomes the current one, as requests must keep passing for the same browser.
+func profileFallbackVQD(current fingerprint.Profile) (vqdHeaders, fingerprint.Profile, error) {
+	var candidates []fingerprint.Profile
+	for _, p := range fingerprint.List() {
+		if p.Name != current.Name {
+			candidates = append(candidates, p)
+		}
+	}
+	if len(candidates) == 0 {
+		return vqdHeaders{}, current, fmt.Errorf("no other fingerprint profile to try (add some to %s)", fingerprint.Dir())
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return blockCount(candidates[i]) < blockCount(candidates[j])
+	})
+
+	var errs []string
+	for _, profile := range candidates {
+		headers, _, err := statusVQD(profile)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", profile.Name, err))
+			continue
+		}
+		if err := fingerprint.Select(profile.Name); err != nil {
+			return vqdHeaders{}, profile, err
+		}
+		ui.Warningln("🧬 Switched to fingerprint profile %s for this session, select it in /config to keep it", profile.Label())
+		return headers, profile, nil
+	}
+	return vqdHeaders{}, current, errors.New(strings.Join(errs, "; "))
+}
+
+// blockCount returns how many times DuckDuckGo answered 418 to a profile
+func blockCount(p fingerprint.Profile) int {
+	record, _ := fingerprint.Blocks(p)
+	return record.Count
+}
+
+// newVQDClient returns a client with the minimum cookies DuckDuckGo expects,
+// as in the PowerShell script
+func newVQDClient(timeout time.Duration) *http.Client {
+	jar, _ := cookiejar.New(nil)
+	u, _ := url.Parse("https://duckduckgo.com")
+	jar.SetCookies(u, []*http.Cookie{
+		{Name: "5", Value: "1", Domain: ".duckduckgo.com"},
+		{Name: "dcm", Value: "3", Domain: ".duckduckgo.com"},
+		{Name: "dcs", Value: "1", Domain: ".duckduckgo.com"},
+	})
+	return &http.Client{Timeout: timeout, Transport: Transport, Jar: jar}
+}
+
+// requestStatusVQD sends a GET to the status endpoint with the exact headers
+// of the PowerShell script, and returns the VQD it hands out
+func requestStatusVQD(client *http.Client, profile fingerprint.Profile) (string, error) {
+	req, _ := http.NewRequest("GET", models.StatusURL, nil)
+	req.Header.Set("Accept", "*/*")
+	req.Header.Set("Accept-Encoding", "gzip, deflate, br, zstd")
+	req.Header.Set("Authority", "duckduckgo.com")
+	req.Header.Set("Cache-Control", "no-store")
+	req.Header.Set("DNT", "1")
+	req.Header.Set("Method", "GET")
+	req.Header.Set("Path", "/duckchat/v1/status")
+	req.Header.Set("Priority", "u=1, i")
+	req.Header.Set("Referer", "https://duckduckgo.com/")
+	req.Header.Set("Scheme", "https")
+	req.Header.Set("Sec-Fetch-Dest", "empty")
+	req.Header.Set("Sec-Fetch-Mode", "cors")
+	req.Header.Set("Sec-Fetch-Site", "same-origin")
+	req.Header.Set("Sec-GPC", "1")
+	profile.SetHeaders(req.Header)
+	req.Header.Set("x-vqd-accept", "1")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error fetching VQD: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("status API answered %s", resp.Status)
+	}
+	// Le VQD header de la status API pour x-vqd-4
+	vqd := resp.Header.Get("x-vqd-hash-1")
+	if vqd == "" {
+		return "", errors.New("no VQD header found in response")
+	}
+	return vqd, nil
+}
+
+// refreshVQD acquires new VQD headers for the conversation. A VQD the
+// session acquired and never sent goes back to the cache first.
+func (c *Chat) refreshVQD() error {
+	c.mu.Lock()
+	unsent := c.unsentVQD
+	c.unsentVQD = vqdHeaders{}
+	c.mu.Unlock()
+	releaseVQD(unsent)
+
+	headers, err := acquireVQD(c.Analytics)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.unsentVQD = headers
+	c.OldVqd = c.NewVqd
+	c.NewVqd = headers.VQD
+	c.VqdHash1 = headers.VqdHash1
+	c.FeSignals = headers.FeSignals
+	c.FeVersion = headers.FeVersion
+	c.mu.Unlock()
+	return nil
+}
+
+// HandleDoctorCommand processes the /doctor command
+func HandleDoctorCommand(c *Chat, args string) {
+	switch topic := strings.TrimSpace(args); topic {
+	case "", "vqd":
+		printVQDReport(c)
+	default:
+		ui.Errorln("Unknown diagnostic %q. Usage: /doctor vqd", topic)
+	}
+}
+
+// printVQDReport shows the state of the VQD pipeline, then probes each
+// strategy without touching the cache or the session
+func printVQDReport(c *Chat) {
+	if c.Provider.Name() != config.ProviderDuckDuckGo {
+		ui.Warningln("The %s provider does not use a VQD.", c.Provider.Name())
+		return
+	}
+
+	vqdPipeline.mu.Lock()
+	cached := vqdPipeline.cached
+	last := vqdPipeline.lastSuccess
+	attempts := append([]VQDAttempt(nil), vqdPipeline.attempts...)
+	vqdPipeline.mu.Unlock()
+
+	c.mu.RLock()
+	current := c.NewVqd
+	c.mu.RUnlock()
+
+	profile := fingerprint.Current()
+	ui.Systemln("\n" + strings.Repeat("-", 50))
+	ui.Systemln("VQD DIAGNOSTICS")
+	ui.Systemln(strings.Repeat("-", 50))
+
+	ui.AIln("Session:")
+	ui.Whiteln("  Current VQD: %s", abbreviate(current))
+	ui.Whiteln("  Fingerprint profile: %s", profile.Label())
+	if record, ok := fingerprint.Blocks(profile); ok {
+		ui.Warningln("  418 answers with this profile: %d (first %s, last %s)", record.Count,
+			record.First.Format("2006-01-02 15:04"), record.Last.Format("2006-01-02 15:04"))
+	}
+
+	ui.AIln("\nPipeline:")
+	ui.Whiteln("  Order: %s", strings.Join(strategyNames(orderedStrategies()), " → "))
+	if last == "" {
+		last = "none yet"
+	}
+	ui.Whiteln("  Last successful strategy: %s", last)
+	if cached.VQD != "" && time.Since(cached.AcquiredAt) <= vqdCacheTTL {
+		ui.Whiteln("  Cached VQD: %s (expires in %s)", abbreviate(cached.VQD), (vqdCacheTTL - time.Since(cached.AcquiredAt)).Round(time.Second))
+	} else {
+		ui.Whiteln("  Cached VQD: none")
+	}
+	ui.Whiteln("  Header refreshes this session: %d", c.Analytics.HeaderRefreshes())
+
+	if len(attempts) > 0 {
+		ui.AIln("\nRecent attempts:")
+		for _, a := range attempts {
+			line := fmt.Sprintf("  %s  %-9s %-30s %6s", a.Time.Format("15:04:05"), a.Strategy, a.Profile, a.Duration.Round(time.Millisecond))
+			if a.Err != nil {
+				ui.Errorln("%s  ✗ %v", line, a.Err)
+			} else {
+				ui.Whiteln("%s  ✓", line)
+			}
+		}
+	}
+
+	ui.AIln("\nProbes:")
+	for _, strategy := range vqdStrategies[1:] {
+		if strategy.name == StrategyProfile {
+			probeProfiles(profile)
+			continue
+		}
+		start := time.Now()
+		_, _, err := strategy.acquire(profile)
+		reportProbe(strategy.name, profile, time.Since(start), err)
+	}
+}
+
+// probeProfiles checks the status endpoint with every other profile, without
+// selecting any of them
+func probeProfiles(current fingerprint.Profile) {
+	probed := false
+	for _, p := range fingerprint.List() {
+		if p.Name == current.Name {
+			continue
+		}
+		probed = true
+		start := time.Now()
+		_, err := requestStatusVQD(newVQDClient(10*time.Second), p)
+		reportProbe(StrategyProfile, p, time.Since(start), err)
+	}
+	if !probed {
+		ui.Mutedln("  %-9s no other fingerprint profile in %s", StrategyProfile, fingerprint.Dir())
+	}
+}
+
+func reportProbe(strategy string, profile fingerprint.Profile, d time.Duration, err error) {
+	line := fmt.Sprintf("  %-9s %-30s %6s", strategy, profile.Label(), d.Round(time.Millisecond))
+	if err != nil {
+		ui.Errorln("%s  ✗ %v", line, err)
+	} else {
+		ui.AIln("%s  ✓", line)
+	}
+}
+
+func strategyNames(strategies []vqdStrategy) []string {
+	names := make([]string, len(strategies))
+	for i, s := range strategies {
+		names[i] = s.name
+	}
+	return names
+}
+
+// abbreviate shortens a VQD for display
+func abbreviate(vqd string) string {
+	if vqd == "" {
+		return "none"
+	}
+	if len(vqd) > 24 {
+		return vqd[:24] + "..."
+	}
+	return vqd
+}
@@ -17,19 +17,81 @@ func New() *Context {
 	}
 }
 
+// Headers that mark context messages in the conversation.
+const (
+	FileHeader    = "[File Context]"
+	URLHeader     = "[URL Context]"
+	SearchHeader  = "[Search Context]"
+	ProjectHeader = "[Project Context - Generated by PMP]"
+)
+
+// Item describes a piece of context loaded in a conversation.
+type Item struct {
+	Kind   string // "file", "url", "search" or "project"
+	Source string // file path, URL, search query or project path
+}
+
+// FormatFile formats file content as a context message.
+func FormatFile(path string, content []byte) string {
+	return fmt.Sprintf("%s\nFile: %s\n\n%s", FileHeader, path, string(content))
+}
+
+// FormatURL formats webpage content as a context message.
+func FormatURL(url string, content string) string {
+	return fmt.Sprintf("%s\nURL: %s\n\n%s", URLHeader, url, content)
+}
+
+// FormatSearch formats search results as a context message.
+func FormatSearch(query string, results string) string {
+	return fmt.Sprintf("%s\nQuery: %s\n\n%s", SearchHeader, query, results)
+}
+
+// FormatProject formats a prompt generated by PMP as a context message.
+func FormatProject(path string, prompt string) string {
+	return fmt.Sprintf("%s\nPath: %s\n\n%s", ProjectHeader, path, prompt)
+}
+
+// Describe reports which kind of context a message holds, based on the
+// header written by the Format functions. Messages combining several items,
+// as produced by command chains, are described by their first item.
+func Describe(message string) (Item, bool) {
+	kinds := []struct {
+		header, kind, label string
+	}{
+		{FileHeader, "file", "File: "},
+		{URLHeader, "url", "URL: "},
+		{SearchHeader, "search", "Query: "},
+		{ProjectHeader, "project", "Path: "},
+	}
+
+	for _, k := range kinds {
+		rest, ok := strings.CutPrefix(message, k.header+"\n")
+		if !ok {
+			continue
+		}
+		firstLine, _, _ := strings.Cut(rest, "\n")
+		source, _ := strings.CutPrefix(firstLine, k.label)
+		if source == firstLine {
+			source = "" // e.g. search context without a query line
+		}
+		return Item{Kind: k.kind, Source: source}, true
+	}
+	return Item{}, false
+}
+
 // AddFile adds file content to the context.
 func (c *Context) AddFile(path string, content []byte) {
-	c.items = append(c.items, fmt.Sprintf("[File Context]\nFile: %s\n\n%s", path, string(content)))
+	c.items = append(c.items, FormatFile(path, content))
 }
 
 // AddURL adds URL content to the context.
 func (c *Context) AddURL(url string, content string) {
-	c.items = append(c.items, fmt.Sprintf("[URL Context]\nURL: %s\n\n%s", url, content))
+	c.items = append(c.items, FormatURL(url, content))
 }
 
 // AddSearch adds search results to the context.
 func (c *Context) AddSearch(query string, results string) {
-	c.items = append(c.items, fmt.Sprintf("[Search Context]\nQuery: %s\n\n%s", query, results))
+	c.items = append(c.items, FormatSearch(query, results))
 }
 
 // String returns the full accumulated context as a single string.
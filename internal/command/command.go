@@ -45,8 +45,38 @@ func GetCommandRegistry() *CommandRegistry {
 			},
 			"/history": {
 				Name:        "/history",
-				Description: "Show the chat history",
-				Usage:       "/history",
+				Description: "Show the chat history, or every branch with /history tree",
+				Usage:       "/history [tree]",
+				Category:    "core",
+			},
+			"/branch": {
+				Name:        "/branch",
+				Description: "List, switch or name conversation branches",
+				Usage:       "/branch [list|switch [n|name]|name <name>]",
+				Category:    "core",
+			},
+			"/retry": {
+				Name:        "/retry",
+				Description: "Regenerate the last answer, optionally with another model",
+				Usage:       "/retry [model]",
+				Category:    "core",
+			},
+			"/edit": {
+				Name:        "/edit",
+				Description: "Edit one of your messages and resend it",
+				Usage:       "/edit [n]",
+				Category:    "core",
+			},
+			"/undo": {
+				Name:        "/undo",
+				Description: "Remove the last exchange",
+				Usage:       "/undo",
+				Category:    "core",
+			},
+			"/compare": {
+				Name:        "/compare",
+				Description: "Ask several models at once and keep the best answer",
+				Usage:       "/compare [models...] -- prompt",
 				Category:    "core",
 			},
 			"/search": {
@@ -129,6 +159,12 @@ func GetCommandRegistry() *CommandRegistry {
 				Usage:       "/stats",
 				Category:    "core",
 			},
+			"/doctor": {
+				Name:        "/doctor",
+				Description: "Diagnose how the VQD is acquired",
+				Usage:       "/doctor vqd",
+				Category:    "core",
+			},
 			"/update": {
 				Name:        "/update",
 				Description: "Update the CLI to the latest version",
@@ -1,6 +1,8 @@
 package config
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -8,10 +10,13 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"duckduckgo-chat-cli/internal/fingerprint"
 	"duckduckgo-chat-cli/internal/interfaces"
 	"duckduckgo-chat-cli/internal/models"
+	"duckduckgo-chat-cli/internal/proxy"
 	"duckduckgo-chat-cli/internal/ui"
 
 	"github.com/AlecAivazis/survey/v2"
@@ -29,12 +34,79 @@ type LibraryConfig struct {
 	Enabled     bool     `json:"enabled"`
 }
 
+// APIKey grants access to the API server
+type APIKey struct {
+	Name      string `json:"name"`
+	Key       string `json:"key"`
+	RateLimit int    `json:"rate_limit"` // requests per minute, 0 uses the default
+}
+
 type APIConfig struct {
-	Enabled     bool `json:"enabled"`
-	Port        int  `json:"port"`
-	Autostart   bool `json:"autostart"`
-	LogRequests bool `json:"log_requests"`
-	ShowGinLogs bool `json:"show_gin_logs"`
+	Enabled            bool     `json:"enabled"`
+	Port               int      `json:"port"`
+	Autostart          bool     `json:"autostart"`
+	LogRequests        bool     `json:"log_requests"`
+	ShowGinLogs        bool     `json:"show_gin_logs"`
+	MaxSessions        *int     `json:"max_sessions,omitempty"`         // 0 for no limit
+	SessionIdleTimeout *int     `json:"session_idle_timeout,omitempty"` // minutes, 0 never evicts idle sessions
+	Keys               []APIKey `json:"keys"`
+	RateLimit          *int     `json:"rate_limit,omitempty"` // default requests per minute, 0 for no limit
+	AllowedOrigins     []string `json:"allowed_origins"`
+	MaxQueuedRequests  *int     `json:"max_queued_requests,omitempty"` // per session, 0 for no limit
+}
+
+// Upstream chat providers
+const (
+	ProviderDuckDuckGo = "duckduckgo"
+	ProviderOpenAI     = "openai"
+)
+
+// ProviderConfig selects the upstream chat service
+type ProviderConfig struct {
+	Type    string `json:"type"`               // ProviderDuckDuckGo or ProviderOpenAI
+	BaseURL string `json:"base_url,omitempty"` // OpenAI-compatible API, e.g. http://localhost:8000/v1
+	APIKey  string `json:"api_key,omitempty"`
+	Model   string `json:"model,omitempty"` // model requested from the OpenAI-compatible server
+}
+
+// NetworkConfig tunes how requests to the upstream chat service are timed out
+// and retried
+type NetworkConfig struct {
+	ConnectTimeout   int  `json:"connect_timeout"`       // seconds to open the connection
+	FirstByteTimeout int  `json:"first_byte_timeout"`    // seconds to wait for the answer to start
+	IdleTimeout      int  `json:"idle_timeout"`          // seconds without data before a streamed answer is abandoned
+	MaxRetries       *int `json:"max_retries,omitempty"` // 0 turns retries off, nil uses the default
+	RetryBaseDelay   int  `json:"retry_base_delay"`      // milliseconds, doubled on each retry
+	BreakerThreshold int  `json:"breaker_threshold"`     // 418 answers in a row that pause requests
+	BreakerCooldown  int  `json:"breaker_cooldown"`      // seconds requests stay paused
+}
+
+// WithDefaults fills in the settings left at zero, and MaxRetries when it is
+// missing
+func (n NetworkConfig) WithDefaults() NetworkConfig {
+	if n.ConnectTimeout <= 0 {
+		n.ConnectTimeout = 10
+	}
+	if n.FirstByteTimeout <= 0 {
+		n.FirstByteTimeout = 60
+	}
+	if n.IdleTimeout <= 0 {
+		n.IdleTimeout = 60
+	}
+	if n.MaxRetries == nil || *n.MaxRetries < 0 {
+		maxRetries := 3
+		n.MaxRetries = &maxRetries
+	}
+	if n.RetryBaseDelay <= 0 {
+		n.RetryBaseDelay = 1000
+	}
+	if n.BreakerThreshold <= 0 {
+		n.BreakerThreshold = 5
+	}
+	if n.BreakerCooldown <= 0 {
+		n.BreakerCooldown = 120
+	}
+	return n
 }
 
 type Config struct {
@@ -45,6 +117,10 @@ type Config struct {
 	Search           SearchConfig      `json:"search"`
 	Library          LibraryConfig     `json:"library"`
 	API              APIConfig         `json:"api"`
+	Provider         ProviderConfig    `json:"provider"`
+	Network          NetworkConfig     `json:"network"`
+	Proxy            proxy.Config      `json:"proxy"`
+	Fingerprint      string            `json:"fingerprint_profile,omitempty"` // browser profile sent to DuckDuckGo
 	ShowMenu         bool              `json:"show_menu"`
 	GlobalPrompt     string            `json:"global_prompt"`
 	ConfirmLongInput bool              `json:"confirm_long_input"`
@@ -54,7 +130,7 @@ type Config struct {
 func Initialize() *Config {
 	cfg := loadConfig()
 	if cfg.DefaultModel == "" {
-		cfg.DefaultModel = "gpt-4o-mini"
+		cfg.DefaultModel = string(models.Default().Alias)
 	}
 	if cfg.Search.MaxResults == 0 {
 		cfg.Search.MaxResults = 10
@@ -66,6 +142,16 @@ func Initialize() *Config {
 		cfg.Search.RetryDelay = 1
 	}
 	cfg.Search.IncludeSnippet = true // default to true
+	if cfg.Provider.Type == "" {
+		cfg.Provider.Type = ProviderDuckDuckGo
+	}
+	cfg.Network = cfg.Network.WithDefaults()
+	if err := proxy.Apply(cfg.Proxy); err != nil {
+		ui.Warningln("Ignoring proxy settings: %v", err)
+	}
+	if err := fingerprint.Select(cfg.Fingerprint); err != nil {
+		ui.Warningln("%v, using %s", err, fingerprint.DefaultProfile)
+	}
 
 	// Initialize library config with defaults
 	if len(cfg.Library.Directories) == 0 {
@@ -85,6 +171,10 @@ func Initialize() *Config {
 	if cfg.API.Port == 0 {
 		cfg.API.Port = 8080 // default port
 	}
+	setDefault(&cfg.API.MaxSessions, 20)
+	setDefault(&cfg.API.SessionIdleTimeout, 30)
+	setDefault(&cfg.API.RateLimit, 60)
+	setDefault(&cfg.API.MaxQueuedRequests, 4)
 
 	// Only set defaults if no config file exists (first run) or if explicitly not set
 	if !configExists {
@@ -98,10 +188,18 @@ func Initialize() *Config {
 	return cfg
 }
 
+// setDefault gives a setting missing from the config file its default value.
+// Settings stored as pointers keep 0 when it is set explicitly.
+func setDefault(setting **int, value int) {
+	if *setting == nil || **setting < 0 {
+		*setting = &value
+	}
+}
+
 func loadConfig() *Config {
 	cfg := &Config{
 		TOSAccepted:      false,
-		DefaultModel:     "gpt-4o-mini",
+		DefaultModel:     string(models.Default().Alias),
 		ExportDir:        defaultExportPath(),
 		LastUpdateTime:   time.Now(),
 		ConfirmLongInput: true, // default to enabled for safety
@@ -209,6 +307,10 @@ func HandleConfiguration(cfg *Config, chatSession interfaces.ChatSession) {
 				"Long Input Protection",
 				"Library Settings",
 				"API Settings",
+				"Provider Settings",
+				"Network Settings",
+				"Proxy Settings",
+				"Fingerprint Profiles",
 				"Prompt Management",
 				"Back to chat",
 			},
@@ -233,6 +335,14 @@ func HandleConfiguration(cfg *Config, chatSession interfaces.ChatSession) {
 			handleLibrarySettings(cfg)
 		case "API Settings":
 			handleAPISettings(cfg)
+		case "Provider Settings":
+			handleProviderSettings(cfg)
+		case "Network Settings":
+			handleNetworkSettings(cfg)
+		case "Proxy Settings":
+			handleProxySettings(cfg)
+		case "Fingerprint Profiles":
+			handleFingerprintSettings(cfg)
 		case "Prompt Management":
 			HandlePromptManagement(cfg)
 		case "Back to chat", "":
@@ -245,16 +355,18 @@ func HandleConfiguration(cfg *Config, chatSession interfaces.ChatSession) {
 
 func handleModelChange(cfg *Config, chatSession interfaces.ChatSession) {
 	model := ""
+	var options []string
+	for _, m := range models.Offered() {
+		options = append(options, string(m.Alias))
+	}
+	current, _, ok := models.LookupModel(cfg.DefaultModel)
+	if !ok {
+		current = models.Default().Alias
+	}
 	prompt := &survey.Select{
 		Message: "Choose Default Model:",
-		Options: []string{
-			"gpt-4o-mini",
-			"claude-3-haiku",
-			"llama",
-			"mixtral",
-			"o4mini",
-		},
-		Default: cfg.DefaultModel,
+		Options: options,
+		Default: string(current),
 	}
 	survey.AskOne(prompt, &model)
 
@@ -333,6 +445,226 @@ func handleSearchSettings(cfg *Config) {
 	}
 }
 
+func handleProviderSettings(cfg *Config) {
+	providerType := ""
+	typePrompt := &survey.Select{
+		Message: "Upstream chat provider:",
+		Options: []string{ProviderDuckDuckGo, ProviderOpenAI},
+		Default: cfg.Provider.Type,
+		Help:    "openai talks to any OpenAI-compatible server, such as llama.cpp, vLLM or Ollama.",
+	}
+	if err := survey.AskOne(typePrompt, &providerType); err != nil {
+		return
+	}
+
+	if providerType == ProviderOpenAI {
+		qs := []*survey.Question{
+			{
+				Name:     "base_url",
+				Prompt:   &survey.Input{Message: "API base URL:", Default: cfg.Provider.BaseURL, Help: "For example http://localhost:8000/v1"},
+				Validate: survey.Required,
+			},
+			{
+				Name:   "model",
+				Prompt: &survey.Input{Message: "Model:", Default: cfg.Provider.Model},
+			},
+			{
+				Name:   "api_key",
+				Prompt: &survey.Password{Message: "API key (leave empty to keep the current one):"},
+			},
+		}
+		answers := struct {
+			BaseURL string `survey:"base_url"`
+			Model   string `survey:"model"`
+			APIKey  string `survey:"api_key"`
+		}{}
+		if err := survey.Ask(qs, &answers); err != nil {
+			ui.Errorln("Error reading input: %v", err)
+			return
+		}
+		cfg.Provider.BaseURL = strings.TrimRight(strings.TrimSpace(answers.BaseURL), "/")
+		cfg.Provider.Model = strings.TrimSpace(answers.Model)
+		if answers.APIKey != "" {
+			cfg.Provider.APIKey = answers.APIKey
+		}
+	}
+	cfg.Provider.Type = providerType
+
+	if err := saveConfig(cfg); err != nil {
+		ui.Errorln("Error saving config: %v", err)
+	} else {
+		ui.AIln("Provider set to %s. Restart the app to apply it.", providerType)
+	}
+}
+
+func handleNetworkSettings(cfg *Config) {
+	for {
+		choice := ""
+		prompt := &survey.Select{
+			Message: "Network Settings (applied on restart)",
+			Options: []string{
+				fmt.Sprintf("Connect Timeout (%ds)", cfg.Network.ConnectTimeout),
+				fmt.Sprintf("First Byte Timeout (%ds)", cfg.Network.FirstByteTimeout),
+				fmt.Sprintf("Idle Timeout (%ds)", cfg.Network.IdleTimeout),
+				fmt.Sprintf("Max Retries (%d)", *cfg.Network.MaxRetries),
+				fmt.Sprintf("Retry Base Delay (%dms)", cfg.Network.RetryBaseDelay),
+				fmt.Sprintf("Circuit Breaker Threshold (%d)", cfg.Network.BreakerThreshold),
+				fmt.Sprintf("Circuit Breaker Cooldown (%ds)", cfg.Network.BreakerCooldown),
+				"Back",
+			},
+			Default: "Back",
+		}
+		survey.AskOne(prompt, &choice)
+
+		switch {
+		case strings.HasPrefix(choice, "Connect Timeout"):
+			handleAPIIntChange(cfg, "Enter connect timeout (seconds):", &cfg.Network.ConnectTimeout, "Connect timeout (seconds)")
+		case strings.HasPrefix(choice, "First Byte Timeout"):
+			handleAPIIntChange(cfg, "Enter first byte timeout (seconds):", &cfg.Network.FirstByteTimeout, "First byte timeout (seconds)")
+		case strings.HasPrefix(choice, "Idle Timeout"):
+			handleAPIIntChange(cfg, "Enter idle timeout between chunks (seconds):", &cfg.Network.IdleTimeout, "Idle timeout (seconds)")
+		case strings.HasPrefix(choice, "Max Retries"):
+			handleCountChange(cfg, "Enter maximum number of retries (0 to disable):", cfg.Network.MaxRetries, "Max retries")
+		case strings.HasPrefix(choice, "Retry Base Delay"):
+			handleAPIIntChange(cfg, "Enter delay before the first retry (milliseconds):", &cfg.Network.RetryBaseDelay, "Retry base delay (ms)")
+		case strings.HasPrefix(choice, "Circuit Breaker Threshold"):
+			handleAPIIntChange(cfg, "Enter number of 418 errors in a row that pause requests:", &cfg.Network.BreakerThreshold, "Circuit breaker threshold")
+		case strings.HasPrefix(choice, "Circuit Breaker Cooldown"):
+			handleAPIIntChange(cfg, "Enter how long requests stay paused (seconds):", &cfg.Network.BreakerCooldown, "Circuit breaker cooldown (seconds)")
+		case choice == "Back":
+			return
+		}
+	}
+}
+
+func handleProxySettings(cfg *Config) {
+	qs := []*survey.Question{
+		{
+			Name:   "url",
+			Prompt: &survey.Input{Message: "Proxy URL (leave empty to connect directly):", Default: cfg.Proxy.URL, Help: "For example http://proxy.corp:3128 or socks5://proxy.corp:1080"},
+		},
+		{
+			Name:   "username",
+			Prompt: &survey.Input{Message: "Username (optional):", Default: cfg.Proxy.Username},
+		},
+		{
+			Name:   "password",
+			Prompt: &survey.Password{Message: "Password (leave empty to keep the current one):"},
+		},
+		{
+			Name:   "no_proxy",
+			Prompt: &survey.Input{Message: "Hosts reached directly, comma-separated:", Default: strings.Join(cfg.Proxy.NoProxy, ","), Help: "Hosts, .domains, IPs and CIDR ranges, e.g. .corp.local,10.0.0.0/8"},
+		},
+	}
+	answers := struct {
+		URL      string `survey:"url"`
+		Username string `survey:"username"`
+		Password string `survey:"password"`
+		NoProxy  string `survey:"no_proxy"`
+	}{}
+	if err := survey.Ask(qs, &answers); err != nil {
+		ui.Errorln("Error reading input: %v", err)
+		return
+	}
+
+	updated := proxy.Config{
+		URL:      strings.TrimSpace(answers.URL),
+		Username: strings.TrimSpace(answers.Username),
+		Password: cfg.Proxy.Password,
+	}
+	if answers.Password != "" {
+		updated.Password = answers.Password
+	}
+	for _, host := range strings.Split(answers.NoProxy, ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			updated.NoProxy = append(updated.NoProxy, host)
+		}
+	}
+	if err := proxy.Validate(updated); err != nil {
+		ui.Errorln("%v. No changes made.", err)
+		return
+	}
+
+	cfg.Proxy = updated
+	if err := saveConfig(cfg); err != nil {
+		ui.Errorln("Error saving config: %v", err)
+	} else if updated.URL == "" {
+		ui.AIln("Proxy removed. Restart the app to apply it.")
+	} else {
+		ui.AIln("Proxy set to %s. Restart the app to apply it.", updated.URL)
+	}
+}
+
+func handleFingerprintSettings(cfg *Config) {
+	for {
+		current := fingerprint.Current()
+		profiles := fingerprint.List()
+
+		var options []string
+		for _, p := range profiles {
+			option := p.Label()
+			if p.Description != "" {
+				option += " - " + p.Description
+			}
+			if record, ok := fingerprint.Blocks(p); ok {
+				option += fmt.Sprintf(" [418 x%d, last %s]", record.Count, record.Last.Format("2006-01-02 15:04"))
+			}
+			if p.Name == current.Name {
+				option += " (current)"
+			}
+			options = append(options, option)
+		}
+		options = append(options, "Import a profile file", "Reload profiles", "Back")
+
+		var choice int
+		prompt := &survey.Select{
+			Message: "Fingerprint Profiles",
+			Options: options,
+			Help:    fmt.Sprintf("Profiles are JSON files in %s, reloaded when they change.", fingerprint.Dir()),
+		}
+		if err := survey.AskOne(prompt, &choice); err != nil {
+			return
+		}
+
+		switch {
+		case choice < len(profiles):
+			selectFingerprint(cfg, profiles[choice].Name)
+		case options[choice] == "Import a profile file":
+			path := ""
+			survey.AskOne(&survey.Input{Message: "Path of the profile file:"}, &path)
+			path = strings.TrimSpace(path)
+			if path == "" {
+				continue
+			}
+			profile, err := fingerprint.Import(path)
+			if err != nil {
+				ui.Errorln("Import failed: %v", err)
+				continue
+			}
+			ui.AIln("Imported profile %s", profile.Label())
+			use := false
+			survey.AskOne(&survey.Confirm{Message: "Use it now?", Default: true}, &use)
+			if use {
+				selectFingerprint(cfg, profile.Name)
+			}
+		case options[choice] == "Reload profiles":
+			ui.AIln("%d profiles loaded.", len(fingerprint.Reload()))
+		default:
+			return
+		}
+	}
+}
+
+// selectFingerprint makes a profile the current one and saves the choice
+func selectFingerprint(cfg *Config, name string) {
+	if err := fingerprint.Select(name); err != nil {
+		ui.Errorln("%v", err)
+		return
+	}
+	cfg.Fingerprint = name
+	saveAndReport(cfg, fmt.Sprintf("Fingerprint profile set to %s. It applies from the next VQD (use /clear to get one now).", name))
+}
+
 func handleShowMenuChange(cfg *Config) {
 	showMenu := false
 	prompt := &survey.Confirm{
@@ -400,6 +732,12 @@ func handleAPISettings(cfg *Config) {
 				fmt.Sprintf("Autostart on launch (%t)", cfg.API.Autostart),
 				fmt.Sprintf("Log API Requests (%t)", cfg.API.LogRequests),
 				fmt.Sprintf("Show GIN Logs (%t)", cfg.API.ShowGinLogs),
+				fmt.Sprintf("Max Sessions (%d)", *cfg.API.MaxSessions),
+				fmt.Sprintf("Session Idle Timeout (%d min)", *cfg.API.SessionIdleTimeout),
+				fmt.Sprintf("API Keys (%d)", len(cfg.API.Keys)),
+				fmt.Sprintf("Default Rate Limit (%d/min)", *cfg.API.RateLimit),
+				fmt.Sprintf("Allowed Origins (%d)", len(cfg.API.AllowedOrigins)),
+				fmt.Sprintf("Max Queued Requests (%d)", *cfg.API.MaxQueuedRequests),
 				"Back",
 			},
 			Default: "Back",
@@ -421,6 +759,18 @@ func handleAPISettings(cfg *Config) {
 		case strings.HasPrefix(choice, "Show GIN Logs"):
 			cfg.API.ShowGinLogs = !cfg.API.ShowGinLogs
 			saveAndReport(cfg, fmt.Sprintf("GIN Logs visibility set to: %t", cfg.API.ShowGinLogs))
+		case strings.HasPrefix(choice, "Max Sessions"):
+			handleCountChange(cfg, "Enter maximum number of API sessions (0 for no limit):", cfg.API.MaxSessions, "Max API sessions")
+		case strings.HasPrefix(choice, "Session Idle Timeout"):
+			handleCountChange(cfg, "Enter session idle timeout (minutes, 0 to keep idle sessions):", cfg.API.SessionIdleTimeout, "API session idle timeout (minutes)")
+		case strings.HasPrefix(choice, "API Keys"):
+			handleAPIKeys(cfg)
+		case strings.HasPrefix(choice, "Default Rate Limit"):
+			handleCountChange(cfg, "Enter default rate limit (requests per minute, 0 for no limit):", cfg.API.RateLimit, "Default API rate limit (requests/min)")
+		case strings.HasPrefix(choice, "Allowed Origins"):
+			handleAllowedOrigins(cfg)
+		case strings.HasPrefix(choice, "Max Queued Requests"):
+			handleCountChange(cfg, "Enter maximum number of requests waiting for a session (0 for no limit):", cfg.API.MaxQueuedRequests, "Max queued requests per session")
 		case choice == "Back":
 			return
 		}
@@ -443,6 +793,220 @@ func handleAPIPortChange(cfg *Config) {
 	}
 }
 
+func handleAPIIntChange(cfg *Config, message string, value *int, label string) {
+	valueStr := ""
+	prompt := &survey.Input{
+		Message: message,
+		Default: strconv.Itoa(*value),
+	}
+	survey.AskOne(prompt, &valueStr)
+
+	if parsed, err := strconv.Atoi(valueStr); err == nil && parsed > 0 {
+		*value = parsed
+		saveAndReport(cfg, fmt.Sprintf("%s updated to: %d", label, parsed))
+	} else {
+		ui.Errorln("Invalid value. No changes made.")
+	}
+}
+
+// handleCountChange is handleAPIIntChange for settings where 0 is meaningful
+func handleCountChange(cfg *Config, message string, value *int, label string) {
+	valueStr := ""
+	prompt := &survey.Input{
+		Message: message,
+		Default: strconv.Itoa(*value),
+	}
+	survey.AskOne(prompt, &valueStr)
+
+	if parsed, err := strconv.Atoi(valueStr); err == nil && parsed >= 0 {
+		*value = parsed
+		saveAndReport(cfg, fmt.Sprintf("%s updated to: %d", label, parsed))
+	} else {
+		ui.Errorln("Invalid value. No changes made.")
+	}
+}
+
+func handleAPIKeys(cfg *Config) {
+	for {
+		if len(cfg.API.Keys) == 0 {
+			ui.Warningln("No API keys configured: the API server accepts unauthenticated requests.")
+		}
+
+		choice := ""
+		prompt := &survey.Select{
+			Message: "API Keys",
+			Options: []string{"List Keys", "Add Key", "Remove Key", "Back"},
+			Default: "Back",
+		}
+		survey.AskOne(prompt, &choice)
+
+		switch choice {
+		case "List Keys":
+			if len(cfg.API.Keys) == 0 {
+				ui.AIln("No API keys saved.")
+				continue
+			}
+			ui.AIln("API keys:")
+			for _, key := range cfg.API.Keys {
+				limit := "default"
+				if key.RateLimit > 0 {
+					limit = fmt.Sprintf("%d/min", key.RateLimit)
+				}
+				ui.AIln("- %s: %s (rate limit: %s)", key.Name, maskAPIKey(key.Key), limit)
+			}
+		case "Add Key":
+			name := ""
+			survey.AskOne(&survey.Input{Message: "Key name:"}, &name)
+			name = strings.TrimSpace(name)
+			if name == "" {
+				ui.Errorln("Name required.")
+				continue
+			}
+			limitStr := ""
+			survey.AskOne(&survey.Input{Message: "Rate limit (requests per minute, 0 for default):", Default: "0"}, &limitStr)
+			limit, err := strconv.Atoi(limitStr)
+			if err != nil || limit < 0 {
+				ui.Errorln("Invalid rate limit. No changes made.")
+				continue
+			}
+			key, err := AddAPIKey(cfg, name, limit)
+			if err != nil {
+				ui.Errorln("%v", err)
+				continue
+			}
+			ui.AIln("API key '%s' added. Copy it now, it will not be shown again:", name)
+			ui.AIln("%s", key)
+		case "Remove Key":
+			if len(cfg.API.Keys) == 0 {
+				ui.AIln("No API keys to remove.")
+				continue
+			}
+			names := make([]string, len(cfg.API.Keys))
+			for i, key := range cfg.API.Keys {
+				names[i] = key.Name
+			}
+			name := ""
+			survey.AskOne(&survey.Select{Message: "Select key to remove:", Options: names}, &name)
+			if err := RemoveAPIKey(cfg, name); err != nil {
+				ui.Errorln("%v", err)
+			} else {
+				ui.AIln("API key '%s' removed.", name)
+			}
+		case "Back", "":
+			return
+		}
+	}
+}
+
+func handleAllowedOrigins(cfg *Config) {
+	for {
+		choice := ""
+		prompt := &survey.Select{
+			Message: "Allowed CORS Origins",
+			Options: []string{"List Origins", "Add Origin", "Remove Origin", "Back"},
+			Default: "Back",
+		}
+		survey.AskOne(prompt, &choice)
+
+		switch choice {
+		case "List Origins":
+			if len(cfg.API.AllowedOrigins) == 0 {
+				ui.AIln("No origins allowed: browsers can only call the API from the same origin.")
+				continue
+			}
+			ui.AIln("Allowed origins:")
+			for _, origin := range cfg.API.AllowedOrigins {
+				ui.AIln("- %s", origin)
+			}
+		case "Add Origin":
+			origin := ""
+			survey.AskOne(&survey.Input{
+				Message: "Origin (e.g. http://localhost:3000, or * for any):",
+			}, &origin)
+			origin = strings.TrimRight(strings.TrimSpace(origin), "/")
+			if origin == "" {
+				ui.Errorln("Origin required.")
+				continue
+			}
+			cfg.API.AllowedOrigins = append(cfg.API.AllowedOrigins, origin)
+			saveAndReport(cfg, fmt.Sprintf("Origin '%s' allowed.", origin))
+		case "Remove Origin":
+			if len(cfg.API.AllowedOrigins) == 0 {
+				ui.AIln("No origins to remove.")
+				continue
+			}
+			origin := ""
+			survey.AskOne(&survey.Select{Message: "Select origin to remove:", Options: cfg.API.AllowedOrigins}, &origin)
+			for i, allowed := range cfg.API.AllowedOrigins {
+				if allowed == origin {
+					cfg.API.AllowedOrigins = append(cfg.API.AllowedOrigins[:i], cfg.API.AllowedOrigins[i+1:]...)
+					break
+				}
+			}
+			saveAndReport(cfg, fmt.Sprintf("Origin '%s' removed.", origin))
+		case "Back", "":
+			return
+		}
+	}
+}
+
+// AddAPIKey generates a new API key, saves it and returns its value
+func AddAPIKey(cfg *Config, name string, rateLimit int) (string, error) {
+	apiKeysMu.Lock()
+	defer apiKeysMu.Unlock()
+
+	for _, key := range cfg.API.Keys {
+		if key.Name == name {
+			return "", fmt.Errorf("API key '%s' already exists", name)
+		}
+	}
+
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("error generating API key: %v", err)
+	}
+	key := "dck_" + hex.EncodeToString(b)
+
+	keys := make([]APIKey, 0, len(cfg.API.Keys)+1)
+	cfg.API.Keys = append(append(keys, cfg.API.Keys...), APIKey{Name: name, Key: key, RateLimit: rateLimit})
+	return key, saveConfig(cfg)
+}
+
+// RemoveAPIKey removes the API key with the given name
+func RemoveAPIKey(cfg *Config, name string) error {
+	apiKeysMu.Lock()
+	defer apiKeysMu.Unlock()
+
+	for i, key := range cfg.API.Keys {
+		if key.Name == name {
+			keys := make([]APIKey, 0, len(cfg.API.Keys)-1)
+			cfg.API.Keys = append(append(keys, cfg.API.Keys[:i]...), cfg.API.Keys[i+1:]...)
+			return saveConfig(cfg)
+		}
+	}
+	return fmt.Errorf("API key '%s' not found", name)
+}
+
+// apiKeysMu guards cfg.API.Keys, which the API server reads on every request
+// while /config may change it. The slice is replaced, never modified, so a
+// list returned by APIKeys stays valid.
+var apiKeysMu sync.RWMutex
+
+// APIKeys returns the configured API keys. The list must not be modified.
+func APIKeys(cfg *Config) []APIKey {
+	apiKeysMu.RLock()
+	defer apiKeysMu.RUnlock()
+	return cfg.API.Keys
+}
+
+// maskAPIKey hides all but the start and end of a key
+func maskAPIKey(key string) string {
+	if len(key) <= 12 {
+		return strings.Repeat("*", len(key))
+	}
+	return key[:8] + "..." + key[len(key)-4:]
+}
+
 func handleLongInputProtectionChange(cfg *Config) {
 	confirmLongInput := cfg.ConfirmLongInput
 	prompt := &survey.Confirm{
@@ -0,0 +1,70 @@
+package fingerprint
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// BlockRecord counts the 418 answers received with a version of a profile.
+// A profile that keeps collecting them has gone stale.
+type BlockRecord struct {
+	Profile string    `json:"profile"`
+	Version string    `json:"version"`
+	Count   int       `json:"count"`
+	First   time.Time `json:"first"`
+	Last    time.Time `json:"last"`
+}
+
+var blocksMu sync.Mutex
+
+// blocksPath returns the file of the block records. It is kept out of the
+// profile directory, where every JSON file is a profile.
+func blocksPath() string {
+	return filepath.Join(filepath.Dir(Dir()), "fingerprint_blocks.json")
+}
+
+func blockKey(p Profile) string {
+	return p.Name + "@" + p.Version
+}
+
+func readBlocks() map[string]BlockRecord {
+	blocks := make(map[string]BlockRecord)
+	if data, err := os.ReadFile(blocksPath()); err == nil {
+		json.Unmarshal(data, &blocks)
+	}
+	return blocks
+}
+
+// RecordBlocked notes that DuckDuckGo answered 418 while the profile was in
+// use. Failing to save the record is not worth interrupting the chat for.
+func RecordBlocked(p Profile) BlockRecord {
+	blocksMu.Lock()
+	defer blocksMu.Unlock()
+
+	blocks := readBlocks()
+	record := blocks[blockKey(p)]
+	now := time.Now()
+	if record.Count == 0 {
+		record = BlockRecord{Profile: p.Name, Version: p.Version, First: now}
+	}
+	record.Count++
+	record.Last = now
+	blocks[blockKey(p)] = record
+
+	if data, err := json.MarshalIndent(blocks, "", "  "); err == nil {
+		os.MkdirAll(filepath.Dir(blocksPath()), 0755)
+		os.WriteFile(blocksPath(), data, 0644)
+	}
+	return record
+}
+
+// Blocks returns the 418 answers received with this version of the profile
+func Blocks(p Profile) (BlockRecord, bool) {
+	blocksMu.Lock()
+	defer blocksMu.Unlock()
+	record, ok := readBlocks()[blockKey(p)]
+	return record, ok
+}
@@ -0,0 +1,308 @@
+package fingerprint
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"duckduckgo-chat-cli/internal/ui"
+
+	"github.com/fatih/color"
+)
+
+// FormatVersion is the version of the profile file format. Files written for
+// a newer format are skipped.
+const FormatVersion = 1
+
+// DefaultProfile is the profile used when none is chosen
+const DefaultProfile = "brave-138-windows"
+
+// reloadInterval is how often the profile directory is checked for changes
+const reloadInterval = 2 * time.Second
+
+// Profile is the browser fingerprint sent to DuckDuckGo: the headers of the
+// browser the requests pass for, and the challenge values captured from it.
+// DuckDuckGo starts answering 418 once they go stale.
+type Profile struct {
+	Format          int    `json:"format"`
+	Name            string `json:"name"`
+	Version         string `json:"version"` // revision of the profile, e.g. the date it was captured
+	Description     string `json:"description,omitempty"`
+	UserAgent       string `json:"user_agent"`
+	SecCHUA         string `json:"sec_ch_ua"`
+	SecCHUAMobile   string `json:"sec_ch_ua_mobile"`
+	SecCHUAPlatform string `json:"sec_ch_ua_platform"`
+	AcceptLanguage  string `json:"accept_language"`
+	VqdHash1        string `json:"vqd_hash_1"` // x-vqd-hash-1 header
+	FeSignals       string `json:"fe_signals"` // x-fe-signals header
+	FeVersion       string `json:"fe_version"` // x-fe-version header
+
+	Path string `json:"-"` // file the profile was loaded from, empty for built-in profiles
+}
+
+// Label names the profile and its version
+func (p Profile) Label() string {
+	if p.Version == "" {
+		return p.Name
+	}
+	return fmt.Sprintf("%s (%s)", p.Name, p.Version)
+}
+
+// SetHeaders sets the browser identification headers of a request
+func (p Profile) SetHeaders(h http.Header) {
+	h.Set("User-Agent", p.UserAgent)
+	h.Set("Sec-CH-UA", p.SecCHUA)
+	h.Set("Sec-CH-UA-Mobile", p.SecCHUAMobile)
+	h.Set("Sec-CH-UA-Platform", p.SecCHUAPlatform)
+	h.Set("Accept-Language", p.AcceptLanguage)
+}
+
+var builtinProfiles = []Profile{
+	{
+		Format:          FormatVersion,
+		Name:            DefaultProfile,
+		Version:         "2025-07-10",
+		Description:     "Brave 138 on Windows",
+		UserAgent:       "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/138.0.0.0 Safari/537.36",
+		SecCHUA:         `"Not)A;Brand";v="8", "Chromium";v="138", "Brave";v="138"`,
+		SecCHUAMobile:   "?0",
+		SecCHUAPlatform: `"Windows"`,
+		AcceptLanguage:  "fr-FR,fr;q=0.6",
+		VqdHash1:        "eyJzZXJ2ZXJfaGFzaGVzIjpbImRQSlJJTWczZnFYQXIvaStaa3c2cEpFVzEwckdTdmxJVlVkNlFsOVRGWXc9IiwiMUN3Qzg3N0Q3WXE1dzlEeTc4UjhBVi9qZVZWaUlYbmV0Q0xvckx3c01QZz0iLCJQSzc3TGc2L25weDdWQ2J2UWxsTEhBR3cyenJIVmEvQUFBRFBhQTl1ekVRPSJdLCJjbGllbnRfaGFzaGVzIjpbImxWblI0MStCMVFWZ0o4d0hhMUdBNmdxR0JoSjlWdjN5K0dISkdGekJmTGM9IiwiVS9RRUc2RE1qdEU4V2hHU1FxOUU1Z0VGNmw1SWJrNk9NVlBuY01DU1licz0iLCJ6SURsYUNvZG9JUjNwbTNSVTlWOUJXaUJkZDJqenRMODAyN0VYTHhkWll3PSJdLCJzaWduYWxzIjp7fSwibWV0YSI6eyJ2IjoiNCIsImNoYWxsZW5nZV9pZCI6ImM4M2Q0ZTc5NTU2MjJmZjU3Mzc0ZDUzOTk2ZjliMmJhZGE2ZDQxZTMzNDM1ZjVlNzMyYjFmNmZjNmQ0ZTE1NzVoOGpidCIsInRpbWVzdGFtcCI6IjE3NTIxNTU3Nzc4NjYiLCJvcmlnaW4iOiJodHRwczovL2R1Y2tkdWNrZ28uY29tIiwic3RhY2siOiJFcnJvclxuYXQgRSAoaHR0cHM6Ly9kdWNrZHVja2dvLmNvbS9kaXN0L3dwbS5jaGF0LjcwZWFjYTZhZWEyOTQ4YjBiYjYwLmpzOjE6MTQ4MjUpXG5hdCBhc3luYyBodHRwczovL2R1Y2tkdWNrZ28uY29tL2Rpc3Qvd3BtLmNoYXQuNzBlYWNhNmFlYTI5NDhiMGJiNjAuanM6MToxNjk4NSIsImR1cmF0aW9uIjoiNTgifX0=",
+		FeSignals:       "eyJzdGFydCI6MTc1MjE1NTc3NzQ4MCwiZXZlbnRzIjpbeyJuYW1lIjoic3RhcnROZXdDaGF0IiwiZGVsdGEiOjc1fSx7Im5hbWUiOiJyZWNlbnRDaGF0c0xpc3RJbXByZXNzaW9uIiwiZGVsdGEiOjEyNH1dLCJlbmQiOjQzNDN9",
+		FeVersion:       "serp_20250710_090702_ET-70eaca6aea2948b0bb60",
+	},
+}
+
+// store holds the built-in profiles merged with the profile directory. It is
+// loaded on first use and reloaded when the directory changes.
+var store struct {
+	mu       sync.Mutex
+	loaded   bool
+	profiles []Profile
+	selected string
+	stamp    string
+	checked  time.Time
+}
+
+// Dir returns the directory of the user's profiles, next to the
+// configuration file
+func Dir() string {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		if runtime.GOOS == "windows" {
+			configDir = filepath.Join(os.Getenv("USERPROFILE"), ".config")
+		} else {
+			configDir = filepath.Join(os.Getenv("HOME"), ".config")
+		}
+	}
+	return filepath.Join(configDir, "duckduckgo-chat-cli", "profiles")
+}
+
+// refresh loads the profiles, or reloads them if the directory changed since
+// the last check. The caller must hold store.mu.
+func refresh(force bool) {
+	if !force && store.loaded && time.Since(store.checked) < reloadInterval {
+		return
+	}
+	store.checked = time.Now()
+
+	stamp := dirStamp(Dir())
+	if !force && store.loaded && stamp == store.stamp {
+		return
+	}
+
+	profiles, errs := loadProfiles(builtinProfiles, Dir())
+	for _, err := range errs {
+		ui.WarningColor.Fprintf(color.Error, "Warning: ignoring fingerprint profile: %v\n", err)
+	}
+	if store.loaded {
+		ui.Mutedln("🔄 Fingerprint profiles reloaded")
+	}
+	store.profiles = profiles
+	store.stamp = stamp
+	store.loaded = true
+}
+
+// dirStamp summarizes the names, sizes and modification times of the
+// profile files, so that changes can be noticed without reading them
+func dirStamp(dir string) string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+	var stamp strings.Builder
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		if info, err := entry.Info(); err == nil {
+			fmt.Fprintf(&stamp, "%s:%d:%d;", entry.Name(), info.Size(), info.ModTime().UnixNano())
+		}
+	}
+	return stamp.String()
+}
+
+// loadProfiles merges the profile files into the built-in profiles. A file
+// naming a built-in profile changes only the fields it sets; the fields a new
+// profile leaves out are taken from the default profile. A missing directory
+// is not an error, and a broken file only skips that profile.
+func loadProfiles(base []Profile, dir string) ([]Profile, []error) {
+	merged := append([]Profile(nil), base...)
+
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	sort.Strings(paths)
+	var errs []error
+	for _, path := range paths {
+		profile, err := readProfile(path, merged)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		replaced := false
+		for i := range merged {
+			if merged[i].Name == profile.Name {
+				merged[i] = profile
+				replaced = true
+			}
+		}
+		if !replaced {
+			merged = append(merged, profile)
+		}
+	}
+	return merged, errs
+}
+
+// readProfile reads a profile file onto the profile it overrides
+func readProfile(path string, known []Profile) (Profile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Profile{}, err
+	}
+
+	var key struct {
+		Format int    `json:"format"`
+		Name   string `json:"name"`
+	}
+	if err := json.Unmarshal(data, &key); err != nil {
+		return Profile{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if key.Format > FormatVersion {
+		return Profile{}, fmt.Errorf("%s: format %d is newer than this version supports (%d)", path, key.Format, FormatVersion)
+	}
+	if key.Name == "" {
+		key.Name = strings.TrimSuffix(filepath.Base(path), ".json")
+	}
+
+	// Unmarshalling onto the profile it overrides keeps the fields left out
+	profile := known[0]
+	profile.Version, profile.Description = "", ""
+	for _, p := range known {
+		if p.Name == key.Name {
+			profile = p
+		}
+	}
+	if err := json.Unmarshal(data, &profile); err != nil {
+		return Profile{}, fmt.Errorf("%s: %w", path, err)
+	}
+	profile.Format = FormatVersion
+	profile.Name = key.Name
+	profile.Path = path
+	if profile.UserAgent == "" || profile.VqdHash1 == "" {
+		return Profile{}, fmt.Errorf("%s: a profile needs a user_agent and a vqd_hash_1", path)
+	}
+	return profile, nil
+}
+
+// List returns every profile, built-in ones first
+func List() []Profile {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	refresh(false)
+	return append([]Profile(nil), store.profiles...)
+}
+
+// Current returns the selected profile, picking up changes made to the
+// profile files since the last call
+func Current() Profile {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	refresh(false)
+
+	for _, p := range store.profiles {
+		if p.Name == store.selected {
+			return p
+		}
+	}
+	for _, p := range store.profiles {
+		if p.Name == DefaultProfile {
+			return p
+		}
+	}
+	return builtinProfiles[0]
+}
+
+// Select makes a profile the current one. An empty name selects the default.
+func Select(name string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	refresh(false)
+
+	if name == "" {
+		name = DefaultProfile
+	}
+	for _, p := range store.profiles {
+		if p.Name == name {
+			store.selected = name
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown fingerprint profile %q", name)
+}
+
+// Reload reads the profile files again
+func Reload() []Profile {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	refresh(true)
+	return append([]Profile(nil), store.profiles...)
+}
+
+// Import validates a profile file and copies it into the profile directory,
+// where it is picked up at once
+func Import(path string) (Profile, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	refresh(false)
+
+	profile, err := readProfile(path, store.profiles)
+	if err != nil {
+		return Profile{}, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Profile{}, err
+	}
+
+	if err := os.MkdirAll(Dir(), 0755); err != nil {
+		return Profile{}, fmt.Errorf("failed to create profile directory: %w", err)
+	}
+	if filepath.Base(profile.Name) != profile.Name || strings.HasPrefix(profile.Name, ".") {
+		return Profile{}, fmt.Errorf("invalid profile name %q", profile.Name)
+	}
+	profile.Path = filepath.Join(Dir(), profile.Name+".json")
+	if err := os.WriteFile(profile.Path, data, 0644); err != nil {
+		return Profile{}, fmt.Errorf("failed to save profile: %w", err)
+	}
+
+	refresh(true)
+	return profile, nil
+}
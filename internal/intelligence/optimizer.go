@@ -18,7 +18,8 @@ type ContextOptimizer struct {
 	ImportanceThreshold float64 // Minimum importance score to keep content
 }
 
-// Message represents a chat message for optimization
+// Message represents a chat message for optimization. It is also the format
+// of the messages of saved sessions, so it carries the chat message metadata.
 type Message struct {
 	Content    string    `json:"content"`
 	Role       string    `json:"role"`
@@ -26,6 +27,11 @@ type Message struct {
 	Importance float64   `json:"importance"`
 	Hash       uint64    `json:"hash"`
 	Compressed bool      `json:"compressed"`
+
+	ID      string        `json:"id,omitempty"`
+	Model   string        `json:"model,omitempty"`
+	Latency time.Duration `json:"latency,omitempty"`
+	Source  string        `json:"source,omitempty"`
 }
 
 // ContextAnalysis provides insights about the current context
@@ -22,46 +22,6 @@ const (
 	MinChromeVersion = "115.0.5790.110"
 )
 
-type Model string
-type ModelAlias string
-
-const (
-	GPT4Mini Model = "gpt-4o-mini"
-	Claude3  Model = "claude-3-haiku-20240307"
-	Llama    Model = "meta-llama/Llama-3.3-70B-Instruct-Turbo"
-	Mixtral  Model = "mistralai/Mistral-Small-24B-Instruct-2501"
-	o4mini   Model = "o4-mini"
-
-	GPT4MiniAlias ModelAlias = "gpt-4o-mini"
-	Claude3Alias  ModelAlias = "claude-3-haiku"
-	LlamaAlias    ModelAlias = "llama"
-	MixtralAlias  ModelAlias = "mixtral"
-	o4miniAlias   ModelAlias = "o4mini"
-)
-
-var modelMap = map[ModelAlias]Model{
-	GPT4MiniAlias: GPT4Mini,
-	Claude3Alias:  Claude3,
-	LlamaAlias:    Llama,
-	MixtralAlias:  Mixtral,
-	o4miniAlias:   o4mini,
-}
-
-var modelDisplayMap = map[Model]string{
-	GPT4Mini: "GPT-4o-mini",
-	Claude3:  "Claude-3-haiku",
-	Llama:    "Llama 3.3",
-	Mixtral:  "Mistral Small 3",
-	o4mini:   "o4-mini",
-}
-
-func GetModel(alias string) Model {
-	if model, ok := modelMap[ModelAlias(alias)]; ok {
-		return model
-	}
-	return GPT4Mini // default model
-}
-
 func CheckChromeVersion() {
 	version, err := getChromeVersion()
 	if err != nil {
@@ -201,36 +161,35 @@ func extractWindowsVersion(output string) string {
 func HandleModelChange(chat interface{}, modelArg string) ModelAlias {
 	// If a model argument is provided, try to use it directly
 	if modelArg != "" {
-		for alias, model := range modelMap {
-			if strings.EqualFold(modelArg, string(alias)) || strings.EqualFold(modelArg, string(model)) {
-				return alias
-			}
+		if alias, _, ok := LookupModel(modelArg); ok {
+			return alias
 		}
 		ui.Errorln("Invalid model choice: %s", modelArg)
 		return ""
 	}
 
 	// Show an interactive menu if no argument is provided
-	modelOptions := []string{
-		"GPT-4o-mini",
-		"Claude-3-haiku",
-		"Llama 3.3",
-		"Mistral Small 3",
-		"o4-mini",
-		"Cancel",
-	}
-
+	offered := Offered()
+	modelOptions := make([]string, 0, len(offered)+1)
+	defaultOption := ""
 	currentModel := GetCurrentModel(chat)
-	defaultModel, ok := modelDisplayMap[currentModel]
-	if !ok {
-		defaultModel = "GPT-4o-mini" // Fallback
+	for _, m := range offered {
+		option := m.DisplayName
+		if len(m.Strengths) > 0 {
+			option += fmt.Sprintf(" (%s)", strings.Join(m.Strengths, ", "))
+		}
+		modelOptions = append(modelOptions, option)
+		if m.ID == currentModel || (defaultOption == "" && m.Default) {
+			defaultOption = option
+		}
 	}
+	modelOptions = append(modelOptions, "Cancel")
 
-	var choice string
+	var choice int
 	prompt := &survey.Select{
 		Message: "Choose a new model:",
 		Options: modelOptions,
-		Default: defaultModel,
+		Default: defaultOption,
 	}
 	err := survey.AskOne(prompt, &choice, survey.WithStdio(os.Stdin, os.Stdout, os.Stderr))
 	if err != nil {
@@ -238,23 +197,11 @@ func HandleModelChange(chat interface{}, modelArg string) ModelAlias {
 		return ""
 	}
 
-	switch strings.ToLower(choice) {
-	case "gpt-4o-mini":
-		return GPT4MiniAlias
-	case "claude-3-haiku":
-		return Claude3Alias
-	case "llama 3.3":
-		return LlamaAlias
-	case "mistral small 3":
-		return MixtralAlias
-	case "o4mini":
-		return o4miniAlias
-	case "cancel":
+	if choice == len(offered) {
 		ui.Warningln("Model change canceled")
 		return ""
-	default:
-		return ""
 	}
+	return offered[choice].Alias
 }
 
 func GetCurrentModel(chat interface{}) Model {
@@ -0,0 +1,308 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"duckduckgo-chat-cli/internal/ui"
+
+	"github.com/fatih/color"
+)
+
+type Model string
+type ModelAlias string
+
+const (
+	GPT4Mini Model = "gpt-4o-mini"
+	Claude3  Model = "claude-3-haiku-20240307"
+	Llama    Model = "meta-llama/Llama-3.3-70B-Instruct-Turbo"
+	Mixtral  Model = "mistralai/Mistral-Small-24B-Instruct-2501"
+	o4mini   Model = "o4-mini"
+
+	GPT4MiniAlias ModelAlias = "gpt-4o-mini"
+	Claude3Alias  ModelAlias = "claude-3-haiku"
+	LlamaAlias    ModelAlias = "llama"
+	MixtralAlias  ModelAlias = "mixtral"
+	o4miniAlias   ModelAlias = "o4mini"
+)
+
+// DefaultContextWindow is the context window of models that do not set one
+const DefaultContextWindow = 50000
+
+// ModelInfo describes a DuckDuckGo AI Chat model
+type ModelInfo struct {
+	ID            Model      `json:"id"`                // name sent to DuckDuckGo
+	Alias         ModelAlias `json:"alias"`             // short name used by commands, the config and the API
+	Aliases       []string   `json:"aliases,omitempty"` // other names accepted for the model
+	DisplayName   string     `json:"display_name"`
+	Description   string     `json:"description,omitempty"`
+	ContextWindow int        `json:"context_window"` // characters of conversation kept before the optimizer trims it
+	Strengths     []string   `json:"strengths,omitempty"`
+	Offered       bool       `json:"offered"` // whether DuckDuckGo currently serves the model
+	Default       bool       `json:"default,omitempty"`
+}
+
+// Names returns every name the model is known by
+func (m ModelInfo) Names() []string {
+	return append([]string{string(m.Alias), string(m.ID)}, m.Aliases...)
+}
+
+var builtinModels = []ModelInfo{
+	{
+		ID:            GPT4Mini,
+		Alias:         GPT4MiniAlias,
+		DisplayName:   "GPT-4o-mini",
+		Description:   "Fast and efficient model for general conversations",
+		ContextWindow: DefaultContextWindow,
+		Strengths:     []string{"general", "fast"},
+		Offered:       true,
+		Default:       true,
+	},
+	{
+		ID:            Claude3,
+		Alias:         Claude3Alias,
+		DisplayName:   "Claude-3-haiku",
+		Description:   "Anthropic's Claude 3 Haiku model for thoughtful responses",
+		ContextWindow: DefaultContextWindow,
+		Strengths:     []string{"writing", "summaries"},
+		Offered:       true,
+	},
+	{
+		ID:            Llama,
+		Alias:         LlamaAlias,
+		DisplayName:   "Llama 3.3",
+		Description:   "Meta's Llama 3.3 70B model for advanced reasoning",
+		ContextWindow: DefaultContextWindow,
+		Strengths:     []string{"reasoning", "code"},
+		Offered:       true,
+	},
+	{
+		ID:            Mixtral,
+		Alias:         MixtralAlias,
+		DisplayName:   "Mistral Small 3",
+		Description:   "Mistral's efficient small model for quick responses",
+		ContextWindow: DefaultContextWindow,
+		Strengths:     []string{"speed", "multilingual"},
+		Offered:       true,
+	},
+	{
+		ID:            o4mini,
+		Alias:         o4miniAlias,
+		DisplayName:   "o4-mini",
+		Description:   "Compact and efficient model for basic interactions",
+		ContextWindow: DefaultContextWindow,
+		Strengths:     []string{"reasoning", "math"},
+		Offered:       true,
+	},
+}
+
+// registry holds the built-in models merged with the override file. It is
+// loaded on first use.
+var registry struct {
+	once   sync.Once
+	models []ModelInfo
+}
+
+func loadedModels() []ModelInfo {
+	registry.once.Do(func() {
+		registry.models = builtinModels
+		merged, err := loadOverrides(builtinModels, OverridePath())
+		if err != nil {
+			ui.WarningColor.Fprintf(color.Error, "Warning: ignoring model overrides: %v\n", err)
+			return
+		}
+		registry.models = merged
+	})
+	return registry.models
+}
+
+// OverridePath returns the path of the file that adds models or changes the
+// built-in ones, next to the configuration file
+func OverridePath() string {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		if runtime.GOOS == "windows" {
+			configDir = filepath.Join(os.Getenv("USERPROFILE"), ".config")
+		} else {
+			configDir = filepath.Join(os.Getenv("HOME"), ".config")
+		}
+	}
+	return filepath.Join(configDir, "duckduckgo-chat-cli", "models.json")
+}
+
+// loadOverrides merges the override file into the models. An entry whose
+// alias or ID matches a model changes only the fields it sets; other entries
+// add new models. A missing file is not an error.
+func loadOverrides(base []ModelInfo, path string) ([]ModelInfo, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return base, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var file struct {
+		Models []json.RawMessage `json:"models"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	merged := append([]ModelInfo(nil), base...)
+	newDefault := -1
+	for i, raw := range file.Models {
+		var key ModelInfo
+		if err := json.Unmarshal(raw, &key); err != nil {
+			return nil, fmt.Errorf("%s: model %d: %w", path, i+1, err)
+		}
+
+		index := -1
+		for j, m := range merged {
+			if (key.Alias != "" && m.Alias == key.Alias) || (key.ID != "" && m.ID == key.ID) {
+				index = j
+				break
+			}
+		}
+		if index < 0 {
+			if key.ID == "" || key.Alias == "" {
+				return nil, fmt.Errorf("%s: model %d: a new model needs an id and an alias", path, i+1)
+			}
+			merged = append(merged, ModelInfo{})
+			index = len(merged) - 1
+		}
+
+		// Unmarshalling onto the existing entry keeps the fields left out
+		entry := merged[index]
+		entry.Aliases = append([]string(nil), entry.Aliases...)
+		entry.Strengths = append([]string(nil), entry.Strengths...)
+		if err := json.Unmarshal(raw, &entry); err != nil {
+			return nil, fmt.Errorf("%s: model %d: %w", path, i+1, err)
+		}
+		if entry.DisplayName == "" {
+			entry.DisplayName = string(entry.Alias)
+		}
+		if entry.ContextWindow <= 0 {
+			entry.ContextWindow = DefaultContextWindow
+		}
+		merged[index] = entry
+		if key.Default {
+			newDefault = index
+		}
+	}
+
+	if newDefault >= 0 {
+		for i := range merged {
+			merged[i].Default = i == newDefault
+		}
+	}
+	if len(offered(merged)) == 0 {
+		return nil, fmt.Errorf("%s: no model is offered", path)
+	}
+	return merged, nil
+}
+
+func offered(all []ModelInfo) []ModelInfo {
+	var result []ModelInfo
+	for _, m := range all {
+		if m.Offered {
+			result = append(result, m)
+		}
+	}
+	return result
+}
+
+// Registry returns every known model, including those no longer offered
+func Registry() []ModelInfo {
+	return append([]ModelInfo(nil), loadedModels()...)
+}
+
+// Offered returns the models that can be chosen, in registry order
+func Offered() []ModelInfo {
+	return offered(loadedModels())
+}
+
+// Default returns the model used when none is chosen
+func Default() ModelInfo {
+	list := Offered()
+	for _, m := range list {
+		if m.Default {
+			return m
+		}
+	}
+	return list[0]
+}
+
+// Info finds a model by any of its names, case-insensitively, whether it is
+// offered or not
+func Info(name string) (ModelInfo, bool) {
+	for _, m := range loadedModels() {
+		for _, known := range m.Names() {
+			if strings.EqualFold(name, known) {
+				return m, true
+			}
+		}
+	}
+	return ModelInfo{}, false
+}
+
+func GetModel(alias string) Model {
+	if _, model, ok := LookupModel(alias); ok {
+		return model
+	}
+	return Default().ID
+}
+
+// LookupModel resolves a model alias or full model name, case-insensitively.
+// Unlike GetModel it reports unknown names instead of falling back to the default.
+// Models that are no longer offered are unknown.
+func LookupModel(name string) (ModelAlias, Model, bool) {
+	m, ok := Info(name)
+	if !ok || !m.Offered {
+		return "", "", false
+	}
+	return m.Alias, m.ID, true
+}
+
+// Aliases returns the aliases of all available models, sorted by name
+func Aliases() []ModelAlias {
+	list := Offered()
+	aliases := make([]ModelAlias, len(list))
+	for i, m := range list {
+		aliases[i] = m.Alias
+	}
+	sort.Slice(aliases, func(i, j int) bool { return aliases[i] < aliases[j] })
+	return aliases
+}
+
+// DisplayName returns the human-readable name of a model
+func DisplayName(model Model) string {
+	if m, ok := Info(string(model)); ok {
+		return m.DisplayName
+	}
+	return string(model)
+}
+
+// ShortName returns the alias of a model, or its name if it is not in the
+// registry, as is the case with other providers' models
+func ShortName(model Model) string {
+	if m, ok := Info(string(model)); ok {
+		return string(m.Alias)
+	}
+	return string(model)
+}
+
+// ContextWindow returns how many characters of conversation are kept for a
+// model before the context optimizer trims it
+func ContextWindow(model Model) int {
+	if m, ok := Info(string(model)); ok {
+		return m.ContextWindow
+	}
+	return DefaultContextWindow
+}
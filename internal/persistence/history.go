@@ -25,6 +25,20 @@ type ConversationSession struct {
 	Analytics         SessionAnalytics       `json:"analytics"`
 	Compressed        bool                   `json:"compressed"`
 	Version           string                 `json:"version"`
+	// Branches holds the conversation tree when the conversation was forked.
+	// Messages is then the active branch.
+	Branches     []SessionBranch `json:"branches,omitempty"`
+	ActiveBranch int             `json:"active_branch,omitempty"`
+}
+
+// SessionBranch is a saved branch of the conversation tree
+type SessionBranch struct {
+	ID       int                    `json:"id"`
+	Name     string                 `json:"name,omitempty"`
+	Parent   int                    `json:"parent"`
+	ForkAt   int                    `json:"fork_at"`
+	Created  time.Time              `json:"created"`
+	Messages []intelligence.Message `json:"messages"`
 }
 
 // SessionAnalytics stores session-specific analytics
@@ -0,0 +1,277 @@
+package persistence
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"duckduckgo-chat-cli/internal/intelligence"
+	"duckduckgo-chat-cli/internal/ui"
+)
+
+// Journal entry types
+const (
+	JournalStart   = "start"   // the session began, or resumed in a new process
+	JournalMessage = "message" // a message was added
+	JournalReplace = "replace" // the history or the conversation tree changed, e.g. by /undo or /branch
+	JournalModel   = "model"   // the model changed
+)
+
+// JournalEntry is one line of a session journal
+type JournalEntry struct {
+	Type      string                 `json:"type"`
+	Time      time.Time              `json:"time"`
+	SessionID string                 `json:"session_id,omitempty"`
+	StartTime time.Time              `json:"start_time,omitempty"`
+	PID       int                    `json:"pid,omitempty"`
+	Model     string                 `json:"model,omitempty"`
+	Messages  []intelligence.Message `json:"messages,omitempty"`
+
+	// Conversation tree, recorded with each replace once the conversation forked
+	Branches     []SessionBranch `json:"branches,omitempty"`
+	ActiveBranch int             `json:"active_branch,omitempty"`
+}
+
+// Journal records the history of a conversation as it changes, one JSON entry
+// per line, synced to disk before the change is shown. A session that ends
+// normally is saved and its journal removed; a journal left behind belongs to
+// a session that was killed, and can be recovered on the next start.
+type Journal struct {
+	mu        sync.Mutex
+	path      string
+	file      *os.File
+	sessionID string
+	startTime time.Time
+	model     string
+	failed    bool
+}
+
+// RecoveredSession is a session rebuilt from a journal left behind
+type RecoveredSession struct {
+	Session *ConversationSession
+	Path    string
+	Updated time.Time
+}
+
+// NewJournal returns a new journal for a session. Each journal has its own
+// file, so removing one never touches another journal of the same session.
+// The file is only created when the first entry is written, so sessions
+// without messages leave nothing behind.
+func (hm *HistoryManager) NewJournal(sessionID string, startTime time.Time, model string) *Journal {
+	name := fmt.Sprintf("journal_%s_%d.jsonl", sessionID, time.Now().UnixNano())
+	return &Journal{
+		path:      filepath.Join(hm.StorageDir, name),
+		sessionID: sessionID,
+		startTime: startTime,
+		model:     model,
+	}
+}
+
+// AppendMessage records a message added to the history
+func (j *Journal) AppendMessage(msg intelligence.Message) {
+	j.write(JournalEntry{Type: JournalMessage, Messages: []intelligence.Message{msg}})
+}
+
+// ReplaceMessages records a history replaced as a whole, with the
+// conversation tree it belongs to (nil when the conversation never forked)
+func (j *Journal) ReplaceMessages(messages []intelligence.Message, branches []SessionBranch, activeBranch int) {
+	j.write(JournalEntry{Type: JournalReplace, Messages: messages, Branches: branches, ActiveBranch: activeBranch})
+}
+
+// SetModel records a model change
+func (j *Journal) SetModel(model string) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	j.model = model
+	j.mu.Unlock()
+	j.write(JournalEntry{Type: JournalModel, Model: model})
+}
+
+// write appends an entry and syncs it. After the first failure the journal
+// warns once and stops, rather than getting in the way of the chat.
+func (j *Journal) write(entry JournalEntry) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.failed {
+		return
+	}
+
+	if err := j.writeLocked(entry); err != nil {
+		j.failed = true
+		ui.Warningln("⚠️  Session journal disabled, the conversation will not be recoverable after a crash: %v", err)
+	}
+}
+
+func (j *Journal) writeLocked(entry JournalEntry) error {
+	if j.file == nil {
+		if err := os.MkdirAll(filepath.Dir(j.path), 0755); err != nil {
+			return err
+		}
+		file, err := os.OpenFile(j.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return err
+		}
+		j.file = file
+
+		start := JournalEntry{
+			Type:      JournalStart,
+			Time:      time.Now(),
+			SessionID: j.sessionID,
+			StartTime: j.startTime,
+			PID:       os.Getpid(),
+			Model:     j.model,
+		}
+		if err := j.appendLine(start); err != nil {
+			return err
+		}
+	}
+
+	entry.Time = time.Now()
+	if err := j.appendLine(entry); err != nil {
+		return err
+	}
+	return j.file.Sync()
+}
+
+func (j *Journal) appendLine(entry JournalEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = j.file.Write(append(data, '\n'))
+	return err
+}
+
+// Remove closes the journal and deletes it, once its session has been saved
+func (j *Journal) Remove() {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.file != nil {
+		j.file.Close()
+		j.file = nil
+	}
+	os.Remove(j.path)
+	j.failed = true // nothing more is recorded for this session
+}
+
+// UnfinishedJournals rebuilds the sessions whose journal was left behind by a
+// process that is no longer running, most recent first
+func (hm *HistoryManager) UnfinishedJournals() ([]RecoveredSession, error) {
+	files, err := os.ReadDir(hm.StorageDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read storage directory: %w", err)
+	}
+
+	var recovered []RecoveredSession
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || !strings.HasPrefix(name, "journal_") || !strings.HasSuffix(name, ".jsonl") {
+			continue
+		}
+
+		path := filepath.Join(hm.StorageDir, name)
+		session, updated, pid, err := readJournal(path)
+		if err != nil {
+			ui.Warningln("Failed to read journal %s: %v", name, err)
+			continue
+		}
+		if pid == os.Getpid() || processRunning(pid) || len(session.Messages) == 0 {
+			continue
+		}
+		recovered = append(recovered, RecoveredSession{Session: session, Path: path, Updated: updated})
+	}
+
+	sort.Slice(recovered, func(i, j int) bool {
+		return recovered[i].Updated.After(recovered[j].Updated)
+	})
+	return recovered, nil
+}
+
+// readJournal replays a journal. A line cut short by the crash ends the
+// replay instead of failing it.
+func readJournal(path string) (*ConversationSession, time.Time, int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, time.Time{}, 0, err
+	}
+	defer file.Close()
+
+	session := &ConversationSession{}
+	var updated time.Time
+	pid := 0
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		var entry JournalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			break
+		}
+		updated = entry.Time
+
+		switch entry.Type {
+		case JournalStart:
+			session.ID = entry.SessionID
+			session.StartTime = entry.StartTime
+			session.Model = entry.Model
+			pid = entry.PID
+		case JournalMessage:
+			session.Messages = append(session.Messages, entry.Messages...)
+		case JournalReplace:
+			session.Messages = entry.Messages
+			session.Branches = entry.Branches
+			session.ActiveBranch = entry.ActiveBranch
+		case JournalModel:
+			session.Model = entry.Model
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, time.Time{}, 0, err
+	}
+	if session.ID == "" {
+		return nil, time.Time{}, 0, fmt.Errorf("no session start recorded")
+	}
+	return session, updated, pid, nil
+}
+
+// DiscardJournal deletes a journal left behind
+func (hm *HistoryManager) DiscardJournal(recovered RecoveredSession) error {
+	return os.Remove(recovered.Path)
+}
+
+// processRunning reports whether a process with this ID exists
+func processRunning(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	// On Windows FindProcess only succeeds for running processes
+	if runtime.GOOS == "windows" {
+		process.Release()
+		return true
+	}
+	return process.Signal(syscall.Signal(0)) == nil
+}
@@ -0,0 +1,153 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// Config routes outbound traffic through a proxy. Without a URL, the
+// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables apply, as before.
+type Config struct {
+	URL      string   `json:"url,omitempty"` // http://, https:// or socks5:// proxy, e.g. socks5://proxy.corp:1080
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	NoProxy  []string `json:"no_proxy,omitempty"` // hosts, .domains, IPs and CIDR ranges reached directly
+}
+
+// Supported proxy schemes
+var schemes = []string{"http", "https", "socks5", "socks5h"}
+
+var (
+	mu     sync.RWMutex
+	active Config
+	proxy  *url.URL // with credentials, nil when connecting directly
+)
+
+// Apply makes every client that uses http.DefaultTransport, or a clone of it
+// made afterwards, go through the proxy. It is called once the configuration
+// is loaded, before any request is sent.
+func Apply(cfg Config) error {
+	proxyURL, err := parse(cfg)
+	if err != nil {
+		return err
+	}
+
+	mu.Lock()
+	active = cfg
+	proxy = proxyURL
+	mu.Unlock()
+
+	if t, ok := http.DefaultTransport.(*http.Transport); ok && proxyURL != nil {
+		t.Proxy = transportProxy()
+	}
+	return nil
+}
+
+// Validate reports whether the proxy settings can be applied
+func Validate(cfg Config) error {
+	_, err := parse(cfg)
+	return err
+}
+
+// parse checks the proxy URL and adds the credentials to it
+func parse(cfg Config) (*url.URL, error) {
+	raw := strings.TrimSpace(cfg.URL)
+	if raw == "" {
+		return nil, nil
+	}
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+
+	proxyURL, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	supported := false
+	for _, scheme := range schemes {
+		supported = supported || proxyURL.Scheme == scheme
+	}
+	if !supported {
+		return nil, fmt.Errorf("unsupported proxy scheme %q (use %s)", proxyURL.Scheme, strings.Join(schemes, ", "))
+	}
+	if proxyURL.Host == "" {
+		return nil, fmt.Errorf("proxy URL %q has no host", cfg.URL)
+	}
+
+	if cfg.Username != "" {
+		proxyURL.User = url.UserPassword(cfg.Username, cfg.Password)
+	}
+	return proxyURL, nil
+}
+
+// transportProxy returns the proxy function of http.Transport: the configured
+// proxy, except for the hosts of the no-proxy list and loopback addresses
+func transportProxy() func(*http.Request) (*url.URL, error) {
+	mu.RLock()
+	proxyURL, noProxy := proxy, active.NoProxy
+	mu.RUnlock()
+	if proxyURL == nil {
+		return http.ProxyFromEnvironment
+	}
+
+	proxyFunc := (&httpproxy.Config{
+		HTTPProxy:  proxyURL.String(),
+		HTTPSProxy: proxyURL.String(),
+		NoProxy:    strings.Join(noProxy, ","),
+	}).ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return proxyFunc(req.URL)
+	}
+}
+
+// Chrome returns the --proxy-server and --proxy-bypass-list values for
+// Chrome, and the credentials to answer the proxy's authentication with.
+// The server is empty when connecting directly.
+func Chrome() (server, bypass string, user *url.Userinfo) {
+	mu.RLock()
+	defer mu.RUnlock()
+	if proxy == nil {
+		return "", "", nil
+	}
+
+	// Chrome takes the credentials separately and calls socks5h socks5
+	server = fmt.Sprintf("%s://%s", strings.TrimSuffix(proxy.Scheme, "h"), proxy.Host)
+	bypass = strings.Join(append([]string{"<local>"}, active.NoProxy...), ";")
+	return server, bypass, proxy.User
+}
+
+// Describe returns the proxy in use, without its password
+func Describe() string {
+	mu.RLock()
+	proxyURL, noProxy := proxy, active.NoProxy
+	mu.RUnlock()
+
+	if proxyURL == nil {
+		for _, name := range []string{"HTTPS_PROXY", "https_proxy", "HTTP_PROXY", "http_proxy"} {
+			if value := os.Getenv(name); value != "" {
+				return fmt.Sprintf("%s (from %s)", redact(value), name)
+			}
+		}
+		return "none (direct connection)"
+	}
+
+	description := proxyURL.Redacted()
+	if len(noProxy) > 0 {
+		description += fmt.Sprintf(" (direct for %s)", strings.Join(noProxy, ", "))
+	}
+	return description
+}
+
+// redact hides the password of a proxy URL
+func redact(raw string) string {
+	if u, err := url.Parse(raw); err == nil && u.Host != "" {
+		return u.Redacted()
+	}
+	return raw
+}
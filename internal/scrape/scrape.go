@@ -5,10 +5,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"net/url"
 	"regexp"
 	"strings"
 	"time"
 
+	"duckduckgo-chat-cli/internal/proxy"
+
+	"github.com/chromedp/cdproto/fetch"
 	"github.com/chromedp/chromedp"
 )
 
@@ -17,23 +21,43 @@ type WebContentResult struct {
 	Content string `json:"content"`
 }
 
-func WebContent(urlStr string) (*WebContentResult, error) {
+// WebContent loads the page in a headless Chrome and returns its text.
+// Cancelling ctx stops Chrome.
+func WebContent(ctx context.Context, urlStr string) (*WebContentResult, error) {
 	if !strings.HasPrefix(urlStr, "http://") && !strings.HasPrefix(urlStr, "https://") {
 		urlStr = "https://" + urlStr
 	}
 
-	ctx, cancel := chromedp.NewContext(context.Background())
+	options := chromedp.DefaultExecAllocatorOptions[:]
+	server, bypass, user := proxy.Chrome()
+	if server != "" {
+		options = append(options, chromedp.ProxyServer(server), chromedp.Flag("proxy-bypass-list", bypass))
+	}
+	parent := ctx
+	ctx, cancel := chromedp.NewExecAllocator(ctx, options...)
+	defer cancel()
+	ctx, cancel = chromedp.NewContext(ctx)
 	defer cancel()
 	ctx, cancel = context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
+	var tasks chromedp.Tasks
+	if server != "" && user != nil {
+		answerProxyAuth(ctx, user)
+		tasks = append(tasks, fetch.Enable().WithHandleAuthRequests(true))
+	}
+
 	var content string
-	err := chromedp.Run(ctx,
+	tasks = append(tasks,
 		chromedp.Navigate(urlStr),
 		chromedp.Sleep(2*time.Second),
 		chromedp.Evaluate(extractionScript, &content),
 	)
+	err := chromedp.Run(ctx, tasks)
 	if err != nil {
+		if parent.Err() != nil {
+			return nil, parent.Err()
+		}
 		log.Printf("Error fetching content for URL %s: %v\n", urlStr, err)
 		return nil, err
 	}
@@ -46,6 +70,29 @@ func WebContent(urlStr string) (*WebContentResult, error) {
 	return result, nil
 }
 
+// answerProxyAuth gives Chrome the proxy credentials, which it does not take
+// on the command line. Enabling the fetch domain pauses every request, so the
+// others are let through unchanged.
+func answerProxyAuth(ctx context.Context, user *url.Userinfo) {
+	password, _ := user.Password()
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		switch ev := ev.(type) {
+		case *fetch.EventRequestPaused:
+			go chromedp.Run(ctx, fetch.ContinueRequest(ev.RequestID))
+		case *fetch.EventAuthRequired:
+			response := &fetch.AuthChallengeResponse{Response: fetch.AuthChallengeResponseResponseDefault}
+			if ev.AuthChallenge.Source == fetch.AuthChallengeSourceProxy {
+				response = &fetch.AuthChallengeResponse{
+					Response: fetch.AuthChallengeResponseResponseProvideCredentials,
+					Username: user.Username(),
+					Password: password,
+				}
+			}
+			go chromedp.Run(ctx, fetch.ContinueWithAuth(ev.RequestID, response))
+		}
+	})
+}
+
 func (wcr *WebContentResult) ToJSON() (string, error) {
 	jsonData, err := json.MarshalIndent(wcr, "", "  ")
 	if err != nil {
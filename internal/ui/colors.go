@@ -17,12 +17,13 @@ var (
 	MutedColor  = color.New(color.FgHiBlack)
 )
 
-// Formatted print functions (without newlines)
+// Formatted print functions (without newlines). Errors go to stderr, so they
+// are kept apart from the regular output when it is redirected.
 func Userf(format string, a ...interface{})    { UserColor.Printf(format, a...) }
 func AIf(format string, a ...interface{})      { AIColor.Printf(format, a...) }
 func Systemf(format string, a ...interface{})  { SystemColor.Printf(format, a...) }
 func Warningf(format string, a ...interface{}) { WarningColor.Printf(format, a...) }
-func Errorf(format string, a ...interface{})   { ErrorColor.Printf(format, a...) }
+func Errorf(format string, a ...interface{})   { ErrorColor.Fprintf(color.Error, format, a...) }
 func Whitef(format string, a ...interface{})   { WhiteColor.Printf(format, a...) }
 func Promptf(format string, a ...interface{})  { PromptColor.Printf(format, a...) }
 func Mutedf(format string, a ...interface{})   { MutedColor.Printf(format, a...) }
@@ -32,6 +33,6 @@ func Userln(format string, a ...interface{})    { UserColor.Printf(format+"\n",
 func AIln(format string, a ...interface{})      { AIColor.Printf(format+"\n", a...) }
 func Systemln(format string, a ...interface{})  { SystemColor.Printf(format+"\n", a...) }
 func Warningln(format string, a ...interface{}) { WarningColor.Printf(format+"\n", a...) }
-func Errorln(format string, a ...interface{})   { ErrorColor.Printf(format+"\n", a...) }
+func Errorln(format string, a ...interface{})   { ErrorColor.Fprintf(color.Error, format+"\n", a...) }
 func Whiteln(format string, a ...interface{})   { WhiteColor.Printf(format+"\n", a...) }
 func Mutedln(format string, a ...interface{})   { MutedColor.Printf(format+"\n", a...) }